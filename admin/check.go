@@ -0,0 +1,124 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/realcaishen/utils-go/redact"
+)
+
+// Check reports whether a dependency is ready; a nil error means ready.
+type Check func(ctx context.Context) error
+
+type checkResult struct {
+	Name     string `json:"name"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type readyResponse struct {
+	Status string        `json:"status"`
+	Checks []checkResult `json:"checks"`
+}
+
+// runChecks runs checks concurrently, each bounded by timeout, and returns the results
+// in registration order. Errors are redacted since /readyz is often reachable from
+// outside the pod and rpc errors carry endpoint URLs with API keys.
+func runChecks(ctx context.Context, checks []namedCheck, timeout time.Duration) []checkResult {
+	results := make([]checkResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c namedCheck) {
+			defer wg.Done()
+			start := time.Now()
+			err := runCheck(ctx, c.check, timeout)
+			results[i] = checkResult{Name: c.name, Duration: time.Since(start).Round(time.Millisecond).String()}
+			if err != nil {
+				results[i].Error = redact.String(err.Error())
+			}
+		}(i, c)
+	}
+	wg.Wait()
+	return results
+}
+
+// runCheck gives up on a check that ignores its context once timeout passed.
+func runCheck(ctx context.Context, check Check, timeout time.Duration) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// DBCheck pings db.
+func DBCheck(db *sql.DB) Check {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// BlockNumberer is the part of rpc.Rpc RpcCheck needs.
+type BlockNumberer interface {
+	GetLatestBlockNumber(ctx context.Context) (int64, error)
+}
+
+// RpcCheck asks the chain for its latest block, e.g. with an rpc.Rpc.
+func RpcCheck(client BlockNumberer) Check {
+	return func(ctx context.Context) error {
+		_, err := client.GetLatestBlockNumber(ctx)
+		return err
+	}
+}
+
+// Loader is implemented by the loader managers that track their last database load and
+// snapshot restore, such as *loader.ChainInfoManager.
+type Loader interface {
+	LoadedAt() time.Time
+	StaleSince() time.Time
+}
+
+// LoaderCheck fails until l completed its first database load, unless l holds data
+// restored from a snapshot saved at most maxStale ago: a service restarted while the
+// database is down serves that data and stays ready. A maxStale of zero only accepts
+// database loads.
+func LoaderCheck(l Loader, maxStale time.Duration) Check {
+	return func(ctx context.Context) error {
+		if !l.LoadedAt().IsZero() {
+			return nil
+		}
+		staleSince := l.StaleSince()
+		if staleSince.IsZero() {
+			return errors.New("not loaded yet")
+		}
+		if age := time.Since(staleSince); maxStale <= 0 || age > maxStale {
+			return fmt.Errorf("not loaded yet, snapshot saved %v ago", age.Round(time.Second))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,218 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/realcaishen/utils-go/asynccache"
+	"github.com/realcaishen/utils-go/log"
+	"github.com/realcaishen/utils-go/telemetry"
+)
+
+var logger = log.Module("admin")
+
+// Config describes the admin server. It is meant for an internal port only.
+type Config struct {
+	// Addr is the listen address, ":9090" when empty.
+	Addr string `mapstructure:"addr"`
+	// MetricsFormat is the /metrics format when the request has no ?format=,
+	// see telemetry.FormatPrometheus.
+	MetricsFormat string `mapstructure:"metrics_format"`
+	// Pprof mounts net/http/pprof under /debug/pprof/.
+	Pprof bool `mapstructure:"pprof"`
+	// Debug mounts asynccache.DebugHandler on /debug/cache and log.LevelHandler on
+	// /debug/log/level.
+	Debug bool `mapstructure:"debug"`
+	// CheckTimeout bounds every readiness check, 3s when not positive.
+	CheckTimeout time.Duration `mapstructure:"check_timeout"`
+	// ShutdownTimeout bounds how long Run waits for open requests, 5s when not positive.
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+}
+
+func (c Config) withDefaults() Config {
+	if c.Addr == "" {
+		c.Addr = ":9090"
+	}
+	if c.CheckTimeout <= 0 {
+		c.CheckTimeout = 3 * time.Second
+	}
+	if c.ShutdownTimeout <= 0 {
+		c.ShutdownTimeout = 5 * time.Second
+	}
+	return c
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Server serves /metrics, /healthz and /readyz. /healthz answers as long as the
+// process serves HTTP; /readyz runs the registered checks and fails while any of them
+// fails or once shutdown started, so traffic drains before the process exits.
+type Server struct {
+	cfg     Config
+	metrics *telemetry.Metrics
+	mux     *http.ServeMux
+
+	mutex    sync.RWMutex
+	checks   []namedCheck
+	server   *http.Server
+	listener net.Listener
+	stopping atomic.Bool
+}
+
+// NewServer builds a server for cfg. metrics may be nil, as returned by telemetry.New
+// when telemetry is disabled; /metrics then answers 503.
+func NewServer(cfg Config, metrics *telemetry.Metrics) *Server {
+	s := &Server{
+		cfg:     cfg.withDefaults(),
+		metrics: metrics,
+		mux:     http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/metrics", s.serveMetrics)
+	s.mux.HandleFunc("/healthz", s.serveHealth)
+	s.mux.HandleFunc("/readyz", s.serveReady)
+	if s.cfg.Pprof {
+		s.mux.HandleFunc("/debug/pprof/", pprof.Index)
+		s.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		s.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		s.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		s.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	if s.cfg.Debug {
+		s.mux.Handle("/debug/cache", asynccache.DebugHandler())
+		s.mux.Handle("/debug/log/level", log.LevelHandler())
+	}
+	return s
+}
+
+// AddCheck registers a readiness check. Checks with the same name replace each other.
+func (s *Server) AddCheck(name string, check Check) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := range s.checks {
+		if s.checks[i].name == name {
+			s.checks[i].check = check
+			return
+		}
+	}
+	s.checks = append(s.checks, namedCheck{name: name, check: check})
+}
+
+// Handle mounts an extra handler, e.g. a service specific debug page.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Handler returns the mux of the server, for tests or to mount it on another server.
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Start listens on Config.Addr and serves in the background. It fails if the address
+// cannot be bound or the server was already started.
+func (s *Server) Start() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.server != nil {
+		return errors.New("admin server already started")
+	}
+	listener, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.server = &http.Server{Handler: s.mux, ReadHeaderTimeout: 10 * time.Second}
+	server := s.server
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("admin server stopped: %v", err)
+		}
+	}()
+	logger.Infof("admin server listening on %s", listener.Addr())
+	return nil
+}
+
+// Addr returns the bound address once started, useful with port 0.
+func (s *Server) Addr() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Shutdown fails readiness, then stops accepting connections and waits for open
+// requests until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopping.Store(true)
+	s.mutex.RLock()
+	server := s.server
+	s.mutex.RUnlock()
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
+
+// Run starts the server and shuts it down when ctx is done.
+func (s *Server) Run(ctx context.Context) error {
+	if err := s.Start(); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	return s.Shutdown(shutdownCtx)
+}
+
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if s.metrics == nil {
+		http.Error(w, "telemetry disabled", http.StatusServiceUnavailable)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = s.cfg.MetricsFormat
+	}
+	resp, err := s.metrics.Gather(format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", resp.ContentType)
+	w.Write(resp.Metrics)
+}
+
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+}
+
+func (s *Server) serveReady(w http.ResponseWriter, r *http.Request) {
+	s.mutex.RLock()
+	checks := append([]namedCheck(nil), s.checks...)
+	s.mutex.RUnlock()
+
+	results := runChecks(r.Context(), checks, s.cfg.CheckTimeout)
+	status := http.StatusOK
+	body := readyResponse{Status: "ok", Checks: results}
+	for _, result := range results {
+		if result.Error != "" {
+			status = http.StatusServiceUnavailable
+			body.Status = "unavailable"
+		}
+	}
+	if s.stopping.Load() {
+		status = http.StatusServiceUnavailable
+		body.Status = "shutting down"
+	}
+	writeJSON(w, status, body)
+}
@@ -0,0 +1,164 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/realcaishen/utils-go/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLoader struct {
+	loadedAt   time.Time
+	staleSince time.Time
+}
+
+func (l *fakeLoader) LoadedAt() time.Time {
+	return l.loadedAt
+}
+
+func (l *fakeLoader) StaleSince() time.Time {
+	return l.staleSince
+}
+
+type fakeChain struct {
+	err error
+}
+
+func (c fakeChain) GetLatestBlockNumber(ctx context.Context) (int64, error) {
+	return 100, c.err
+}
+
+func get(t *testing.T, h http.Handler, target string) (int, string) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec.Code, rec.Body.String()
+}
+
+func TestReadiness(t *testing.T) {
+	s := NewServer(Config{CheckTimeout: 50 * time.Millisecond}, nil)
+	h := s.Handler()
+
+	code, _ := get(t, h, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+
+	l := &fakeLoader{}
+	s.AddCheck("chains", LoaderCheck(l, time.Hour))
+	s.AddCheck("rpc", RpcCheck(fakeChain{}))
+	s.AddCheck("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	code, body := get(t, h, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	var resp readyResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &resp))
+	assert.Equal(t, "unavailable", resp.Status)
+	require.Len(t, resp.Checks, 3)
+	assert.Equal(t, "chains", resp.Checks[0].Name)
+	assert.Equal(t, "not loaded yet", resp.Checks[0].Error)
+	assert.Empty(t, resp.Checks[1].Error)
+	assert.Equal(t, context.DeadlineExceeded.Error(), resp.Checks[2].Error)
+
+	l.loadedAt = time.Now()
+	s.AddCheck("slow", func(ctx context.Context) error { return nil })
+	code, _ = get(t, h, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+
+	s.AddCheck("rpc", RpcCheck(fakeChain{err: errors.New(`Post "https://mainnet.infura.io/v3/0123456789abcdef0123456789abcdef": connection refused`)}))
+	code, body = get(t, h, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, body, "connection refused")
+	assert.NotContains(t, body, "0123456789abcdef0123456789abcdef")
+
+	code, body = get(t, h, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body)
+}
+
+func TestLoaderCheck(t *testing.T) {
+	ctx := context.Background()
+	l := &fakeLoader{}
+	assert.EqualError(t, LoaderCheck(l, time.Hour)(ctx), "not loaded yet")
+
+	l.staleSince = time.Now().Add(-time.Minute)
+	assert.NoError(t, LoaderCheck(l, time.Hour)(ctx))
+	assert.Error(t, LoaderCheck(l, 0)(ctx))
+
+	l.staleSince = time.Now().Add(-2 * time.Hour)
+	assert.ErrorContains(t, LoaderCheck(l, time.Hour)(ctx), "snapshot saved 2h0m0s ago")
+
+	l.loadedAt, l.staleSince = time.Now(), time.Time{}
+	assert.NoError(t, LoaderCheck(l, 0)(ctx))
+}
+
+func TestMetricsAndPprof(t *testing.T) {
+	code, _ := get(t, NewServer(Config{}, nil).Handler(), "/metrics")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	code, _ = get(t, NewServer(Config{}, nil).Handler(), "/debug/pprof/")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	m, err := telemetry.New(telemetry.Config{ServiceName: "admin", Enabled: true})
+	require.NoError(t, err)
+	telemetry.IncrCounter(1, "admin_test")
+
+	h := NewServer(Config{Pprof: true}, m).Handler()
+	code, body := get(t, h, "/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "admin_test")
+
+	code, _ = get(t, h, "/metrics?format=prometheus")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = get(t, h, "/debug/pprof/")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestStartShutdown(t *testing.T) {
+	s := NewServer(Config{Addr: "127.0.0.1:0"}, nil)
+	require.NoError(t, s.Start())
+	assert.Error(t, s.Start())
+
+	resp, err := http.Get("http://" + s.Addr() + "/readyz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.NoError(t, s.Shutdown(context.Background()))
+	code, body := get(t, s.Handler(), "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, body, "shutting down")
+
+	_, err = http.Get("http://" + s.Addr() + "/healthz")
+	assert.Error(t, err)
+}
+
+func TestRun(t *testing.T) {
+	s := NewServer(Config{Addr: "127.0.0.1:0"}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	require.Eventually(t, func() bool { return s.Addr() != "" }, time.Second, 10*time.Millisecond)
+	resp, err := http.Get("http://" + s.Addr() + "/healthz")
+	require.NoError(t, err)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
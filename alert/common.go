@@ -1,9 +1,12 @@
 package alert
 
 import (
+	"fmt"
 	"log"
 	"sync"
 	"time"
+
+	"github.com/realcaishen/utils-go/redact"
 )
 
 type Alerter interface {
@@ -17,6 +20,7 @@ type CommonAlerter struct {
 	lazyReset    int64
 	lazyTimer    map[string]int64
 	mutex        *sync.RWMutex
+	redactor     *redact.Redactor
 }
 
 func NewCommonAlerter(lazyInterval int64, lazyReset int64) *CommonAlerter {
@@ -25,11 +29,27 @@ func NewCommonAlerter(lazyInterval int64, lazyReset int64) *CommonAlerter {
 		lazyReset:    lazyReset,
 		lazyTimer:    make(map[string]int64),
 		mutex:        &sync.RWMutex{},
+		redactor:     redact.New(redact.Options{}),
+	}
+}
+
+// SetRedactor changes how alert texts are masked before they leave the process, e.g. to
+// also mask addresses; nil sends them unchanged.
+func (ca *CommonAlerter) SetRedactor(redactor *redact.Redactor) {
+	ca.redactor = redactor
+}
+
+// Text renders msg and err the way alerts show them, with secrets masked.
+func (ca *CommonAlerter) Text(msg string, err error) string {
+	text := fmt.Sprintf("%s : %v", msg, err)
+	if ca.redactor == nil {
+		return text
 	}
+	return ca.redactor.String(text)
 }
 
 func (ca *CommonAlerter) AlertText(msg string, err error) {
-	log.Printf("%s : %v", msg, err)
+	log.Println(ca.Text(msg, err))
 }
 
 func (ca *CommonAlerter) AlertTextLazyGroup(group string, msg string, err error) {
@@ -1,7 +1,6 @@
 package alert
 
 import (
-	"fmt"
 	"log"
 	"strings"
 
@@ -31,12 +30,13 @@ func (la *LarkAlerter) AlertTextLazy(msg string, err error) {
 }
 
 func (la *LarkAlerter) AlertText(msg string, err error) {
+	text := la.Text(msg, err)
 	data := map[string]interface{}{
 		"msg_type": "text",
 		"content": map[string]interface{}{
-			"text": fmt.Sprintf("%s : %v", msg, err),
+			"text": text,
 		},
 	}
 	network.Request(la.webhook, data, nil)
-	log.Println(msg, ":", err)
+	log.Println(text)
 }
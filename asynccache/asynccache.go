@@ -1,19 +1,88 @@
 package asynccache
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/realcaishen/utils-go/log"
 	sf "golang.org/x/sync/singleflight"
 )
 
+var logger = log.Module("asynccache")
+
+// Fetcher loads the value of a key from the backing store.
+type Fetcher[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// CacheOptions controls the behavior of Cache.
+type CacheOptions[K comparable, V any] struct {
+	// Name labels the cache's metrics and lists it in DebugHandler. Unnamed caches are
+	// not reported.
+	Name string
+
+	RefreshDuration time.Duration
+	Fetcher         Fetcher[K, V]
+
+	// FetchTimeout bounds every call to Fetcher through its context, 0 means no timeout.
+	FetchTimeout time.Duration
+	// RefreshJitter moves every refresh round by up to this fraction of RefreshDuration
+	// either way, so caches and instances started together do not hit the backend in step.
+	RefreshJitter float64
+	// RefreshConcurrency is how many keys a refresh round fetches at once, default 1.
+	RefreshConcurrency int
+
+	// BatchFetcher, when set, replaces Fetcher: misses arriving within BatchWait of each
+	// other are loaded together, and refresh rounds fetch BatchSize keys per call.
+	BatchFetcher BatchFetcher[K, V]
+	BatchSize    int
+	BatchWait    time.Duration
+	// NegativeTTL is how long a key whose fetch returned ErrNotFound is answered from the
+	// cache with ErrNotFound before it is fetched again. 0 treats ErrNotFound like any
+	// other error.
+	NegativeTTL time.Duration
+
+	// If EnableExpire is true, ExpireDuration MUST be set.
+	EnableExpire   bool
+	ExpireDuration time.Duration
+
+	ErrorHandler  func(key K, err error)
+	ChangeHandler func(key K, oldData, newData V)
+	DeleteHandler func(key K, oldData V)
+
+	IsSame     func(key K, oldData, newData V) bool
+	ErrLogFunc func(str string)
+
+	// MaxEntries and MaxCost bound the cache, 0 means no bound. Cost weighs an entry
+	// against MaxCost and defaults to 1; it also sees the zero value of failed fetches.
+	// When a bound is exceeded entries are evicted by EvictionPolicy and DeleteHandler
+	// is called for them.
+	MaxEntries     int
+	MaxCost        int64
+	Cost           func(key K, val V) int64
+	EvictionPolicy EvictionPolicy
+}
+
 // Options controls the behavior of AsyncCache.
 type Options struct {
+	Name string
+
 	RefreshDuration time.Duration
 	Fetcher         func(key string) (interface{}, error)
+	// CtxFetcher is used instead of Fetcher when set.
+	CtxFetcher func(ctx context.Context, key string) (interface{}, error)
+
+	FetchTimeout       time.Duration
+	RefreshJitter      float64
+	RefreshConcurrency int
+
+	BatchFetcher func(ctx context.Context, keys []string) (map[string]interface{}, error)
+	BatchSize    int
+	BatchWait    time.Duration
+	NegativeTTL  time.Duration
 
 	// If EnableExpire is true, ExpireDuration MUST be set.
 	EnableExpire   bool
@@ -25,6 +94,41 @@ type Options struct {
 
 	IsSame     func(key string, oldData, newData interface{}) bool
 	ErrLogFunc func(str string)
+
+	MaxEntries     int
+	MaxCost        int64
+	Cost           func(key string, val interface{}) int64
+	EvictionPolicy EvictionPolicy
+}
+
+// Cache is the type safe form of AsyncCache.
+type Cache[K comparable, V any] interface {
+	// SetDefault sets the default value of given key if it is new to the cache.
+	// It is useful for cache warming up.
+	SetDefault(key K, val V) (exist bool)
+
+	// Get tries to fetch a value corresponding to the given key from the cache.
+	// If error occurs during the first time fetching, it will be cached until the
+	// sequential fetching triggered by the refresh goroutine succeed.
+	Get(key K) (val V, err error)
+
+	// GetOrSet tries to fetch a value corresponding to the given key from the cache.
+	// If the key is not yet cached or error occurs, the default value will be set.
+	GetOrSet(key K, defaultVal V) (val V)
+
+	// Dump dumps all cache entries.
+	// This will not cause expire to refresh.
+	Dump() map[K]V
+
+	// DeleteIf deletes cached entries that match the `shouldDelete` predicate.
+	DeleteIf(shouldDelete func(key K) bool)
+
+	// Stats returns the current size and the counters so far.
+	Stats() CacheStats
+
+	// Close closes the async cache.
+	// This should be called when the cache is no longer needed, or may lead to resource leak.
+	Close()
 }
 
 // AsyncCache .
@@ -55,101 +159,148 @@ type AsyncCache interface {
 	Close()
 }
 
-// asyncCache .
+// cache .
+type cache[K comparable, V any] struct {
+	sfg       sf.Group
+	opt       CacheOptions[K, V]
+	data      sync.Map
+	bounds    *bounds[K, V]
+	batcher   *batcher[K, V]
+	evictions atomic.Uint64
+	counters  counters
+	// ctx is cancelled by Close, stopping the background loops and their fetches
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// asyncCache adapts a Cache[string, interface{}] to the untyped AsyncCache API.
 type asyncCache struct {
-	sfg  sf.Group
-	opt  Options
-	data sync.Map
+	*cache[string, interface{}]
 }
 
-type tickerType int
+type entry[V any] struct {
+	val       atomic.Pointer[V]
+	updatedAt atomic.Int64
+	expire    int32 // 0 means useful, 1 will expire
+	err       Error
+	// negativeUntil is the unix nano time a not found entry stops being served, 0 for
+	// every other entry
+	negativeUntil atomic.Int64
+}
 
-const (
-	refreshTicker tickerType = iota
-	expireTicker
-)
+func (e *entry[V]) Store(x V, err error) {
+	e.val.Store(&x)
+	e.err.Store(err)
+	e.negativeUntil.Store(0)
+	e.updatedAt.Store(time.Now().UnixNano())
+}
 
-type sharedTicker struct {
-	sync.Mutex
-	started  bool
-	stopChan chan bool
-	ticker   *time.Ticker
-	caches   map[*asyncCache]struct{}
+func (e *entry[V]) markNegative(ttl time.Duration) {
+	e.negativeUntil.Store(time.Now().Add(ttl).UnixNano())
 }
 
-var (
-	// 共用 ticker
-	refreshTickerMap, expireTickerMap sync.Map
-)
+func (e *entry[V]) isNegative() bool {
+	return e.negativeUntil.Load() != 0
+}
 
-type entry struct {
-	val    atomic.Value
-	expire int32 // 0 means useful, 1 will expire
-	err    Error
+func (e *entry[V]) negativeExpired() bool {
+	until := e.negativeUntil.Load()
+	return until != 0 && time.Now().UnixNano() >= until
 }
 
-func (e *entry) Store(x interface{}, err error) {
-	if x != nil {
-		e.val.Store(x)
-	} else {
-		e.val = atomic.Value{}
+func (e *entry[V]) Load() V {
+	if v := e.val.Load(); v != nil {
+		return *v
 	}
-	e.err.Store(err)
+	var zero V
+	return zero
 }
 
-func (e *entry) Touch() {
+func (e *entry[V]) Touch() {
 	atomic.StoreInt32(&e.expire, 0)
 }
 
+// NewCache creates a Cache.
+func NewCache[K comparable, V any](opt CacheOptions[K, V]) Cache[K, V] {
+	return newCache(opt)
+}
+
 // NewAsyncCache creates an AsyncCache.
 func NewAsyncCache(opt Options) AsyncCache {
-	c := &asyncCache{
-		sfg: sf.Group{},
-		opt: opt,
+	fetcher := opt.CtxFetcher
+	if fetcher == nil && opt.Fetcher != nil {
+		fetcher = func(ctx context.Context, key string) (interface{}, error) {
+			return opt.Fetcher(key)
+		}
+	}
+	return &asyncCache{newCache(CacheOptions[string, interface{}]{
+		Name:               opt.Name,
+		RefreshDuration:    opt.RefreshDuration,
+		Fetcher:            fetcher,
+		FetchTimeout:       opt.FetchTimeout,
+		RefreshJitter:      opt.RefreshJitter,
+		RefreshConcurrency: opt.RefreshConcurrency,
+		BatchFetcher:       opt.BatchFetcher,
+		BatchSize:          opt.BatchSize,
+		BatchWait:          opt.BatchWait,
+		NegativeTTL:        opt.NegativeTTL,
+		EnableExpire:       opt.EnableExpire,
+		ExpireDuration:     opt.ExpireDuration,
+		ErrorHandler:       opt.ErrorHandler,
+		ChangeHandler:      opt.ChangeHandler,
+		DeleteHandler:      opt.DeleteHandler,
+		IsSame:             opt.IsSame,
+		ErrLogFunc:         opt.ErrLogFunc,
+		MaxEntries:         opt.MaxEntries,
+		MaxCost:            opt.MaxCost,
+		Cost:               opt.Cost,
+		EvictionPolicy:     opt.EvictionPolicy,
+	})}
+}
+
+func newCache[K comparable, V any](opt CacheOptions[K, V]) *cache[K, V] {
+	c := &cache[K, V]{
+		sfg:    sf.Group{},
+		opt:    opt,
+		bounds: newBounds(opt),
 	}
 	if c.opt.ErrLogFunc == nil {
 		c.opt.ErrLogFunc = func(str string) {
-			log.Println(str)
+			logger.Error(str)
 		}
 	}
-	if c.opt.EnableExpire {
-		if c.opt.ExpireDuration == 0 {
-			panic("asynccache: invalid ExpireDuration")
-		}
-		ti, _ := expireTickerMap.LoadOrStore(c.opt.ExpireDuration,
-			&sharedTicker{caches: make(map[*asyncCache]struct{}), stopChan: make(chan bool, 1)})
-		et := ti.(*sharedTicker)
-		et.Lock()
-		et.caches[c] = struct{}{}
-		if !et.started {
-			et.started = true
-			et.ticker = time.NewTicker(c.opt.ExpireDuration)
-			go et.tick(et.ticker, expireTicker)
-		}
-		et.Unlock()
+	if c.opt.BatchFetcher != nil {
+		c.batcher = &batcher[K, V]{c: c}
+	}
+	if c.opt.RefreshDuration <= 0 {
+		panic("asynccache: invalid RefreshDuration")
 	}
+	if c.opt.EnableExpire && c.opt.ExpireDuration <= 0 {
+		panic("asynccache: invalid ExpireDuration")
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
 
-	ti, _ := refreshTickerMap.LoadOrStore(c.opt.RefreshDuration,
-		&sharedTicker{caches: make(map[*asyncCache]struct{}), stopChan: make(chan bool, 1)})
-	rt := ti.(*sharedTicker)
-	rt.Lock()
-	rt.caches[c] = struct{}{}
-	if !rt.started {
-		rt.started = true
-		rt.ticker = time.NewTicker(c.opt.RefreshDuration)
-		go rt.tick(rt.ticker, refreshTicker)
+	register(c)
+	go c.loop(c.opt.RefreshDuration, c.opt.RefreshJitter, func() {
+		c.refresh()
+		c.report()
+	})
+	if c.opt.EnableExpire {
+		go c.loop(c.opt.ExpireDuration, 0, c.expire)
 	}
-	rt.Unlock()
 	return c
 }
 
 // SetDefault sets the default value of given key if it is new to the cache.
-func (c *asyncCache) SetDefault(key string, val interface{}) bool {
-	ety := &entry{}
+func (c *cache[K, V]) SetDefault(key K, val V) bool {
+	ety := &entry[V]{}
 	ety.Store(val, nil)
 	actual, exist := c.data.LoadOrStore(key, ety)
 	if exist {
-		actual.(*entry).Touch()
+		actual.(*entry[V]).Touch()
+		c.touch(key)
+	} else {
+		c.admit(key, val, true)
 	}
 	return exist
 }
@@ -157,198 +308,274 @@ func (c *asyncCache) SetDefault(key string, val interface{}) bool {
 // Get tries to fetch a value corresponding to the given key from the cache.
 // If error occurs during in the first time fetching, it will be cached until the
 // sequential fetchings triggered by the refresh goroutine succeed.
-func (c *asyncCache) Get(key string) (val interface{}, err error) {
-	var ok bool
-	val, ok = c.data.Load(key)
-	if ok {
-		e := val.(*entry)
-		e.Touch()
-		return e.val.Load(), e.err.Load()
+func (c *cache[K, V]) Get(key K) (val V, err error) {
+	if v, ok := c.data.Load(key); ok {
+		e := v.(*entry[V])
+		if !e.negativeExpired() {
+			e.Touch()
+			c.touch(key)
+			c.counters.hits.Add(1)
+			return e.Load(), e.err.Load()
+		}
 	}
+	c.counters.misses.Add(1)
 
-	val, err, _ = c.sfg.Do(key, func() (v interface{}, e error) {
-		v, e = c.opt.Fetcher(key)
-		ety := &entry{}
+	v, err, _ := c.sfg.Do(c.flightKey(key), func() (interface{}, error) {
+		v, e := c.load(key)
+		ety := &entry[V]{}
 		ety.Store(v, e)
+		if errors.Is(e, ErrNotFound) && c.opt.NegativeTTL > 0 {
+			ety.markNegative(c.opt.NegativeTTL)
+		}
 		c.data.Store(key, ety)
-		return
+		c.admit(key, v, true)
+		return v, e
 	})
-	return
+	val, _ = v.(V)
+	return val, err
 }
 
 // GetOrSet tries to fetch a value corresponding to the given key from the cache.
 // If the key is not yet cached or fetching failed, the default value will be set.
-func (c *asyncCache) GetOrSet(key string, def interface{}) (val interface{}) {
+func (c *cache[K, V]) GetOrSet(key K, def V) (val V) {
 	if v, ok := c.data.Load(key); ok {
-		e := v.(*entry)
+		e := v.(*entry[V])
 		if e.err.Load() != nil {
-			ety := &entry{}
+			ety := &entry[V]{}
 			ety.Store(def, nil)
 			c.data.Store(key, ety)
+			c.admit(key, def, true)
 			return def
 		}
 		e.Touch()
-		return e.val.Load()
+		c.touch(key)
+		c.counters.hits.Add(1)
+		return e.Load()
 	}
+	c.counters.misses.Add(1)
 
-	val, _, _ = c.sfg.Do(key, func() (interface{}, error) {
-		v, e := c.opt.Fetcher(key)
+	v, _, _ := c.sfg.Do(c.flightKey(key), func() (interface{}, error) {
+		v, e := c.load(key)
 		if e != nil {
 			v = def
 		}
-		ety := &entry{}
+		ety := &entry[V]{}
 		ety.Store(v, nil)
 		c.data.Store(key, ety)
+		c.admit(key, v, true)
 		return v, nil
 	})
-	return
+	val, _ = v.(V)
+	return val
+}
+
+// flightKey turns a key into the string singleflight needs. String keys are used as is.
+func (c *cache[K, V]) flightKey(key K) string {
+	if s, ok := any(key).(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%#v", key)
 }
 
 // Dump dumps all cached entries.
-func (c *asyncCache) Dump() map[string]interface{} {
-	data := make(map[string]interface{})
+func (c *cache[K, V]) Dump() map[K]V {
+	data := make(map[K]V)
 	c.data.Range(func(key, val interface{}) bool {
-		k, ok := key.(string)
-		if !ok {
-			c.opt.ErrLogFunc(fmt.Sprintf("invalid key: %v, type: %T is not string", k, k))
-			c.data.Delete(key)
-			return true
-		}
-		data[k] = val.(*entry).val.Load()
+		data[key.(K)] = val.(*entry[V]).Load()
 		return true
 	})
 	return data
 }
 
 // DeleteIf deletes cached entries that match the `shouldDelete` predicate.
-func (c *asyncCache) DeleteIf(shouldDelete func(key string) bool) {
+func (c *cache[K, V]) DeleteIf(shouldDelete func(key K) bool) {
 	c.data.Range(func(key, value interface{}) bool {
-		s := key.(string)
-		if shouldDelete(s) {
+		k := key.(K)
+		if shouldDelete(k) {
 			if c.opt.DeleteHandler != nil {
-				go c.opt.DeleteHandler(s, value)
+				go c.opt.DeleteHandler(k, value.(*entry[V]).Load())
 			}
 			c.data.Delete(key)
+			c.forget(k)
 		}
 		return true
 	})
 }
 
-// Close stops the background goroutine.
-func (c *asyncCache) Close() {
-	// close refresh ticker
-	ti, _ := refreshTickerMap.Load(c.opt.RefreshDuration)
-	rt := ti.(*sharedTicker)
-	rt.Lock()
-	delete(rt.caches, c)
-	if len(rt.caches) == 0 {
-		rt.stopChan <- true
-		rt.started = false
+// admit accounts a stored value against the size bounds and evicts what no longer fits.
+func (c *cache[K, V]) admit(key K, val V, access bool) {
+	if c.bounds == nil {
+		return
 	}
-	rt.Unlock()
-
-	if c.opt.EnableExpire {
-		// close expire ticker
-		ti, _ := expireTickerMap.Load(c.opt.ExpireDuration)
-		et := ti.(*sharedTicker)
-		et.Lock()
-		delete(et.caches, c)
-		if len(et.caches) == 0 {
-			et.stopChan <- true
-			et.started = false
+	for _, victim := range c.bounds.set(key, val, access) {
+		value, ok := c.data.LoadAndDelete(victim)
+		if !ok {
+			continue
+		}
+		c.evictions.Add(1)
+		if c.opt.DeleteHandler != nil {
+			go c.opt.DeleteHandler(victim, value.(*entry[V]).Load())
 		}
-		et.Unlock()
 	}
 }
 
-// tick .
-// pass ticker but not use t.ticker directly is to ignore race.
-func (t *sharedTicker) tick(ticker *time.Ticker, tt tickerType) {
-	var wg sync.WaitGroup
-	defer ticker.Stop()
+func (c *cache[K, V]) touch(key K) {
+	if c.bounds != nil {
+		c.bounds.touch(key)
+	}
+}
+
+func (c *cache[K, V]) forget(key K) {
+	if c.bounds != nil {
+		c.bounds.remove(key)
+	}
+}
+
+// Close stops the background goroutines.
+func (c *cache[K, V]) Close() {
+	c.cancel()
+	unregister(c)
+}
+
+// loop runs fn every interval, moved by up to jitter of it either way, until the cache
+// is closed. Every cache has its own loops, so a slow backend only delays its own rounds.
+func (c *cache[K, V]) loop(interval time.Duration, jitter float64, fn func()) {
 	for {
+		wait := interval
+		if jitter > 0 {
+			wait += time.Duration((rand.Float64()*2 - 1) * jitter * float64(interval))
+		}
+		timer := time.NewTimer(wait)
 		select {
-		case <-ticker.C:
-			t.Lock()
-			for c := range t.caches {
-				wg.Add(1)
-				go func(c *asyncCache) {
-					defer wg.Done()
-					if tt == expireTicker {
-						c.expire()
-					} else {
-						c.refresh()
-					}
-				}(c)
-			}
-			wg.Wait()
-			t.Unlock()
-		case stop := <-t.stopChan:
-			if stop {
-				return
-			}
+		case <-c.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
 		}
+		fn()
+	}
+}
+
+// load fetches a missing key, through the batcher if there is one.
+func (c *cache[K, V]) load(key K) (V, error) {
+	if c.batcher != nil {
+		return c.batcher.load(key)
 	}
+	return c.fetch(context.Background(), key)
 }
 
-func (c *asyncCache) expire() {
+func (c *cache[K, V]) fetch(ctx context.Context, key K) (V, error) {
+	if c.opt.FetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opt.FetchTimeout)
+		defer cancel()
+	}
+	start := time.Now()
+	val, err := c.opt.Fetcher(ctx, key)
+	c.fetched(start, err)
+	return val, err
+}
+
+func (c *cache[K, V]) expire() {
 	c.data.Range(func(key, value interface{}) bool {
-		k, ok := key.(string)
-		if !ok {
-			c.opt.ErrLogFunc(fmt.Sprintf("invalid key: %v, type: %T is not string", k, k))
-			c.data.Delete(key)
-			return true
-		}
-		e, ok := value.(*entry)
-		if !ok {
-			c.opt.ErrLogFunc(fmt.Sprintf("invalid key: %v, type: %T is not entry", k, value))
-			c.data.Delete(key)
-			return true
-		}
+		e := value.(*entry[V])
 		if !atomic.CompareAndSwapInt32(&e.expire, 0, 1) {
 			if c.opt.DeleteHandler != nil {
-				go c.opt.DeleteHandler(k, value)
+				go c.opt.DeleteHandler(key.(K), e.Load())
 			}
 			c.data.Delete(key)
+			c.forget(key.(K))
 		}
 
 		return true
 	})
 }
 
-func (c *asyncCache) refresh() {
+func (c *cache[K, V]) refresh() {
+	start := time.Now()
+	defer func() {
+		c.counters.lastRefresh.Store(int64(time.Since(start)))
+	}()
+
+	concurrency := c.opt.RefreshConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	run := func(fn func()) bool {
+		select {
+		case sem <- struct{}{}:
+		case <-c.ctx.Done():
+			return false
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn()
+		}()
+		return true
+	}
+
+	var keys []K
+	var entries []*entry[V]
 	c.data.Range(func(key, value interface{}) bool {
-		k, ok := key.(string)
-		if !ok {
-			c.opt.ErrLogFunc(fmt.Sprintf("invalid key: %v, type: %T is not string", k, k))
-			c.data.Delete(key)
+		k, e := key.(K), value.(*entry[V])
+		// not found entries are only fetched again once their ttl is over
+		if e.isNegative() {
+			if e.negativeExpired() && c.data.CompareAndDelete(key, value) {
+				c.forget(k)
+			}
 			return true
 		}
-		e, ok := value.(*entry)
-		if !ok {
-			c.opt.ErrLogFunc(fmt.Sprintf("invalid key: %v, type: %T is not entry", k, value))
-			c.data.Delete(key)
+		if c.batcher == nil {
+			return run(func() { c.refreshEntry(k, e) })
+		}
+		keys = append(keys, k)
+		entries = append(entries, e)
+		if len(keys) < c.batchSize() {
 			return true
 		}
+		batchKeys, batchEntries := keys, entries
+		keys, entries = nil, nil
+		return run(func() { c.refreshBatch(batchKeys, batchEntries) })
+	})
+	if len(keys) > 0 {
+		run(func() { c.refreshBatch(keys, entries) })
+	}
+	wg.Wait()
+}
 
-		newVal, err := c.opt.Fetcher(k)
-		if err != nil {
-			if c.opt.ErrorHandler != nil {
-				go c.opt.ErrorHandler(k, err)
-			}
-			if e.err.Load() != nil {
-				e.err.Store(err)
-			}
-			return true
+func (c *cache[K, V]) refreshEntry(k K, e *entry[V]) {
+	newVal, err := c.fetch(c.ctx, k)
+	c.applyRefresh(k, e, newVal, err)
+}
+
+func (c *cache[K, V]) applyRefresh(k K, e *entry[V], newVal V, err error) {
+	if err != nil {
+		if errors.Is(err, ErrNotFound) && c.opt.NegativeTTL > 0 {
+			e.Store(newVal, err)
+			e.markNegative(c.opt.NegativeTTL)
+			return
+		}
+		if c.opt.ErrorHandler != nil {
+			go c.opt.ErrorHandler(k, err)
 		}
+		if e.err.Load() != nil {
+			e.err.Store(err)
+		}
+		return
+	}
 
-		if c.opt.IsSame != nil && !c.opt.IsSame(k, e.val.Load(), newVal) {
-			if c.opt.ChangeHandler != nil {
-				go c.opt.ChangeHandler(k, e.val.Load(), newVal)
-			}
+	if c.opt.IsSame != nil && !c.opt.IsSame(k, e.Load(), newVal) {
+		if c.opt.ChangeHandler != nil {
+			go c.opt.ChangeHandler(k, e.Load(), newVal)
 		}
+	}
 
-		e.Store(newVal, err)
-		return true
-	})
+	e.Store(newVal, err)
+	c.admit(k, newVal, false)
 }
@@ -1,7 +1,10 @@
 package asynccache
 
 import (
+	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -384,3 +387,138 @@ func BenchmarkRefreshParallel(b *testing.B) {
 		}
 	})
 }
+
+func TestCacheTyped(t *testing.T) {
+	type tokenKey struct {
+		chain string
+		addr  string
+	}
+	var calls int
+	c := NewCache(CacheOptions[tokenKey, *int]{
+		RefreshDuration: time.Minute,
+		Fetcher: func(ctx context.Context, key tokenKey) (*int, error) {
+			calls++
+			if key.chain == "bad" {
+				return nil, errors.New("error")
+			}
+			n := len(key.chain) + len(key.addr)
+			return &n, nil
+		},
+	})
+	defer c.Close()
+
+	v, err := c.Get(tokenKey{chain: "eth", addr: "0x1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 6, *v)
+	v, err = c.Get(tokenKey{chain: "eth", addr: "0x1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 6, *v)
+	assert.Equal(t, 1, calls)
+
+	v, err = c.Get(tokenKey{chain: "bad"})
+	assert.Error(t, err)
+	assert.Nil(t, v)
+
+	def := 0
+	assert.Equal(t, &def, c.GetOrSet(tokenKey{chain: "bad"}, &def))
+
+	deleted := make(chan tokenKey, 2)
+	c2 := NewCache(CacheOptions[int, string]{
+		RefreshDuration: time.Minute,
+		Fetcher: func(ctx context.Context, key int) (string, error) {
+			return "", errors.New("error")
+		},
+		DeleteHandler: func(key int, oldData string) {
+			assert.Equal(t, "one", oldData)
+			deleted <- tokenKey{}
+		},
+	})
+	defer c2.Close()
+	c2.SetDefault(1, "one")
+	c2.SetDefault(2, "two")
+	assert.Equal(t, map[int]string{1: "one", 2: "two"}, c2.Dump())
+	c2.DeleteIf(func(key int) bool { return key == 1 })
+	<-deleted
+	assert.Equal(t, map[int]string{2: "two"}, c2.Dump())
+}
+
+func TestFetchTimeout(t *testing.T) {
+	c := NewCache(CacheOptions[string, string]{
+		RefreshDuration: time.Minute,
+		FetchTimeout:    20 * time.Millisecond,
+		Fetcher: func(ctx context.Context, key string) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		},
+	})
+	defer c.Close()
+
+	start := time.Now()
+	_, err := c.Get("key")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRefreshConcurrency(t *testing.T) {
+	var running, maxRunning int32
+	var mu sync.Mutex
+	c := NewCache(CacheOptions[int, int]{
+		RefreshDuration:    time.Minute,
+		RefreshConcurrency: 3,
+		Fetcher: func(ctx context.Context, key int) (int, error) {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return key, nil
+		},
+	}).(*cache[int, int])
+	defer c.Close()
+
+	for i := 0; i < 10; i++ {
+		c.SetDefault(i, 0)
+	}
+	c.refresh()
+	assert.Equal(t, int32(3), maxRunning)
+	for i := 0; i < 10; i++ {
+		v, _ := c.Get(i)
+		assert.Equal(t, i, v)
+	}
+}
+
+func TestSlowCacheDoesNotBlockOthers(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := NewCache(CacheOptions[string, string]{
+		RefreshDuration: 50 * time.Millisecond,
+		Fetcher: func(ctx context.Context, key string) (string, error) {
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return "slow", nil
+		},
+	})
+	defer slow.Close()
+	slow.SetDefault("key", "")
+
+	var refreshed atomic.Int32
+	fast := NewCache(CacheOptions[string, string]{
+		RefreshDuration: 50 * time.Millisecond,
+		Fetcher: func(ctx context.Context, key string) (string, error) {
+			refreshed.Add(1)
+			return "fast", nil
+		},
+	})
+	defer fast.Close()
+	fast.SetDefault("key", "")
+
+	time.Sleep(300 * time.Millisecond)
+	assert.GreaterOrEqual(t, refreshed.Load(), int32(3))
+}
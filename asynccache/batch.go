@@ -0,0 +1,130 @@
+package asynccache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound tells the cache a key does not exist in the backend. Fetchers return it, and
+// BatchFetchers imply it for keys missing from their result. With NegativeTTL set, such
+// keys are remembered as missing for that long instead of being fetched again.
+var ErrNotFound = errors.New("asynccache: key not found")
+
+const (
+	DefaultBatchSize = 100
+	DefaultBatchWait = 5 * time.Millisecond
+)
+
+// BatchFetcher loads many keys in one call. Keys absent from the result are not found;
+// an error fails every key of the batch.
+type BatchFetcher[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+type batchCall[K comparable, V any] struct {
+	key  K
+	val  V
+	err  error
+	done chan struct{}
+}
+
+// batcher gathers concurrent misses for up to BatchWait or BatchSize keys and loads them
+// with a single BatchFetcher call.
+type batcher[K comparable, V any] struct {
+	c       *cache[K, V]
+	mutex   sync.Mutex
+	pending []*batchCall[K, V]
+}
+
+func (b *batcher[K, V]) load(key K) (V, error) {
+	call := &batchCall[K, V]{key: key, done: make(chan struct{})}
+	b.mutex.Lock()
+	b.pending = append(b.pending, call)
+	switch {
+	case len(b.pending) >= b.c.batchSize():
+		calls := b.pending
+		b.pending = nil
+		b.mutex.Unlock()
+		go b.run(calls)
+	case len(b.pending) == 1:
+		b.mutex.Unlock()
+		time.AfterFunc(b.c.batchWait(), b.flush)
+	default:
+		b.mutex.Unlock()
+	}
+	<-call.done
+	return call.val, call.err
+}
+
+func (b *batcher[K, V]) flush() {
+	b.mutex.Lock()
+	calls := b.pending
+	b.pending = nil
+	b.mutex.Unlock()
+	if len(calls) > 0 {
+		b.run(calls)
+	}
+}
+
+func (b *batcher[K, V]) run(calls []*batchCall[K, V]) {
+	keys := make([]K, 0, len(calls))
+	seen := make(map[K]struct{}, len(calls))
+	for _, call := range calls {
+		if _, ok := seen[call.key]; !ok {
+			seen[call.key] = struct{}{}
+			keys = append(keys, call.key)
+		}
+	}
+	results, err := b.c.batchFetch(context.Background(), keys)
+	for _, call := range calls {
+		call.val, call.err = batchResult(results, err, call.key)
+		close(call.done)
+	}
+}
+
+// batchResult picks the outcome of one key out of a BatchFetcher call.
+func batchResult[K comparable, V any](results map[K]V, err error, key K) (V, error) {
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	if val, ok := results[key]; ok {
+		return val, nil
+	}
+	var zero V
+	return zero, ErrNotFound
+}
+
+func (c *cache[K, V]) batchSize() int {
+	if c.opt.BatchSize > 0 {
+		return c.opt.BatchSize
+	}
+	return DefaultBatchSize
+}
+
+func (c *cache[K, V]) batchWait() time.Duration {
+	if c.opt.BatchWait > 0 {
+		return c.opt.BatchWait
+	}
+	return DefaultBatchWait
+}
+
+func (c *cache[K, V]) batchFetch(ctx context.Context, keys []K) (map[K]V, error) {
+	if c.opt.FetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opt.FetchTimeout)
+		defer cancel()
+	}
+	start := time.Now()
+	results, err := c.opt.BatchFetcher(ctx, keys)
+	c.fetched(start, err)
+	return results, err
+}
+
+func (c *cache[K, V]) refreshBatch(keys []K, entries []*entry[V]) {
+	results, err := c.batchFetch(c.ctx, keys)
+	for i, k := range keys {
+		val, err := batchResult(results, err, k)
+		c.applyRefresh(k, entries[i], val, err)
+	}
+}
@@ -0,0 +1,110 @@
+package asynccache
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchFetcher(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]string
+	c := NewCache(CacheOptions[string, string]{
+		RefreshDuration: time.Minute,
+		BatchSize:       3,
+		BatchWait:       20 * time.Millisecond,
+		NegativeTTL:     time.Minute,
+		BatchFetcher: func(ctx context.Context, keys []string) (map[string]string, error) {
+			sorted := append([]string(nil), keys...)
+			sort.Strings(sorted)
+			mu.Lock()
+			batches = append(batches, sorted)
+			mu.Unlock()
+			result := make(map[string]string)
+			for _, key := range keys {
+				if !strings.HasPrefix(key, "missing") {
+					result[key] = "v-" + key
+				}
+			}
+			return result, nil
+		},
+	}).(*cache[string, string])
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b", "missing"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			c.Get(key)
+		}(key)
+	}
+	wg.Wait()
+	assert.Equal(t, [][]string{{"a", "b", "missing"}}, batches)
+
+	v, err := c.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "v-a", v)
+	_, err = c.Get("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Len(t, batches, 1)
+
+	// refresh skips the negative entry and fetches the rest in batches
+	batches = nil
+	c.SetDefault("c", "")
+	c.SetDefault("d", "")
+	c.refresh()
+	assert.Len(t, batches, 2)
+	all := make([]string, 0)
+	for _, batch := range batches {
+		assert.LessOrEqual(t, len(batch), 3)
+		all = append(all, batch...)
+	}
+	sort.Strings(all)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, all)
+	v, _ = c.Get("d")
+	assert.Equal(t, "v-d", v)
+}
+
+func TestNegativeTTL(t *testing.T) {
+	var calls atomic.Int32
+	found := atomic.Bool{}
+	c := NewCache(CacheOptions[string, string]{
+		RefreshDuration: time.Minute,
+		NegativeTTL:     50 * time.Millisecond,
+		Fetcher: func(ctx context.Context, key string) (string, error) {
+			calls.Add(1)
+			if !found.Load() {
+				return "", ErrNotFound
+			}
+			return "v", nil
+		},
+	})
+	defer c.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := c.Get("key")
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+
+	found.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	v, err := c.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "v", v)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// a key that disappears on refresh becomes a negative entry
+	found.Store(false)
+	c.(*cache[string, string]).refresh()
+	_, err = c.Get("key")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, int32(3), calls.Load())
+}
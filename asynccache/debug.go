@@ -0,0 +1,211 @@
+package asynccache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultDebugLimit = 1000
+
+// EntryInfo describes one cached key for DebugHandler.
+type EntryInfo struct {
+	Key        string  `json:"key"`
+	AgeSeconds float64 `json:"age_seconds"`
+	Error      string  `json:"error,omitempty"`
+	Negative   bool    `json:"negative,omitempty"`
+}
+
+// inspectable is the untyped view of a named cache that DebugHandler works with. Keys
+// are matched by their fmt.Sprint form.
+type inspectable interface {
+	Stats() CacheStats
+	entries(limit int) []EntryInfo
+	refreshKey(key string) error
+	deleteKey(key string) bool
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]inspectable)
+)
+
+func register[K comparable, V any](c *cache[K, V]) {
+	if c.opt.Name == "" {
+		return
+	}
+	registryMutex.Lock()
+	registry[c.opt.Name] = c
+	registryMutex.Unlock()
+}
+
+func unregister[K comparable, V any](c *cache[K, V]) {
+	if c.opt.Name == "" {
+		return
+	}
+	registryMutex.Lock()
+	if registry[c.opt.Name] == inspectable(c) {
+		delete(registry, c.opt.Name)
+	}
+	registryMutex.Unlock()
+}
+
+func lookup(name string) (inspectable, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	c, ok := registry[name]
+	return c, ok
+}
+
+func (c *cache[K, V]) entries(limit int) []EntryInfo {
+	now := time.Now().UnixNano()
+	infos := make([]EntryInfo, 0)
+	c.data.Range(func(key, value interface{}) bool {
+		e := value.(*entry[V])
+		info := EntryInfo{
+			Key:        fmt.Sprint(key),
+			AgeSeconds: time.Duration(now - e.updatedAt.Load()).Seconds(),
+			Negative:   e.isNegative(),
+		}
+		if err := e.err.Load(); err != nil {
+			info.Error = err.Error()
+		}
+		infos = append(infos, info)
+		return len(infos) < limit
+	})
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	return infos
+}
+
+func (c *cache[K, V]) findKey(key string) (K, *entry[V], bool) {
+	var found K
+	var foundEntry *entry[V]
+	ok := false
+	c.data.Range(func(k, value interface{}) bool {
+		if fmt.Sprint(k) == key {
+			found, foundEntry, ok = k.(K), value.(*entry[V]), true
+			return false
+		}
+		return true
+	})
+	return found, foundEntry, ok
+}
+
+func (c *cache[K, V]) refreshKey(key string) error {
+	k, e, ok := c.findKey(key)
+	if !ok {
+		return ErrNotFound
+	}
+	var val V
+	var err error
+	if c.opt.BatchFetcher != nil {
+		var results map[K]V
+		results, err = c.batchFetch(c.ctx, []K{k})
+		val, err = batchResult(results, err, k)
+	} else {
+		val, err = c.fetch(c.ctx, k)
+	}
+	c.applyRefresh(k, e, val, err)
+	return err
+}
+
+func (c *cache[K, V]) deleteKey(key string) bool {
+	k, _, ok := c.findKey(key)
+	if !ok {
+		return false
+	}
+	c.DeleteIf(func(candidate K) bool { return candidate == k })
+	return true
+}
+
+// DebugHandler serves the named caches of this process:
+//
+//	GET                               stats of every cache
+//	GET  ?cache=<name>[&limit=n]      stats and entries (key, age, error) of one cache
+//	POST ?cache=<name>&key=<key>&action=refresh|delete
+//
+// Mount it on an internal port only; it can drop and reload cache entries.
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(serveDebug)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func serveDebug(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("cache")
+	if name == "" {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "cache is required"})
+			return
+		}
+		registryMutex.RLock()
+		caches := make(map[string]inspectable, len(registry))
+		for n, c := range registry {
+			caches[n] = c
+		}
+		registryMutex.RUnlock()
+		stats := make(map[string]CacheStats, len(caches))
+		for n, c := range caches {
+			stats[n] = c.Stats()
+		}
+		writeJSON(w, http.StatusOK, stats)
+		return
+	}
+
+	c, ok := lookup(name)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown cache " + name})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		limit := defaultDebugLimit
+		if s := r.URL.Query().Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+				return
+			}
+			limit = n
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"stats":   c.Stats(),
+			"entries": c.entries(limit),
+		})
+	case http.MethodPost:
+		key := r.URL.Query().Get("key")
+		switch r.URL.Query().Get("action") {
+		case "refresh":
+			err := c.refreshKey(key)
+			if errors.Is(err, ErrNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]string{"result": "refreshed"})
+		case "delete":
+			if !c.deleteKey(key) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": ErrNotFound.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]string{"result": "deleted"})
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "action must be refresh or delete"})
+		}
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
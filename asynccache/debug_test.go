@@ -0,0 +1,98 @@
+package asynccache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	c := NewCache(CacheOptions[string, string]{
+		RefreshDuration: time.Minute,
+		NegativeTTL:     time.Minute,
+		Fetcher: func(ctx context.Context, key string) (string, error) {
+			switch key {
+			case "missing":
+				return "", ErrNotFound
+			case "broken":
+				return "", errors.New("db down")
+			}
+			return "v", nil
+		},
+	})
+	defer c.Close()
+
+	c.Get("a")
+	c.Get("a")
+	c.Get("missing")
+	c.Get("broken")
+	c.GetOrSet("a", "def")
+
+	stats := c.Stats()
+	assert.Equal(t, 3, stats.Entries)
+	assert.Equal(t, 1, stats.ErrorEntries)
+	assert.Equal(t, 1, stats.NegativeEntries)
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(3), stats.Misses)
+	assert.Equal(t, uint64(1), stats.FetchErrors)
+}
+
+func TestDebugHandler(t *testing.T) {
+	var version int
+	c := NewCache(CacheOptions[int, int]{
+		Name:            "debug-test",
+		RefreshDuration: time.Minute,
+		Fetcher: func(ctx context.Context, key int) (int, error) {
+			version++
+			return key * version, nil
+		},
+	})
+	defer c.Close()
+	c.Get(7)
+	c.Get(8)
+
+	handler := DebugHandler()
+	do := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	w := do(http.MethodGet, "/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var all map[string]CacheStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &all))
+	assert.Equal(t, 2, all["debug-test"].Entries)
+
+	w = do(http.MethodGet, "/?cache=debug-test")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var one struct {
+		Entries []EntryInfo `json:"entries"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &one))
+	if assert.Len(t, one.Entries, 2) {
+		assert.Equal(t, "7", one.Entries[0].Key)
+		assert.GreaterOrEqual(t, one.Entries[0].AgeSeconds, 0.0)
+	}
+
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/?cache=debug-test&key=7&action=refresh").Code)
+	v, _ := c.Get(7)
+	assert.Equal(t, 21, v)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/?cache=debug-test&key=8&action=delete").Code)
+	assert.NotContains(t, c.Dump(), 8)
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/?cache=debug-test&key=9&action=delete").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/?cache=nope").Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/?cache=debug-test&key=7&action=drop").Code)
+
+	c.Close()
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/?cache=debug-test").Code)
+}
@@ -0,0 +1,242 @@
+package asynccache
+
+import (
+	"container/heap"
+	"container/list"
+	"sync"
+)
+
+// EvictionPolicy chooses which entry a bounded cache drops when it is full.
+type EvictionPolicy int
+
+const (
+	// EvictLRU drops the least recently used entry.
+	EvictLRU EvictionPolicy = iota
+	// EvictLFU drops the least frequently used entry, the least recently used one on ties.
+	EvictLFU
+)
+
+func (p EvictionPolicy) String() string {
+	switch p {
+	case EvictLRU:
+		return "lru"
+	case EvictLFU:
+		return "lfu"
+	}
+	return "unknown"
+}
+
+// evictor tracks key usage for one policy. It is not safe for concurrent use.
+type evictor[K comparable] interface {
+	add(key K)
+	touch(key K)
+	remove(key K)
+	victim() (K, bool)
+}
+
+func newEvictor[K comparable](policy EvictionPolicy) evictor[K] {
+	if policy == EvictLFU {
+		return &lfuEvictor[K]{items: make(map[K]*lfuItem[K])}
+	}
+	return &lruEvictor[K]{order: list.New(), items: make(map[K]*list.Element)}
+}
+
+type lruEvictor[K comparable] struct {
+	// front is the most recently used
+	order *list.List
+	items map[K]*list.Element
+}
+
+func (l *lruEvictor[K]) add(key K) {
+	if elem, ok := l.items[key]; ok {
+		l.order.MoveToFront(elem)
+		return
+	}
+	l.items[key] = l.order.PushFront(key)
+}
+
+func (l *lruEvictor[K]) touch(key K) {
+	if elem, ok := l.items[key]; ok {
+		l.order.MoveToFront(elem)
+	}
+}
+
+func (l *lruEvictor[K]) remove(key K) {
+	if elem, ok := l.items[key]; ok {
+		l.order.Remove(elem)
+		delete(l.items, key)
+	}
+}
+
+func (l *lruEvictor[K]) victim() (K, bool) {
+	if elem := l.order.Back(); elem != nil {
+		return elem.Value.(K), true
+	}
+	var zero K
+	return zero, false
+}
+
+type lfuItem[K comparable] struct {
+	key   K
+	freq  uint64
+	seq   uint64
+	index int
+}
+
+// lfuEvictor is a min heap on (frequency, last access).
+type lfuEvictor[K comparable] struct {
+	heap  []*lfuItem[K]
+	items map[K]*lfuItem[K]
+	seq   uint64
+}
+
+func (l *lfuEvictor[K]) Len() int { return len(l.heap) }
+
+func (l *lfuEvictor[K]) Less(i, j int) bool {
+	if l.heap[i].freq != l.heap[j].freq {
+		return l.heap[i].freq < l.heap[j].freq
+	}
+	return l.heap[i].seq < l.heap[j].seq
+}
+
+func (l *lfuEvictor[K]) Swap(i, j int) {
+	l.heap[i], l.heap[j] = l.heap[j], l.heap[i]
+	l.heap[i].index = i
+	l.heap[j].index = j
+}
+
+func (l *lfuEvictor[K]) Push(x any) {
+	item := x.(*lfuItem[K])
+	item.index = len(l.heap)
+	l.heap = append(l.heap, item)
+}
+
+func (l *lfuEvictor[K]) Pop() any {
+	n := len(l.heap)
+	item := l.heap[n-1]
+	l.heap[n-1] = nil
+	l.heap = l.heap[:n-1]
+	return item
+}
+
+func (l *lfuEvictor[K]) add(key K) {
+	if _, ok := l.items[key]; ok {
+		l.touch(key)
+		return
+	}
+	l.seq++
+	item := &lfuItem[K]{key: key, freq: 1, seq: l.seq}
+	l.items[key] = item
+	heap.Push(l, item)
+}
+
+func (l *lfuEvictor[K]) touch(key K) {
+	if item, ok := l.items[key]; ok {
+		l.seq++
+		item.freq++
+		item.seq = l.seq
+		heap.Fix(l, item.index)
+	}
+}
+
+func (l *lfuEvictor[K]) remove(key K) {
+	if item, ok := l.items[key]; ok {
+		heap.Remove(l, item.index)
+		delete(l.items, key)
+	}
+}
+
+func (l *lfuEvictor[K]) victim() (K, bool) {
+	if len(l.heap) > 0 {
+		return l.heap[0].key, true
+	}
+	var zero K
+	return zero, false
+}
+
+// bounds enforces MaxEntries and MaxCost of a cache.
+type bounds[K comparable, V any] struct {
+	sync.Mutex
+	maxEntries int
+	maxCost    int64
+	costFunc   func(key K, val V) int64
+	evictor    evictor[K]
+	costs      map[K]int64
+	cost       int64
+}
+
+func newBounds[K comparable, V any](opt CacheOptions[K, V]) *bounds[K, V] {
+	if opt.MaxEntries <= 0 && opt.MaxCost <= 0 {
+		return nil
+	}
+	return &bounds[K, V]{
+		maxEntries: opt.MaxEntries,
+		maxCost:    opt.MaxCost,
+		costFunc:   opt.Cost,
+		evictor:    newEvictor[K](opt.EvictionPolicy),
+		costs:      make(map[K]int64),
+	}
+}
+
+func (b *bounds[K, V]) full() bool {
+	return (b.maxEntries > 0 && len(b.costs) > b.maxEntries) || (b.maxCost > 0 && b.cost > b.maxCost)
+}
+
+// set records key with the cost of val, counting as a use if access is set, and
+// returns the keys that have to go to get back within bounds.
+func (b *bounds[K, V]) set(key K, val V, access bool) []K {
+	cost := int64(1)
+	if b.costFunc != nil {
+		cost = b.costFunc(key, val)
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	if old, ok := b.costs[key]; ok {
+		b.cost -= old
+		if access {
+			b.evictor.touch(key)
+		}
+	} else {
+		b.evictor.add(key)
+	}
+	b.costs[key] = cost
+	b.cost += cost
+
+	var victims []K
+	for b.full() {
+		victim, ok := b.evictor.victim()
+		if !ok {
+			break
+		}
+		b.removeLocked(victim)
+		victims = append(victims, victim)
+	}
+	return victims
+}
+
+func (b *bounds[K, V]) touch(key K) {
+	b.Lock()
+	b.evictor.touch(key)
+	b.Unlock()
+}
+
+func (b *bounds[K, V]) remove(key K) {
+	b.Lock()
+	b.removeLocked(key)
+	b.Unlock()
+}
+
+func (b *bounds[K, V]) removeLocked(key K) {
+	if cost, ok := b.costs[key]; ok {
+		b.cost -= cost
+		delete(b.costs, key)
+		b.evictor.remove(key)
+	}
+}
+
+func (b *bounds[K, V]) size() (int, int64) {
+	b.Lock()
+	defer b.Unlock()
+	return len(b.costs), b.cost
+}
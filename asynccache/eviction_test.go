@@ -0,0 +1,115 @@
+package asynccache
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newBoundedCache(t *testing.T, opt CacheOptions[string, string]) (Cache[string, string], func() []string) {
+	var mu sync.Mutex
+	var deleted []string
+	opt.RefreshDuration = time.Minute
+	opt.Fetcher = func(ctx context.Context, key string) (string, error) {
+		return "v-" + key, nil
+	}
+	opt.DeleteHandler = func(key string, oldData string) {
+		mu.Lock()
+		deleted = append(deleted, key)
+		mu.Unlock()
+	}
+	c := NewCache(opt)
+	t.Cleanup(c.Close)
+	return c, func() []string {
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		result := append([]string(nil), deleted...)
+		sort.Strings(result)
+		return result
+	}
+}
+
+func keys(c Cache[string, string]) []string {
+	result := make([]string, 0)
+	for k := range c.Dump() {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestEvictLRU(t *testing.T) {
+	c, deleted := newBoundedCache(t, CacheOptions[string, string]{MaxEntries: 3, EvictionPolicy: EvictLRU})
+
+	c.Get("a")
+	c.Get("b")
+	c.Get("c")
+	c.Get("a")
+	c.Get("d")
+	assert.Equal(t, []string{"a", "c", "d"}, keys(c))
+	assert.Equal(t, []string{"b"}, deleted())
+
+	c.SetDefault("e", "x")
+	assert.Equal(t, []string{"a", "d", "e"}, keys(c))
+
+	stats := c.Stats()
+	assert.Equal(t, 3, stats.Entries)
+	assert.Equal(t, uint64(2), stats.Evictions)
+}
+
+func TestEvictLFU(t *testing.T) {
+	c, deleted := newBoundedCache(t, CacheOptions[string, string]{MaxEntries: 2, EvictionPolicy: EvictLFU})
+
+	c.Get("a")
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	c.Get("b")
+	c.Get("c")
+	assert.Equal(t, []string{"a", "b"}, keys(c))
+	assert.Equal(t, []string{"c"}, deleted())
+
+	// ties go to the least recently used
+	c.DeleteIf(func(key string) bool { return key == "a" })
+	c.Get("d")
+	c.Get("e")
+	assert.Equal(t, []string{"b", "e"}, keys(c))
+}
+
+func TestEvictMaxCost(t *testing.T) {
+	c, deleted := newBoundedCache(t, CacheOptions[string, string]{
+		MaxCost: 10,
+		Cost: func(key string, val string) int64 {
+			return int64(len(val))
+		},
+	})
+
+	c.Get("a")   // v-a, 3
+	c.Get("bb")  // v-bb, 4
+	c.Get("ccc") // v-ccc, 5
+	assert.Equal(t, []string{"bb", "ccc"}, keys(c))
+	assert.Equal(t, []string{"a"}, deleted())
+	assert.Equal(t, int64(9), c.Stats().Cost)
+
+	// an entry larger than the whole budget is not kept
+	v, err := c.Get("dddddddddd")
+	assert.NoError(t, err)
+	assert.Equal(t, "v-dddddddddd", v)
+	assert.Empty(t, keys(c))
+	assert.Equal(t, uint64(4), c.Stats().Evictions)
+}
+
+func TestUnboundedStats(t *testing.T) {
+	c, _ := newBoundedCache(t, CacheOptions[string, string]{})
+	c.Get("a")
+	c.Get("b")
+	stats := c.Stats()
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, int64(2), stats.Cost)
+	assert.Equal(t, uint64(0), stats.Evictions)
+}
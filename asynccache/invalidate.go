@@ -0,0 +1,26 @@
+package asynccache
+
+import (
+	"fmt"
+
+	"github.com/realcaishen/utils-go/invalidate"
+)
+
+// InvalidateOn drops the entries of c matched by the messages bus delivers for topic,
+// so an update published by one instance reaches every replica before its next refresh.
+// keyString renders a key the way publishers name it and defaults to fmt.Sprint.
+func InvalidateOn[K comparable, V any](c Cache[K, V], bus invalidate.Bus, topic string, keyString func(key K) string) (unsubscribe func()) {
+	if keyString == nil {
+		keyString = func(key K) string { return fmt.Sprint(key) }
+	}
+	return bus.Subscribe(topic, func(msg invalidate.Message) {
+		c.DeleteIf(func(key K) bool { return msg.Matches(keyString(key)) })
+	})
+}
+
+// InvalidateAsyncCacheOn is InvalidateOn for an AsyncCache.
+func InvalidateAsyncCacheOn(c AsyncCache, bus invalidate.Bus, topic string) (unsubscribe func()) {
+	return bus.Subscribe(topic, func(msg invalidate.Message) {
+		c.DeleteIf(msg.Matches)
+	})
+}
@@ -0,0 +1,42 @@
+package asynccache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/realcaishen/utils-go/invalidate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidateOn(t *testing.T) {
+	var fetches atomic.Int32
+	c := NewCache(CacheOptions[string, string]{
+		RefreshDuration: time.Minute,
+		Fetcher: func(ctx context.Context, key string) (string, error) {
+			fetches.Add(1)
+			return "v-" + key, nil
+		},
+	})
+	defer c.Close()
+
+	bus := invalidate.NewMemoryBus()
+	unsubscribe := InvalidateOn(c, bus, "tokens", nil)
+	for _, key := range []string{"eth#a", "eth#b", "bsc#a"} {
+		c.Get(key)
+	}
+	assert.Equal(t, int32(3), fetches.Load())
+
+	bus.Publish(context.Background(), invalidate.Key("other", "eth#a"))
+	bus.Publish(context.Background(), invalidate.Key("tokens", "bsc#a"))
+	assert.Equal(t, map[string]string{"eth#a": "v-eth#a", "eth#b": "v-eth#b"}, c.Dump())
+
+	bus.Publish(context.Background(), invalidate.Prefix("tokens", "eth#"))
+	assert.Empty(t, c.Dump())
+
+	unsubscribe()
+	c.Get("eth#a")
+	bus.Publish(context.Background(), invalidate.All("tokens"))
+	assert.Len(t, c.Dump(), 1)
+}
@@ -0,0 +1,103 @@
+package asynccache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-metrics"
+	"github.com/realcaishen/utils-go/telemetry"
+)
+
+// CacheStats is a snapshot of a cache's size and counters.
+type CacheStats struct {
+	Entries int
+	Cost    int64
+	// ErrorEntries hold the error of a failed fetch and are served until a refresh succeeds.
+	ErrorEntries int
+	// NegativeEntries are keys remembered as not found.
+	NegativeEntries int
+
+	Hits        uint64
+	Misses      uint64
+	FetchErrors uint64
+	Evictions   uint64
+	// LastRefresh is how long the last refresh round took.
+	LastRefresh time.Duration
+}
+
+type counters struct {
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	fetchErrors atomic.Uint64
+	lastRefresh atomic.Int64
+
+	// reported holds the counters as of the last report, to emit deltas
+	mutex    sync.Mutex
+	reported CacheStats
+}
+
+// Stats returns the current size and the counters so far.
+func (c *cache[K, V]) Stats() CacheStats {
+	stats := CacheStats{
+		Hits:        c.counters.hits.Load(),
+		Misses:      c.counters.misses.Load(),
+		FetchErrors: c.counters.fetchErrors.Load(),
+		Evictions:   c.evictions.Load(),
+		LastRefresh: time.Duration(c.counters.lastRefresh.Load()),
+	}
+	c.data.Range(func(key, value interface{}) bool {
+		e := value.(*entry[V])
+		stats.Entries++
+		if e.isNegative() {
+			stats.NegativeEntries++
+		} else if e.err.Load() != nil {
+			stats.ErrorEntries++
+		}
+		return true
+	})
+	stats.Cost = int64(stats.Entries)
+	if c.bounds != nil {
+		_, stats.Cost = c.bounds.size()
+	}
+	return stats
+}
+
+func (c *cache[K, V]) labels() []metrics.Label {
+	return []metrics.Label{telemetry.NewLabel("cache", c.opt.Name)}
+}
+
+// fetched records the outcome of one Fetcher or BatchFetcher call.
+func (c *cache[K, V]) fetched(start time.Time, err error) {
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		c.counters.fetchErrors.Add(1)
+	}
+	if c.opt.Name != "" {
+		telemetry.MeasureSinceWithLabels([]string{"asynccache", "fetch"}, start, c.labels())
+	}
+}
+
+// report emits the counters accumulated since the previous report and the current
+// sizes through telemetry. It runs after every refresh round of a named cache.
+func (c *cache[K, V]) report() {
+	if c.opt.Name == "" {
+		return
+	}
+	stats := c.Stats()
+	labels := c.labels()
+
+	c.counters.mutex.Lock()
+	last := c.counters.reported
+	c.counters.reported = stats
+	c.counters.mutex.Unlock()
+
+	telemetry.IncrCounterWithLabels([]string{"asynccache", "hits"}, float32(stats.Hits-last.Hits), labels)
+	telemetry.IncrCounterWithLabels([]string{"asynccache", "misses"}, float32(stats.Misses-last.Misses), labels)
+	telemetry.IncrCounterWithLabels([]string{"asynccache", "fetch_errors"}, float32(stats.FetchErrors-last.FetchErrors), labels)
+	telemetry.IncrCounterWithLabels([]string{"asynccache", "evictions"}, float32(stats.Evictions-last.Evictions), labels)
+	telemetry.SetGaugeWithLabels([]string{"asynccache", "entries"}, float32(stats.Entries), labels)
+	telemetry.SetGaugeWithLabels([]string{"asynccache", "error_entries"}, float32(stats.ErrorEntries), labels)
+	telemetry.SetGaugeWithLabels([]string{"asynccache", "negative_entries"}, float32(stats.NegativeEntries), labels)
+	telemetry.SetGaugeWithLabels([]string{"asynccache", "refresh_seconds"}, float32(stats.LastRefresh.Seconds()), labels)
+}
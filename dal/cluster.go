@@ -0,0 +1,249 @@
+package dal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/realcaishen/utils-go/log"
+	"github.com/realcaishen/utils-go/task"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const (
+	DefaultMaxReplicaLag    = 5 * time.Second
+	DefaultLagCheckInterval = 5 * time.Second
+)
+
+var _ dbresolver.Policy = (*Cluster)(nil)
+
+type Config struct {
+	PrimaryDSN  string
+	ReplicaDSNs []string
+	// MaxReplicaLag is the replication delay above which a replica stops serving reads.
+	MaxReplicaLag time.Duration
+	// LagCheckInterval is how often Run measures replica lag.
+	LagCheckInterval time.Duration
+}
+
+type replica struct {
+	name string
+	db   *sql.DB
+	// healthy is set by the lag check; replicas start unhealthy so reads stay on the
+	// primary until the first check passes
+	healthy atomic.Bool
+	lag     atomic.Int64
+}
+
+// Cluster is a primary plus a set of read replicas. Writes and read-after-write paths
+// use Primary; heavy scans use Reader, which only hands out replicas whose lag is under
+// the threshold and falls back to the primary otherwise. Gorm returns a *gorm.DB with
+// the same routing installed through dbresolver, for use with dal/query.
+type Cluster struct {
+	primary          *sql.DB
+	replicas         []*replica
+	maxLag           time.Duration
+	lagCheckInterval time.Duration
+	next             atomic.Uint64
+	gormDB           *gorm.DB
+}
+
+// Open connects to the primary and every replica of cfg.
+func Open(cfg Config) (*Cluster, error) {
+	primary, err := sql.Open("mysql", cfg.PrimaryDSN)
+	if err != nil {
+		return nil, err
+	}
+	replicas := make([]*sql.DB, 0, len(cfg.ReplicaDSNs))
+	for _, dsn := range cfg.ReplicaDSNs {
+		db, err := sql.Open("mysql", dsn)
+		if err != nil {
+			primary.Close()
+			for _, r := range replicas {
+				r.Close()
+			}
+			return nil, err
+		}
+		replicas = append(replicas, db)
+	}
+	return NewCluster(primary, replicas, cfg)
+}
+
+// NewCluster builds a Cluster over existing connections. Only the lag settings of cfg are used.
+func NewCluster(primary *sql.DB, replicas []*sql.DB, cfg Config) (*Cluster, error) {
+	c := &Cluster{
+		primary:          primary,
+		replicas:         make([]*replica, 0, len(replicas)),
+		maxLag:           cfg.MaxReplicaLag,
+		lagCheckInterval: cfg.LagCheckInterval,
+	}
+	if c.maxLag <= 0 {
+		c.maxLag = DefaultMaxReplicaLag
+	}
+	if c.lagCheckInterval <= 0 {
+		c.lagCheckInterval = DefaultLagCheckInterval
+	}
+	for i, db := range replicas {
+		c.replicas = append(c.replicas, &replica{name: fmt.Sprintf("replica-%d", i), db: db})
+	}
+
+	// connections are lazy like sql.Open, nothing is dialed until the first query
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{Conn: primary, SkipInitializeWithVersion: true}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		return nil, err
+	}
+	if len(replicas) > 0 {
+		dialectors := make([]gorm.Dialector, 0, len(replicas))
+		for _, db := range replicas {
+			dialectors = append(dialectors, mysql.New(mysql.Config{Conn: db, SkipInitializeWithVersion: true}))
+		}
+		err = gormDB.Use(dbresolver.Register(dbresolver.Config{
+			Replicas: dialectors,
+			Policy:   c,
+		}))
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.gormDB = gormDB
+	return c, nil
+}
+
+func (c *Cluster) Primary() *sql.DB {
+	return c.primary
+}
+
+// Reader returns a healthy replica, round robin, or the primary if there is none.
+func (c *Cluster) Reader() *sql.DB {
+	if r := c.pick(); r != nil {
+		return r.db
+	}
+	return c.primary
+}
+
+// Gorm returns a *gorm.DB that sends reads to healthy replicas and writes to the
+// primary. Use Clauses(dbresolver.Write) to read your own writes.
+func (c *Cluster) Gorm() *gorm.DB {
+	return c.gormDB
+}
+
+// Resolve implements dbresolver.Policy with the same rules as Reader.
+func (c *Cluster) Resolve(connPools []gorm.ConnPool) gorm.ConnPool {
+	if r := c.pick(); r != nil {
+		for _, pool := range connPools {
+			if pool == gorm.ConnPool(r.db) {
+				return pool
+			}
+		}
+	}
+	return c.primary
+}
+
+func (c *Cluster) pick() *replica {
+	n := len(c.replicas)
+	if n == 0 {
+		return nil
+	}
+	start := int(c.next.Add(1) % uint64(n))
+	for i := 0; i < n; i++ {
+		r := c.replicas[(start+i)%n]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// ReplicaLags returns the last measured lag of every replica by name. Replicas whose
+// lag could not be measured are reported as -1.
+func (c *Cluster) ReplicaLags() map[string]time.Duration {
+	lags := make(map[string]time.Duration, len(c.replicas))
+	for _, r := range c.replicas {
+		lags[r.name] = time.Duration(r.lag.Load())
+	}
+	return lags
+}
+
+// CheckLag measures every replica once and updates which of them may serve reads.
+func (c *Cluster) CheckLag(ctx context.Context) {
+	for _, r := range c.replicas {
+		lag, err := replicaLag(ctx, r.db)
+		if err != nil {
+			log.CtxErrorf(ctx, "check %v lag error: %v", r.name, err)
+			r.lag.Store(-1)
+			r.healthy.Store(false)
+			continue
+		}
+		r.lag.Store(int64(lag))
+		healthy := lag <= c.maxLag
+		if r.healthy.Swap(healthy) != healthy {
+			log.CtxInfof(ctx, "%v lag %v, serving reads: %v", r.name, lag, healthy)
+		}
+	}
+}
+
+// Run checks replica lag every LagCheckInterval until ctx is done.
+func (c *Cluster) Run(ctx context.Context) {
+	if len(c.replicas) == 0 {
+		return
+	}
+	task.PeriodicTask(ctx, func() { c.CheckLag(ctx) }, c.lagCheckInterval)
+}
+
+func (c *Cluster) Close() error {
+	errs := []error{c.primary.Close()}
+	for _, r := range c.replicas {
+		errs = append(errs, r.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// replicaLag reads Seconds_Behind_Source from the replica status. A server that is not
+// replicating has no lag; a replica with replication stopped reports an error.
+func replicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		// servers before MySQL 8.0.22
+		rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS")
+		if err != nil {
+			return 0, err
+		}
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err = rows.Scan(dest...); err != nil {
+		return 0, err
+	}
+	for i, column := range columns {
+		if column != "Seconds_Behind_Source" && column != "Seconds_Behind_Master" {
+			continue
+		}
+		if !values[i].Valid {
+			return 0, errors.New("replication is not running")
+		}
+		seconds, err := strconv.ParseInt(values[i].String, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return 0, errors.New("replica status has no lag column")
+}
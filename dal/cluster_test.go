@@ -0,0 +1,42 @@
+package dal
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestClusterReader(t *testing.T) {
+	primary, err := sql.Open("mysql", "root:@tcp(127.0.0.1:1)/primary")
+	require.NoError(t, err)
+	replicaA, err := sql.Open("mysql", "root:@tcp(127.0.0.1:1)/a")
+	require.NoError(t, err)
+	replicaB, err := sql.Open("mysql", "root:@tcp(127.0.0.1:1)/b")
+	require.NoError(t, err)
+
+	c, err := NewCluster(primary, []*sql.DB{replicaA, replicaB}, Config{})
+	require.NoError(t, err)
+	defer c.Close()
+
+	// replicas serve nothing before the first lag check
+	assert.Same(t, primary, c.Reader())
+	assert.Equal(t, gorm.ConnPool(primary), c.Resolve([]gorm.ConnPool{replicaA, replicaB}))
+
+	c.replicas[1].healthy.Store(true)
+	for i := 0; i < 3; i++ {
+		assert.Same(t, replicaB, c.Reader())
+	}
+	assert.Equal(t, gorm.ConnPool(replicaB), c.Resolve([]gorm.ConnPool{replicaA, replicaB}))
+
+	c.replicas[0].healthy.Store(true)
+	seen := map[*sql.DB]bool{}
+	for i := 0; i < 4; i++ {
+		seen[c.Reader()] = true
+	}
+	assert.Len(t, seen, 2)
+	assert.False(t, seen[primary])
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/realcaishen/utils-go/dal/migrate"
+	"github.com/realcaishen/utils-go/loader"
+)
+
+const usage = `usage: migrate [-dsn DSN] <command>
+
+commands:
+  up                  apply all pending migrations; a database that already has the
+                      baseline tables gets the baseline recorded instead of run
+  down [n]            roll back the last n migrations (default 1); the baseline
+                      cannot be rolled back
+  baseline [version]  record every migration up to version as applied without
+                      running it (default the baseline)
+  status              list migrations and whether they are applied
+  check               verify the schema has every column the loaders select
+
+The DSN defaults to $MYSQL_DSN, e.g. "root:@tcp(localhost:3306)/db_cs".
+`
+
+func main() {
+	dsn := flag.String("dsn", os.Getenv("MYSQL_DSN"), "mysql data source name")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+	if flag.NArg() == 0 || *dsn == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := sql.Open("mysql", *dsn)
+	if err != nil {
+		log.Fatalf("failed to connect to the database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := migrate.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("failed to load migrations: %v", err)
+	}
+	ctx := context.Background()
+
+	switch flag.Arg(0) {
+	case "up":
+		done, err := migrator.Up(ctx)
+		for _, m := range done {
+			fmt.Printf("applied %04d_%v\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("up failed: %v", err)
+		}
+		if len(done) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		steps := 1
+		if flag.NArg() > 1 {
+			if steps, err = strconv.Atoi(flag.Arg(1)); err != nil || steps <= 0 {
+				log.Fatalf("invalid step count %v", flag.Arg(1))
+			}
+		}
+		done, err := migrator.Down(ctx, steps)
+		for _, m := range done {
+			fmt.Printf("rolled back %04d_%v\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("down failed: %v", err)
+		}
+	case "baseline":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("status failed: %v", err)
+		}
+		if len(statuses) == 0 {
+			log.Fatalf("no migrations")
+		}
+		version := statuses[0].Version
+		if flag.NArg() > 1 {
+			if version, err = strconv.ParseInt(flag.Arg(1), 10, 64); err != nil || version <= 0 {
+				log.Fatalf("invalid version %v", flag.Arg(1))
+			}
+		}
+		done, err := migrator.Baseline(ctx, version)
+		for _, m := range done {
+			fmt.Printf("recorded %04d_%v\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("baseline failed: %v", err)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("status failed: %v", err)
+		}
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.Applied {
+				appliedAt = time.Unix(s.AppliedAt, 0).Format(time.DateTime)
+			}
+			fmt.Printf("%04d_%-40v %v\n", s.Version, s.Name, appliedAt)
+		}
+	case "check":
+		if err = loader.CheckSchema(ctx, db); err != nil {
+			log.Fatalf("schema check failed:\n%v", err)
+		}
+		fmt.Println("schema matches loaders")
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
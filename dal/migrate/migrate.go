@@ -0,0 +1,379 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+const (
+	migrationsTable = "schema_migrations"
+	lockName        = "schema_migrations"
+	lockTimeout     = 60
+)
+
+var (
+	fileNamePattern    = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	createTablePattern = regexp.MustCompile("(?i)CREATE TABLE (?:IF NOT EXISTS )?`(\\w+)`")
+)
+
+// ErrIrreversible is returned by Down for a migration whose down file has no statements.
+var ErrIrreversible = errors.New("migration is irreversible")
+
+// Migration is one schema version. Up moves the schema to Version, Down moves it back
+// to the previous version. A down file holding only comments marks the migration as
+// irreversible.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m *Migration) Reversible() bool {
+	return len(splitStatements(m.Down)) > 0
+}
+
+// Tables returns the tables the up script creates.
+func (m *Migration) Tables() []string {
+	tables := make([]string, 0)
+	for _, match := range createTablePattern.FindAllStringSubmatch(m.Up, -1) {
+		tables = append(tables, match[1])
+	}
+	return tables
+}
+
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt int64
+}
+
+// Migrations returns the migrations shipped with this package, ordered by version.
+func Migrations() ([]*Migration, error) {
+	sub, err := fs.Sub(migrationFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return Load(sub)
+}
+
+// Load reads NNNN_name.up.sql and NNNN_name.down.sql pairs from the root of fsys.
+func Load(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		data, err := fs.ReadFile(fsys, path.Join(".", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %v and %v", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			return nil, fmt.Errorf("migration %d_%v needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// splitStatements splits a migration file into single statements, since the driver
+// does not run multi statements by default. Statements end with ';' at the end of a line.
+func splitStatements(script string) []string {
+	statements := make([]string, 0)
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+// Migrator applies migrations and records them in schema_migrations. MySQL DDL is not
+// transactional, so a migration failing halfway leaves its earlier statements applied
+// and is not recorded; fix the schema by hand or make the statements idempotent.
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration
+}
+
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return NewMigratorWith(db, migrations), nil
+}
+
+func NewMigratorWith(db *sql.DB, migrations []*Migration) *Migrator {
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}
+}
+
+// withLock runs fn on a single connection holding a MySQL named lock, so two services
+// starting at once do not apply the same migration twice.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, lockTimeout).Scan(&locked); err != nil {
+		return err
+	}
+	if locked.Int64 != 1 {
+		return fmt.Errorf("timeout waiting for migration lock")
+	}
+	defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+
+	_, err = conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS `"+migrationsTable+"` ("+
+		"`version` bigint NOT NULL, "+
+		"`name` varchar(128) NOT NULL, "+
+		"`applied_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, "+
+		"PRIMARY KEY (`version`)"+
+		") ENGINE = InnoDB DEFAULT CHARSET = utf8mb4")
+	if err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]int64, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, UNIX_TIMESTAMP(applied_at) FROM "+migrationsTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]int64)
+	for rows.Next() {
+		var version, appliedAt int64
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+func runScript(ctx context.Context, conn *sql.Conn, script string) error {
+	for _, statement := range splitStatements(script) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func recordApplied(ctx context.Context, conn *sql.Conn, migration *Migration) error {
+	_, err := conn.ExecContext(ctx, "INSERT INTO "+migrationsTable+" (version, name) VALUES (?, ?)", migration.Version, migration.Name)
+	return err
+}
+
+// existingTables counts how many of tables exist in the current database.
+func existingTables(ctx context.Context, conn *sql.Conn, tables []string) (int, error) {
+	if len(tables) == 0 {
+		return 0, nil
+	}
+	args := make([]any, 0, len(tables))
+	for _, table := range tables {
+		args = append(args, table)
+	}
+	var count int
+	err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name IN (?"+
+		strings.Repeat(", ?", len(tables)-1)+")", args...).Scan(&count)
+	return count, err
+}
+
+// adoptBaseline records the first migration as applied without running it when every
+// table it creates already exists, i.e. the database predates schema_migrations. It
+// fails when only some of them exist, since neither running nor skipping it is safe.
+func adoptBaseline(ctx context.Context, conn *sql.Conn, baseline *Migration) (bool, error) {
+	tables := baseline.Tables()
+	count, err := existingTables(ctx, conn, tables)
+	if err != nil {
+		return false, err
+	}
+	switch count {
+	case 0:
+		return false, nil
+	case len(tables):
+		return true, recordApplied(ctx, conn, baseline)
+	default:
+		return false, fmt.Errorf("migration %d_%v: %d of its %d tables already exist, fix the schema and run baseline",
+			baseline.Version, baseline.Name, count, len(tables))
+	}
+}
+
+// Up applies every pending migration in version order and returns the ones applied.
+// On a database that predates schema_migrations the baseline is adopted instead of
+// run, and is returned as applied.
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	done := make([]*Migration, 0)
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if i == 0 && len(applied) == 0 {
+				adopted, err := adoptBaseline(ctx, conn, migration)
+				if err != nil {
+					return err
+				}
+				if adopted {
+					done = append(done, migration)
+					continue
+				}
+			}
+			if err = runScript(ctx, conn, migration.Up); err != nil {
+				return fmt.Errorf("migration %d_%v up: %w", migration.Version, migration.Name, err)
+			}
+			if err = recordApplied(ctx, conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Baseline records every migration up to version as applied without running it, for a
+// database whose schema was brought to that version by other means. It returns the
+// migrations it recorded.
+func (m *Migrator) Baseline(ctx context.Context, version int64) ([]*Migration, error) {
+	done := make([]*Migration, 0)
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err = recordApplied(ctx, conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the last steps applied migrations, newest first, and returns them.
+// It stops with ErrIrreversible at a migration that cannot be rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	done := make([]*Migration, 0)
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if !migration.Reversible() {
+				return fmt.Errorf("migration %d_%v down: %w", migration.Version, migration.Name, ErrIrreversible)
+			}
+			if err = runScript(ctx, conn, migration.Down); err != nil {
+				return fmt.Errorf("migration %d_%v down: %w", migration.Version, migration.Name, err)
+			}
+			if _, err = conn.ExecContext(ctx, "DELETE FROM "+migrationsTable+" WHERE version = ?", migration.Version); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status reports every known migration and whether it is applied.
+func (m *Migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	statuses := make([]*MigrationStatus, 0, len(m.migrations))
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			appliedAt, ok := applied[migration.Version]
+			statuses = append(statuses, &MigrationStatus{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Applied:   ok,
+				AppliedAt: appliedAt,
+			})
+		}
+		return nil
+	})
+	return statuses, err
+}
@@ -0,0 +1,162 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/realcaishen/utils-go/loader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tables the loaders read that are owned by other services and not created here
+var unmanagedTables = []string{
+	"t_channel_commission_ratio",
+	"t_popular_list",
+	"t_security_addresses",
+	"t_swap_token_info",
+	"t_update_price",
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"0002_b.up.sql":   {Data: []byte("CREATE TABLE b (id int);")},
+		"0002_b.down.sql": {Data: []byte("DROP TABLE b;")},
+		"0001_a.up.sql":   {Data: []byte("CREATE TABLE a (id int);")},
+		"0001_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"README.md":       {Data: []byte("ignored")},
+	})
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "b", migrations[1].Name)
+
+	_, err = Load(fstest.MapFS{"0001_a.up.sql": {Data: []byte("CREATE TABLE a (id int);")}})
+	assert.Error(t, err)
+}
+
+func TestSplitStatements(t *testing.T) {
+	statements := splitStatements("-- comment\nCREATE TABLE a (\n    `id` int\n);\n\nDROP TABLE b;\nSELECT 1")
+	assert.Equal(t, []string{"CREATE TABLE a (\n    `id` int\n)", "DROP TABLE b", "SELECT 1"}, statements)
+}
+
+func TestBaselineIrreversible(t *testing.T) {
+	migrations, err := Migrations()
+	require.NoError(t, err)
+
+	baseline := migrations[0]
+	assert.False(t, baseline.Reversible())
+	assert.Contains(t, baseline.Tables(), "t_chain_info")
+	assert.Contains(t, baseline.Tables(), "t_src_transaction")
+	for _, m := range migrations[1:] {
+		assert.True(t, m.Reversible(), "%d_%v", m.Version, m.Name)
+	}
+}
+
+var (
+	createTableBodyPattern = regexp.MustCompile("(?s)CREATE TABLE `(\\w+)` \\((.*?)\\n\\) ENGINE")
+	columnPattern          = regexp.MustCompile("(?m)^\\s*`(\\w+)`")
+	addColumnPattern       = regexp.MustCompile("ALTER TABLE `(\\w+)` ADD COLUMN `(\\w+)`")
+)
+
+// TestMigrationsCoverLoaders fails when a loader selects a column that no migration creates.
+func TestMigrationsCoverLoaders(t *testing.T) {
+	migrations, err := Migrations()
+	require.NoError(t, err)
+
+	created := make(map[string]map[string]struct{})
+	for _, m := range migrations {
+		for _, match := range createTableBodyPattern.FindAllStringSubmatch(m.Up, -1) {
+			assert.NotContains(t, created, match[1], "table %v created twice", match[1])
+			created[match[1]] = make(map[string]struct{})
+			for _, column := range columnPattern.FindAllStringSubmatch(match[2], -1) {
+				created[match[1]][column[1]] = struct{}{}
+			}
+		}
+		for _, match := range addColumnPattern.FindAllStringSubmatch(m.Up, -1) {
+			require.Contains(t, created, match[1], "column %v added before table exists", match[2])
+			created[match[1]][match[2]] = struct{}{}
+		}
+	}
+
+	for table, columns := range loader.LoaderColumns {
+		if contains(unmanagedTables, table) {
+			continue
+		}
+		require.Contains(t, created, table)
+		for _, column := range columns {
+			assert.Contains(t, created[table], column, "%v.%v", table, column)
+		}
+	}
+}
+
+// TestMigrateMySQL runs the migrations against a real server, e.g.
+// MIGRATE_TEST_DSN="root:@tcp(localhost:3306)/migrate_test" go test ./dal/migrate
+func TestMigrateMySQL(t *testing.T) {
+	dsn := os.Getenv("MIGRATE_TEST_DSN")
+	if dsn == "" {
+		t.Skip("MIGRATE_TEST_DSN not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	migrator, err := NewMigrator(db)
+	require.NoError(t, err)
+
+	done, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, done)
+	done, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, done)
+
+	managed := make([]string, 0)
+	for table := range loader.LoaderColumns {
+		if !contains(unmanagedTables, table) {
+			managed = append(managed, table)
+		}
+	}
+	assert.NoError(t, loader.CheckSchema(ctx, db, managed...))
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied)
+	}
+
+	done, err = migrator.Down(ctx, len(statuses))
+	assert.ErrorIs(t, err, ErrIrreversible)
+	assert.Len(t, done, len(statuses)-1)
+	assert.NoError(t, loader.CheckSchema(ctx, db, "t_chain_info"))
+
+	// a database that predates schema_migrations adopts the baseline instead of failing
+	_, err = db.ExecContext(ctx, "DELETE FROM "+migrationsTable)
+	require.NoError(t, err)
+	done, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, done, len(statuses))
+
+	done, err = migrator.Down(ctx, len(statuses)-1)
+	require.NoError(t, err)
+	assert.Len(t, done, len(statuses)-1)
+	done, err = migrator.Baseline(ctx, statuses[len(statuses)-1].Version)
+	require.NoError(t, err)
+	assert.Len(t, done, len(statuses)-1)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,3 @@
+-- The baseline is the schema production ran before migrations existed. Rolling it back
+-- would drop every table with its data, so this step is irreversible on purpose: the
+-- migrator refuses to run a down file that has no statements.
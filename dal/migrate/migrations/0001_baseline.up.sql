@@ -234,17 +234,6 @@ CREATE TABLE `t_dst_confirmed_queue` (
     PRIMARY KEY (`id`)
 ) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci;
 
-CREATE TABLE `t_token_info` (
-    `id` bigint NOT NULL AUTO_INCREMENT,
-    `token_name` varchar(128) NOT NULL,
-    `chain_name` varchar(128) NOT NULL,
-    `token_address` varchar(128) NOT NULL,
-    `decimals` int NOT NULL,
-    `icon` varchar(128) NOT NULL,
-    PRIMARY KEY (`id`),
-    UNIQUE KEY `chain_name_token_name` (`chain_name`, `token_name`)
-) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci;
-
 CREATE TABLE `t_exchange_info` (
     `id` int NOT NULL,
     `name` varchar(64) NOT NULL,
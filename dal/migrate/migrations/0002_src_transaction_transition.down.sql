@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `t_src_transaction_transition`;
@@ -0,0 +1,11 @@
+CREATE TABLE `t_src_transaction_transition` (
+    `id` bigint NOT NULL AUTO_INCREMENT,
+    `src_id` bigint NOT NULL,
+    `from_state` int NOT NULL,
+    `to_state` int NOT NULL,
+    `operator` varchar(64) NOT NULL DEFAULT '',
+    `reason` varchar(255) NOT NULL DEFAULT '',
+    `insert_timestamp` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `src_id` (`src_id`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci;
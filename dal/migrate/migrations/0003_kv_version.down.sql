@@ -0,0 +1 @@
+ALTER TABLE `t_kv` DROP COLUMN `version`;
@@ -0,0 +1 @@
+ALTER TABLE `t_kv` ADD COLUMN `version` bigint NOT NULL DEFAULT '0';
@@ -0,0 +1 @@
+ALTER TABLE `t_transfer` DROP COLUMN `reason`;
@@ -0,0 +1 @@
+ALTER TABLE `t_transfer` ADD COLUMN `reason` varchar(255) NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+CREATE TABLE `t_invalidation` (
+    `id` bigint NOT NULL AUTO_INCREMENT,
+    `topic` varchar(64) NOT NULL,
+    `key` varchar(255) NOT NULL DEFAULT '',
+    `is_prefix` tinyint(1) NOT NULL DEFAULT '0',
+    `insert_timestamp` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_insert_timestamp` (`insert_timestamp`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci;
@@ -0,0 +1,3 @@
+ALTER TABLE `t_transfer` DROP KEY `idx_process_invalid_retry`;
+ALTER TABLE `t_transfer` DROP COLUMN `next_retry_time`;
+ALTER TABLE `t_transfer` DROP COLUMN `attempts`;
@@ -0,0 +1,3 @@
+ALTER TABLE `t_transfer` ADD COLUMN `attempts` int NOT NULL DEFAULT '0';
+ALTER TABLE `t_transfer` ADD COLUMN `next_retry_time` bigint NOT NULL DEFAULT '0';
+ALTER TABLE `t_transfer` ADD KEY `idx_process_invalid_retry` (`is_processed`, `is_invalid`, `next_retry_time`);
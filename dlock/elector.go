@@ -0,0 +1,83 @@
+package dlock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/realcaishen/utils-go/log"
+)
+
+// Elector keeps campaigning for a named lock and reports whether this process is the
+// leader. Run drives it; IsLeader can be read from any goroutine.
+type Elector struct {
+	locker *Locker
+	name   string
+	lock   *Lock
+	mutex  *sync.RWMutex
+}
+
+func NewElector(db *sql.DB, name string, owner string, ttl time.Duration) *Elector {
+	return &Elector{
+		locker: NewLocker(db, owner, ttl),
+		name:   name,
+		mutex:  &sync.RWMutex{},
+	}
+}
+
+// IsLeader reports whether this process currently holds the leadership lease.
+func (e *Elector) IsLeader() bool {
+	return e.LeaderContext() != nil
+}
+
+// LeaderContext returns a context cancelled when leadership is lost, or nil if this
+// process is not the leader.
+func (e *Elector) LeaderContext() context.Context {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	if e.lock == nil || e.lock.Context().Err() != nil {
+		return nil
+	}
+	return e.lock.Context()
+}
+
+// Leader returns the current lease holder, which may be another process.
+func (e *Elector) Leader(ctx context.Context) (*Lease, error) {
+	return e.locker.GetLease(ctx, e.name)
+}
+
+// Run campaigns until ctx is done: it tries to take the lease every third of the ttl
+// and, once leader, keeps it alive until it is lost. Leadership is released on return.
+func (e *Elector) Run(ctx context.Context) {
+	interval := e.locker.ttl / 3
+	for {
+		lock, err := e.locker.TryLock(ctx, e.name)
+		if err == nil {
+			log.CtxInfof(ctx, "%v became leader of %v, term %v", e.locker.owner, e.name, lock.Term())
+			e.mutex.Lock()
+			e.lock = lock
+			e.mutex.Unlock()
+
+			err = lock.KeepAlive(ctx)
+			log.CtxInfof(ctx, "%v stopped leading %v: %v", e.locker.owner, e.name, err)
+			// stop the work running under LeaderContext before anyone else can take over
+			lock.cancel(err)
+			e.mutex.Lock()
+			e.lock = nil
+			e.mutex.Unlock()
+			if !errors.Is(err, ErrLockLost) {
+				lock.Unlock(context.Background())
+			}
+		} else if !errors.Is(err, ErrLocked) {
+			log.CtxErrorf(ctx, "campaign for %v error: %v", e.name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package dlock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type fakeLease struct {
+	owner    string
+	term     int64
+	expireAt int64
+}
+
+// fakeKV is a database/sql connector that keeps the lock rows of t_kv in memory and
+// answers the statements of Locker the way MySQL would, against a clock the test moves.
+type fakeKV struct {
+	mutex  sync.Mutex
+	nowMs  int64
+	leases map[string]*fakeLease
+}
+
+func newFakeKV() (*sql.DB, *fakeKV) {
+	fake := &fakeKV{nowMs: 1_000_000, leases: make(map[string]*fakeLease)}
+	return sql.OpenDB(fake), fake
+}
+
+func (f *fakeKV) advance(ms int64) {
+	f.mutex.Lock()
+	f.nowMs += ms
+	f.mutex.Unlock()
+}
+
+func (f *fakeKV) Connect(context.Context) (driver.Conn, error) { return &fakeKVConn{kv: f}, nil }
+func (f *fakeKV) Driver() driver.Driver                        { return nil }
+
+type fakeKVConn struct {
+	kv *fakeKV
+}
+
+func (c *fakeKVConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeKVStmt{kv: c.kv, query: query}, nil
+}
+func (c *fakeKVConn) Close() error              { return nil }
+func (c *fakeKVConn) Begin() (driver.Tx, error) { return nil, fmt.Errorf("not supported") }
+
+type fakeKVStmt struct {
+	kv    *fakeKV
+	query string
+}
+
+func (s *fakeKVStmt) Close() error  { return nil }
+func (s *fakeKVStmt) NumInput() int { return -1 }
+
+func (s *fakeKVStmt) Exec(args []driver.Value) (driver.Result, error) {
+	f := s.kv
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	switch {
+	case s.query == acquireSQL:
+		key := args[0].(string)
+		if _, ok := f.leases[key]; ok {
+			return driver.RowsAffected(0), nil
+		}
+		f.leases[key] = &fakeLease{owner: args[1].(string), term: 1, expireAt: f.nowMs + args[2].(int64)}
+		return driver.RowsAffected(1), nil
+	case s.query == takeoverSQL:
+		lease, ok := f.leases[args[2].(string)]
+		if !ok || (lease.owner != args[3].(string) && lease.expireAt >= f.nowMs) {
+			return driver.RowsAffected(0), nil
+		}
+		lease.owner, lease.term, lease.expireAt = args[0].(string), lease.term+1, f.nowMs+args[1].(int64)
+		return driver.RowsAffected(1), nil
+	case strings.Contains(s.query, "JSON_SET(value, '$.expire_at', "+dbNowMs):
+		// refresh: ttl, key, owner, term
+		lease, ok := f.leases[args[1].(string)]
+		if !ok || lease.owner != args[2].(string) || lease.term != args[3].(int64) {
+			return driver.RowsAffected(0), nil
+		}
+		lease.expireAt = f.nowMs + args[0].(int64)
+		return driver.RowsAffected(1), nil
+	case strings.Contains(s.query, "JSON_SET(value, '$.expire_at', 0)"):
+		// unlock: key, owner, term
+		lease, ok := f.leases[args[0].(string)]
+		if !ok || lease.owner != args[1].(string) || lease.term != args[2].(int64) {
+			return driver.RowsAffected(0), nil
+		}
+		lease.expireAt = 0
+		return driver.RowsAffected(1), nil
+	}
+	return nil, fmt.Errorf("unexpected statement %q", s.query)
+}
+
+func (s *fakeKVStmt) Query(args []driver.Value) (driver.Rows, error) {
+	f := s.kv
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if !strings.HasPrefix(s.query, "SELECT value->>'$.owner'") {
+		return nil, fmt.Errorf("unexpected query %q", s.query)
+	}
+	lease, ok := f.leases[args[0].(string)]
+	if !ok {
+		return &fakeKVRows{}, nil
+	}
+	// ->> unquotes every JSON value to a string
+	return &fakeKVRows{row: []driver.Value{lease.owner, strconv.FormatInt(lease.term, 10), strconv.FormatInt(lease.expireAt, 10)}}, nil
+}
+
+type fakeKVRows struct {
+	row []driver.Value
+}
+
+func (r *fakeKVRows) Columns() []string { return []string{"owner", "term", "expire_at"} }
+func (r *fakeKVRows) Close() error      { return nil }
+
+func (r *fakeKVRows) Next(dest []driver.Value) error {
+	if r.row == nil {
+		return io.EOF
+	}
+	copy(dest, r.row)
+	r.row = nil
+	return nil
+}
@@ -0,0 +1,248 @@
+package dlock
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/realcaishen/utils-go/log"
+)
+
+var (
+	ErrLocked   = errors.New("lock held by another owner")
+	ErrLockLost = errors.New("lock lease lost")
+)
+
+const (
+	DefaultTTL = 15 * time.Second
+	keyPrefix  = "lock:"
+	// dbNowMs is evaluated by MySQL so every owner compares expiry against the same clock
+	dbNowMs = "CAST(UNIX_TIMESTAMP(NOW(3)) * 1000 AS SIGNED)"
+
+	acquireSQL = "INSERT IGNORE INTO t_kv (`key`, value) VALUES (?, JSON_OBJECT('owner', ?, 'term', 1, 'expire_at', " + dbNowMs + " + ?))"
+	// takeoverSQL casts the term before adding to it, value->>'$.term' is a string and
+	// string + 1 is a DOUBLE that would be stored as 2.0
+	takeoverSQL = "UPDATE t_kv SET value = JSON_OBJECT('owner', ?, 'term', CAST(value->>'$.term' AS SIGNED) + 1, 'expire_at', " + dbNowMs + " + ?) " +
+		"WHERE `key` = ? AND (value->>'$.owner' = ? OR value->>'$.expire_at' < " + dbNowMs + ")"
+)
+
+// Lease is the value stored in t_kv for a held lock. Term grows by one every time the
+// lock changes hands and can be used as a fencing token.
+type Lease struct {
+	Key      string
+	Owner    string
+	Term     int64
+	ExpireAt time.Time
+}
+
+// Locker acquires lease based locks stored in t_kv. A lease lives in the row
+// "lock:<name>" as {"owner", "term", "expire_at"} and every change is a conditional
+// update on owner and expiry, so any number of processes can share one table.
+type Locker struct {
+	db    *sql.DB
+	owner string
+	ttl   time.Duration
+}
+
+// NewLocker returns a Locker acting as owner. An empty owner gets a unique id built from
+// the hostname and pid; a non positive ttl uses DefaultTTL.
+func NewLocker(db *sql.DB, owner string, ttl time.Duration) *Locker {
+	if owner == "" {
+		owner = defaultOwner()
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Locker{
+		db:    db,
+		owner: owner,
+		ttl:   ttl,
+	}
+}
+
+func defaultOwner() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%v-%d-%v", host, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+func (l *Locker) Owner() string {
+	return l.owner
+}
+
+// TryLock acquires name if it is free, expired or already ours, without waiting.
+// It returns ErrLocked when another owner holds a live lease. The returned Lock's
+// Context is derived from ctx and is cancelled when the lease is lost or released.
+func (l *Locker) TryLock(ctx context.Context, name string) (*Lock, error) {
+	key := keyPrefix + name
+	ttlMs := l.ttl.Milliseconds()
+	start := time.Now()
+
+	result, err := l.db.ExecContext(ctx, acquireSQL, key, l.owner, ttlMs)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		_, err = l.db.ExecContext(ctx, takeoverSQL, l.owner, ttlMs, key, l.owner)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lease, err := l.GetLease(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if lease == nil || lease.Owner != l.owner {
+		return nil, ErrLocked
+	}
+
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	return &Lock{
+		locker:     l,
+		key:        key,
+		term:       lease.Term,
+		validUntil: start.Add(l.ttl),
+		ctx:        lockCtx,
+		cancel:     cancel,
+		mutex:      &sync.Mutex{},
+	}, nil
+}
+
+// GetLease returns the current lease of name, or nil if nobody ever took it.
+func (l *Locker) GetLease(ctx context.Context, name string) (*Lease, error) {
+	var owner, term, expireAt string
+	err := l.db.QueryRowContext(ctx, "SELECT value->>'$.owner', value->>'$.term', value->>'$.expire_at' FROM t_kv WHERE `key` = ?", keyPrefix+name).
+		Scan(&owner, &term, &expireAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lease := &Lease{Key: keyPrefix + name, Owner: owner}
+	if lease.Term, err = strconv.ParseInt(term, 10, 64); err != nil {
+		return nil, err
+	}
+	expireMs, err := strconv.ParseInt(expireAt, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	lease.ExpireAt = time.UnixMilli(expireMs)
+	return lease, nil
+}
+
+// Lock is a held lease. It stays valid until Unlock, until the lease expires without
+// being renewed, or until another owner takes it over after expiry.
+type Lock struct {
+	locker     *Locker
+	key        string
+	term       int64
+	validUntil time.Time
+	ctx        context.Context
+	cancel     context.CancelCauseFunc
+	mutex      *sync.Mutex
+}
+
+// Context is cancelled with ErrLockLost when the lease is lost, or with context.Canceled
+// after Unlock. Work protected by the lock should stop when it is done.
+func (lk *Lock) Context() context.Context {
+	return lk.ctx
+}
+
+// Term is the fencing token of this acquisition.
+func (lk *Lock) Term() int64 {
+	return lk.term
+}
+
+// Refresh extends the lease by the locker's ttl once.
+func (lk *Lock) Refresh(ctx context.Context) error {
+	lk.mutex.Lock()
+	defer lk.mutex.Unlock()
+
+	if err := context.Cause(lk.ctx); err != nil {
+		return err
+	}
+	start := time.Now()
+	result, err := lk.locker.db.ExecContext(ctx, "UPDATE t_kv SET value = JSON_SET(value, '$.expire_at', "+dbNowMs+" + ?) "+
+		"WHERE `key` = ? AND value->>'$.owner' = ? AND value->>'$.term' = ?",
+		lk.locker.ttl.Milliseconds(), lk.key, lk.locker.owner, lk.term)
+	if err != nil {
+		return lk.checkLocalExpiry(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return lk.checkLocalExpiry(err)
+	}
+	if affected == 0 {
+		// MySQL reports 0 rows when the value did not change, e.g. two renewals within
+		// the same millisecond, so confirm the lease is really gone
+		lease, err := lk.locker.GetLease(ctx, lk.key[len(keyPrefix):])
+		if err != nil {
+			return lk.checkLocalExpiry(err)
+		}
+		if lease == nil || lease.Owner != lk.locker.owner || lease.Term != lk.term {
+			lk.cancel(ErrLockLost)
+			return ErrLockLost
+		}
+	}
+	lk.validUntil = start.Add(lk.locker.ttl)
+	return nil
+}
+
+// checkLocalExpiry gives up the lock once the lease must have expired while the
+// database was unreachable.
+func (lk *Lock) checkLocalExpiry(err error) error {
+	if time.Now().After(lk.validUntil) {
+		lk.cancel(ErrLockLost)
+		return errors.Join(ErrLockLost, err)
+	}
+	return err
+}
+
+// KeepAlive refreshes the lease every third of the ttl until ctx is done, the lock is
+// released or the lease is lost. It returns ErrLockLost in the last case.
+func (lk *Lock) KeepAlive(ctx context.Context) error {
+	ticker := time.NewTicker(lk.locker.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-lk.ctx.Done():
+			return context.Cause(lk.ctx)
+		case <-ticker.C:
+			err := lk.Refresh(ctx)
+			if errors.Is(err, ErrLockLost) {
+				return err
+			}
+			if err != nil {
+				log.CtxErrorf(ctx, "refresh lock %v error: %v", lk.key, err)
+			}
+		}
+	}
+}
+
+// Unlock releases the lease if it is still ours and cancels the lock's context. The row
+// is expired rather than deleted so the next owner still gets a higher term.
+func (lk *Lock) Unlock(ctx context.Context) error {
+	lk.mutex.Lock()
+	defer lk.mutex.Unlock()
+
+	lk.cancel(context.Canceled)
+	_, err := lk.locker.db.ExecContext(ctx, "UPDATE t_kv SET value = JSON_SET(value, '$.expire_at', 0) WHERE `key` = ? AND value->>'$.owner' = ? AND value->>'$.term' = ?",
+		lk.key, lk.locker.owner, lk.term)
+	return err
+}
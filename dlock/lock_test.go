@@ -0,0 +1,97 @@
+package dlock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLockMySQL needs a database with t_kv, e.g.
+// DLOCK_TEST_DSN="root:@tcp(localhost:3306)/db_cs" go test ./dlock
+func TestLockMySQL(t *testing.T) {
+	dsn := os.Getenv("DLOCK_TEST_DSN")
+	if dsn == "" {
+		t.Skip("DLOCK_TEST_DSN not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	name := "test-" + defaultOwner()
+	defer db.Exec("DELETE FROM t_kv WHERE `key` = ?", keyPrefix+name)
+
+	a := NewLocker(db, "a", 600*time.Millisecond)
+	b := NewLocker(db, "b", 600*time.Millisecond)
+
+	lockA, err := a.TryLock(ctx, name)
+	require.NoError(t, err)
+	_, err = b.TryLock(ctx, name)
+	assert.ErrorIs(t, err, ErrLocked)
+	require.NoError(t, lockA.Refresh(ctx))
+
+	// a stops renewing, b takes over after expiry and a notices on its next refresh
+	time.Sleep(700 * time.Millisecond)
+	lockB, err := b.TryLock(ctx, name)
+	require.NoError(t, err)
+	assert.Greater(t, lockB.Term(), lockA.Term())
+	assert.ErrorIs(t, lockA.Refresh(ctx), ErrLockLost)
+	assert.True(t, errors.Is(context.Cause(lockA.Context()), ErrLockLost))
+
+	require.NoError(t, lockB.Unlock(ctx))
+	lockA, err = a.TryLock(ctx, name)
+	require.NoError(t, err)
+	assert.Greater(t, lockA.Term(), lockB.Term())
+	require.NoError(t, lockA.Unlock(ctx))
+}
+
+func TestLockTakeover(t *testing.T) {
+	db, kv := newFakeKV()
+	defer db.Close()
+	ctx := context.Background()
+
+	a := NewLocker(db, "a", time.Second)
+	b := NewLocker(db, "b", time.Second)
+
+	lockA, err := a.TryLock(ctx, "job")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), lockA.Term())
+	_, err = b.TryLock(ctx, "job")
+	assert.ErrorIs(t, err, ErrLocked)
+
+	// a refresh keeps the lease alive past the original expiry
+	kv.advance(800)
+	require.NoError(t, lockA.Refresh(ctx))
+	kv.advance(800)
+	_, err = b.TryLock(ctx, "job")
+	assert.ErrorIs(t, err, ErrLocked)
+
+	// once expired, b takes over with the next fencing term and a loses its lock
+	kv.advance(1500)
+	lockB, err := b.TryLock(ctx, "job")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), lockB.Term())
+	assert.ErrorIs(t, lockA.Refresh(ctx), ErrLockLost)
+	assert.ErrorIs(t, context.Cause(lockA.Context()), ErrLockLost)
+	lease, err := a.GetLease(ctx, "job")
+	require.NoError(t, err)
+	assert.Equal(t, &Lease{Key: "lock:job", Owner: "b", Term: 2, ExpireAt: lease.ExpireAt}, lease)
+
+	// a stale unlock by a does not release b's lease
+	require.NoError(t, lockA.Unlock(ctx))
+	_, err = a.TryLock(ctx, "job")
+	assert.ErrorIs(t, err, ErrLocked)
+
+	// an unlocked lease is free at once and the term keeps growing
+	require.NoError(t, lockB.Unlock(ctx))
+	lockA, err = a.TryLock(ctx, "job")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), lockA.Term())
+}
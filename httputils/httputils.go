@@ -10,6 +10,8 @@ import (
 	"net/http"
 	"net/url"
 	"time"
+
+	"github.com/realcaishen/utils-go/tracing"
 )
 
 // Client wraps an HTTP client with additional functionality.
@@ -17,11 +19,13 @@ type Client struct {
 	httpClient *http.Client
 }
 
-// NewClient creates a new HTTP client with a customizable timeout.
+// NewClient creates a new HTTP client with a customizable timeout. Requests are traced
+// and carry the trace context of their ctx, see tracing.Transport.
 func NewClient(timeout time.Duration) *Client {
 	return &Client{
 		httpClient: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: tracing.Transport(nil),
 		},
 	}
 }
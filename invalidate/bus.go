@@ -0,0 +1,115 @@
+package invalidate
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// Message asks every instance to drop cached data of Topic: the entry whose key is Key,
+// or with Prefix set every entry whose key starts with Key. An empty prefix message
+// drops the whole topic.
+type Message struct {
+	Topic  string
+	Key    string
+	Prefix bool
+}
+
+// Matches reports whether key is covered by the message.
+func (m Message) Matches(key string) bool {
+	if m.Prefix {
+		return strings.HasPrefix(key, m.Key)
+	}
+	return key == m.Key
+}
+
+// Key invalidates one key of topic.
+func Key(topic string, key string) Message {
+	return Message{Topic: topic, Key: key}
+}
+
+// Prefix invalidates every key of topic starting with prefix.
+func Prefix(topic string, prefix string) Message {
+	return Message{Topic: topic, Key: prefix, Prefix: true}
+}
+
+// All invalidates the whole topic.
+func All(topic string) Message {
+	return Message{Topic: topic, Prefix: true}
+}
+
+// Handler is called for every message of a subscribed topic. It runs on the bus'
+// delivery goroutine and should return quickly.
+type Handler func(msg Message)
+
+// Bus broadcasts invalidation messages to every instance subscribed to a topic,
+// including the publishing one. Delivery is at least once: handlers must be idempotent.
+type Bus interface {
+	Publish(ctx context.Context, msg Message) error
+	// Subscribe registers handler for topic until the returned function is called.
+	Subscribe(topic string, handler Handler) (unsubscribe func())
+}
+
+// subscribers is the handler registry shared by the Bus implementations.
+type subscribers struct {
+	mutex    sync.RWMutex
+	nextID   int64
+	handlers map[string]map[int64]Handler
+}
+
+func (s *subscribers) subscribe(topic string, handler Handler) func() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.handlers == nil {
+		s.handlers = make(map[string]map[int64]Handler)
+	}
+	if s.handlers[topic] == nil {
+		s.handlers[topic] = make(map[int64]Handler)
+	}
+	s.nextID++
+	id := s.nextID
+	s.handlers[topic][id] = handler
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			delete(s.handlers[topic], id)
+			if len(s.handlers[topic]) == 0 {
+				delete(s.handlers, topic)
+			}
+		})
+	}
+}
+
+func (s *subscribers) deliver(msg Message) {
+	s.mutex.RLock()
+	handlers := make([]Handler, 0, len(s.handlers[msg.Topic]))
+	for _, handler := range s.handlers[msg.Topic] {
+		handlers = append(handlers, handler)
+	}
+	s.mutex.RUnlock()
+	for _, handler := range handlers {
+		handler(msg)
+	}
+}
+
+// MemoryBus delivers messages synchronously within the process. It is meant for tests
+// and single instance deployments.
+type MemoryBus struct {
+	subscribers
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, msg Message) error {
+	b.deliver(msg)
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(topic string, handler Handler) func() {
+	return b.subscribe(topic, handler)
+}
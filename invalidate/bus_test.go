@@ -0,0 +1,107 @@
+package invalidate
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageMatches(t *testing.T) {
+	assert.True(t, Key("t", "eth#0xa").Matches("eth#0xa"))
+	assert.False(t, Key("t", "eth#0xa").Matches("eth#0xab"))
+	assert.True(t, Prefix("t", "eth#").Matches("eth#0xab"))
+	assert.False(t, Prefix("t", "eth#").Matches("bsc#0xab"))
+	assert.True(t, All("t").Matches("anything"))
+}
+
+func TestMemoryBus(t *testing.T) {
+	bus := NewMemoryBus()
+	var got []Message
+	unsubscribe := bus.Subscribe("a", func(msg Message) { got = append(got, msg) })
+	bus.Subscribe("b", func(msg Message) { t.Errorf("unexpected %v", msg) })
+
+	require.NoError(t, bus.Publish(context.Background(), Key("a", "k")))
+	unsubscribe()
+	unsubscribe()
+	require.NoError(t, bus.Publish(context.Background(), Key("a", "k2")))
+	assert.Equal(t, []Message{Key("a", "k")}, got)
+}
+
+// TestDBBusMySQL needs a database with t_invalidation, e.g.
+// INVALIDATE_TEST_DSN="root:@tcp(localhost:3306)/db_cs" go test ./invalidate
+func TestDBBusMySQL(t *testing.T) {
+	dsn := os.Getenv("INVALIDATE_TEST_DSN")
+	if dsn == "" {
+		t.Skip("INVALIDATE_TEST_DSN not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	defer db.Exec("DELETE FROM t_invalidation WHERE topic = 'test'")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	publisher, replica := NewDBBus(db), NewDBBus(db)
+	replica.SetPollInterval(50 * time.Millisecond)
+
+	var mutex sync.Mutex
+	var got []Message
+	replica.Subscribe("test", func(msg Message) {
+		mutex.Lock()
+		got = append(got, msg)
+		mutex.Unlock()
+	})
+	go replica.Run(ctx)
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, publisher.Publish(ctx, Key("test", "eth#0xa")))
+	require.NoError(t, publisher.Publish(ctx, Prefix("test", "bsc#")))
+	assert.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(got) == 2
+	}, 2*time.Second, 20*time.Millisecond)
+	assert.Equal(t, []Message{Key("test", "eth#0xa"), Prefix("test", "bsc#")}, got)
+
+	// a row whose id was taken before a higher one but committed after it
+	var last int64
+	require.NoError(t, db.QueryRow("SELECT MAX(id) FROM t_invalidation").Scan(&last))
+	_, err = db.Exec("INSERT INTO t_invalidation (id, topic, `key`) VALUES (?, 'test', 'late')", last+10)
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	_, err = db.Exec("INSERT INTO t_invalidation (id, topic, `key`) VALUES (?, 'test', 'early')", last+5)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(got) == 4
+	}, 2*time.Second, 20*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, []Message{Key("test", "late"), Key("test", "early")}, got[2:])
+}
+
+func TestPollCursor(t *testing.T) {
+	cursor := newPollCursor()
+	now := time.Now()
+	assert.True(t, cursor.add(1, now))
+	assert.True(t, cursor.add(3, now))
+	assert.False(t, cursor.add(3, now))
+	assert.Equal(t, int64(3), cursor.last)
+
+	// 2 commits after 3 was read and is still delivered once
+	assert.True(t, cursor.add(2, now.Add(time.Second)))
+	assert.False(t, cursor.add(2, now.Add(time.Second)))
+	assert.Equal(t, int64(3), cursor.last)
+
+	cursor.expire(now.Add(time.Millisecond))
+	assert.Len(t, cursor.seen, 1)
+	assert.False(t, cursor.add(2, now))
+	assert.True(t, cursor.add(1, now))
+}
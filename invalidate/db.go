@@ -0,0 +1,203 @@
+package invalidate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/realcaishen/utils-go/log"
+)
+
+const (
+	DefaultPollInterval = 2 * time.Second
+	DefaultRetention    = 24 * time.Hour
+	DefaultLookback     = 30 * time.Second
+	pollLimit           = 500
+	maxTopic            = 64
+	maxKey              = 255
+)
+
+// Columns are the columns of t_invalidation a poll reads.
+const Columns = "id, topic, `key`, is_prefix"
+
+// DBBus broadcasts messages through t_invalidation. Publish appends a row and Run on
+// every instance polls for rows it has not delivered yet. Auto increment ids are handed
+// out before commit, so a row can become visible after a higher id was already read:
+// every poll also reads the rows inserted within the lookback again and skips the ids it
+// delivered. Old rows are pruned after the retention period.
+type DBBus struct {
+	subscribers
+	db           *sql.DB
+	pollInterval time.Duration
+	retention    time.Duration
+	lookback     time.Duration
+}
+
+func NewDBBus(db *sql.DB) *DBBus {
+	return &DBBus{
+		db:           db,
+		pollInterval: DefaultPollInterval,
+		retention:    DefaultRetention,
+		lookback:     DefaultLookback,
+	}
+}
+
+// SetPollInterval changes how often Run looks for new messages, which bounds how long
+// other instances serve stale data after a Publish.
+func (b *DBBus) SetPollInterval(interval time.Duration) {
+	b.pollInterval = interval
+}
+
+// SetRetention changes how long published rows are kept before Run prunes them.
+func (b *DBBus) SetRetention(retention time.Duration) {
+	b.retention = retention
+}
+
+// SetLookback changes how far back, by insert time, every poll looks for rows that
+// committed late. It must exceed the longest time between an insert and its commit.
+func (b *DBBus) SetLookback(lookback time.Duration) {
+	b.lookback = lookback
+}
+
+// Publish stores msg for the other instances and delivers it locally right away, so the
+// publisher reads its own invalidation without waiting for a poll.
+func (b *DBBus) Publish(ctx context.Context, msg Message) error {
+	if msg.Topic == "" || len(msg.Topic) > maxTopic {
+		return fmt.Errorf("invalid invalidation topic %q", msg.Topic)
+	}
+	if len(msg.Key) > maxKey {
+		return fmt.Errorf("invalidation key longer than %v bytes", maxKey)
+	}
+	_, err := b.db.ExecContext(ctx, "INSERT INTO t_invalidation (topic, `key`, is_prefix) VALUES (?, ?, ?)",
+		msg.Topic, msg.Key, msg.Prefix)
+	if err != nil {
+		return err
+	}
+	b.deliver(msg)
+	return nil
+}
+
+func (b *DBBus) Subscribe(topic string, handler Handler) func() {
+	return b.subscribe(topic, handler)
+}
+
+// pollCursor is what Run has seen: the highest id and the ids seen recently, which
+// tell a late committed row from one already delivered.
+type pollCursor struct {
+	last int64
+	seen map[int64]time.Time
+}
+
+func newPollCursor() *pollCursor {
+	return &pollCursor{seen: make(map[int64]time.Time)}
+}
+
+// add records id as seen at now and reports whether it was new.
+func (c *pollCursor) add(id int64, now time.Time) bool {
+	if _, ok := c.seen[id]; ok {
+		return false
+	}
+	c.seen[id] = now
+	if id > c.last {
+		c.last = id
+	}
+	return true
+}
+
+// expire forgets the ids seen before cutoff. A row still inside the lookback after
+// its id is forgotten is delivered again, which idempotent handlers tolerate.
+func (c *pollCursor) expire(cutoff time.Time) {
+	for id, at := range c.seen {
+		if at.Before(cutoff) {
+			delete(c.seen, id)
+		}
+	}
+}
+
+// Run delivers messages published after it started until ctx is done. Each instance
+// runs it once; messages missed while an instance was down do not matter since its
+// caches start empty.
+func (b *DBBus) Run(ctx context.Context) error {
+	cursor := newPollCursor()
+	err := b.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM t_invalidation").Scan(&cursor.last)
+	if err != nil {
+		return err
+	}
+	// rows already visible at start are not delivered, the ones committing late are
+	if err = b.poll(ctx, cursor, false); err != nil {
+		return err
+	}
+
+	lastPrune := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(b.pollInterval):
+		}
+
+		err = b.poll(ctx, cursor, true)
+		if err != nil && ctx.Err() == nil {
+			log.CtxErrorf(ctx, "poll invalidation error: %v", err)
+		}
+		// keep ids a while past the lookback so a slow clock does not redeliver them
+		cursor.expire(time.Now().Add(-2 * b.lookback))
+		if time.Since(lastPrune) > b.retention/24 {
+			lastPrune = time.Now()
+			if err := b.prune(ctx); err != nil && ctx.Err() == nil {
+				log.CtxErrorf(ctx, "prune invalidation error: %v", err)
+			}
+		}
+	}
+}
+
+// poll delivers, in id order, every row after cursor.last or inserted within the
+// lookback that the cursor has not seen yet. With deliver false the rows are only marked
+// as seen.
+func (b *DBBus) poll(ctx context.Context, cursor *pollCursor, deliver bool) error {
+	last := cursor.last
+	page := int64(0)
+	for {
+		rows, err := b.db.QueryContext(ctx, "SELECT "+Columns+" FROM t_invalidation "+
+			"WHERE id > ? AND (id > ? OR insert_timestamp >= NOW() - INTERVAL ? SECOND) ORDER BY id LIMIT ?",
+			page, last, int64(b.lookback.Seconds()), pollLimit)
+		if err != nil {
+			return err
+		}
+		ids := make([]int64, 0)
+		messages := make([]Message, 0)
+		for rows.Next() {
+			var id int64
+			var msg Message
+			if err = rows.Scan(&id, &msg.Topic, &msg.Key, &msg.Prefix); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+			messages = append(messages, msg)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for i, msg := range messages {
+			if cursor.add(ids[i], now) && deliver {
+				b.deliver(msg)
+			}
+		}
+		if len(messages) < pollLimit {
+			return nil
+		}
+		page = ids[len(ids)-1]
+	}
+}
+
+func (b *DBBus) prune(ctx context.Context) error {
+	_, err := b.db.ExecContext(ctx, "DELETE FROM t_invalidation WHERE insert_timestamp < NOW() - INTERVAL ? SECOND LIMIT 10000",
+		int64(b.retention.Seconds()))
+	return err
+}
@@ -0,0 +1,257 @@
+package kvstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/realcaishen/utils-go/log"
+)
+
+var (
+	ErrNotFound        = errors.New("key not found")
+	ErrVersionConflict = errors.New("key version changed")
+)
+
+const DefaultPollInterval = 5 * time.Second
+
+// Columns are the columns of t_kv the store writes.
+const Columns = "`key`, value, version"
+
+// Store is a typed config and state store on t_kv. Keys live in namespaces like Apollo
+// configs do and are stored as "<namespace>/<key>"; values are JSON and every write
+// bumps the row version, which CompareAndSwap and Watch build on. A deleted key keeps
+// its row as a tombstone with a NULL value, so its version never goes back and a key
+// deleted and written again between two polls of Watch is still seen as changed.
+type Store struct {
+	db           *sql.DB
+	pollInterval time.Duration
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db:           db,
+		pollInterval: DefaultPollInterval,
+	}
+}
+
+// SetPollInterval changes how often Watch polls for new versions.
+func (s *Store) SetPollInterval(interval time.Duration) {
+	s.pollInterval = interval
+}
+
+func rowKey(namespace string, key string) string {
+	return namespace + "/" + key
+}
+
+// GetString returns the raw value of a key, or an empty string if it does not exist,
+// like ApolloSDK.GetString.
+func (s *Store) GetString(namespace, key string) (string, error) {
+	value, _, err := s.getRaw(context.Background(), namespace, key)
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	return value, err
+}
+
+// getRaw returns the value and version of a key. A deleted key returns ErrNotFound
+// along with the version of its tombstone, a key never written version 0.
+func (s *Store) getRaw(ctx context.Context, namespace string, key string) (string, int64, error) {
+	var value sql.NullString
+	var version int64
+	err := s.db.QueryRowContext(ctx, "SELECT value, version FROM t_kv WHERE `key` = ?", rowKey(namespace, key)).Scan(&value, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", 0, ErrNotFound
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	if !value.Valid {
+		return "", version, ErrNotFound
+	}
+	return value.String, version, nil
+}
+
+// Delete removes a key, leaving a tombstone at the next version. Deleting a missing
+// key is not an error.
+func (s *Store) Delete(ctx context.Context, namespace, key string) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE t_kv SET value = NULL, version = version + 1 WHERE `key` = ? AND value IS NOT NULL", rowKey(namespace, key))
+	return err
+}
+
+// decode parses a stored value the way apollosdk.GetConfig does: JSON first, raw text
+// for string targets.
+func decode[T any](value string, parseFunc ...func(string) (T, error)) (T, error) {
+	var zero T
+	if len(parseFunc) > 0 && parseFunc[0] != nil {
+		return parseFunc[0](value)
+	}
+
+	var result T
+	if err := json.Unmarshal([]byte(value), &result); err == nil {
+		return result, nil
+	}
+	if _, ok := any(result).(string); ok {
+		return any(value).(T), nil
+	}
+	return zero, fmt.Errorf("unable to parse config, value: %v", value)
+}
+
+// GetConfig has the same shape as apollosdk.GetConfig so callers can switch backends.
+func GetConfig[T any](store *Store, namespace, key string, parseFunc ...func(string) (T, error)) (T, error) {
+	var zero T
+	value, err := store.GetString(namespace, key)
+	if err != nil {
+		return zero, err
+	}
+	return decode(value, parseFunc...)
+}
+
+// Get returns the value of a key and its version, or ErrNotFound.
+func Get[T any](ctx context.Context, store *Store, namespace, key string) (T, int64, error) {
+	var zero T
+	value, version, err := store.getRaw(ctx, namespace, key)
+	if err != nil {
+		return zero, 0, err
+	}
+	result, err := decode[T](value)
+	if err != nil {
+		return zero, 0, err
+	}
+	return result, version, nil
+}
+
+// Set writes value unconditionally and returns the new version.
+func Set[T any](ctx context.Context, store *Store, namespace, key string, value T) (int64, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	k := rowKey(namespace, key)
+	_, err = tx.ExecContext(ctx, "INSERT INTO t_kv ("+Columns+") VALUES (?, ?, 1) ON DUPLICATE KEY UPDATE value = VALUES(value), version = version + 1", k, string(data))
+	if err != nil {
+		return 0, err
+	}
+	var version int64
+	if err = tx.QueryRowContext(ctx, "SELECT version FROM t_kv WHERE `key` = ?", k).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, tx.Commit()
+}
+
+// CompareAndSwap writes value only if the key is still at version, and returns the new
+// version. Version 0 means the key must not exist yet, or be deleted. It returns
+// ErrVersionConflict if someone else wrote in between.
+func CompareAndSwap[T any](ctx context.Context, store *Store, namespace, key string, version int64, value T) (int64, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0, err
+	}
+	k := rowKey(namespace, key)
+
+	if version != 0 {
+		result, err := store.db.ExecContext(ctx, "UPDATE t_kv SET value = ?, version = version + 1 WHERE `key` = ? AND version = ? AND value IS NOT NULL", string(data), k, version)
+		if err != nil {
+			return 0, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		if affected == 0 {
+			return 0, ErrVersionConflict
+		}
+		return version + 1, nil
+	}
+
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// a tombstone is revived at its next version; the version assignment must come first
+	// since MySQL evaluates the later one against the updated row
+	result, err := tx.ExecContext(ctx, "INSERT INTO t_kv ("+Columns+") VALUES (?, ?, 1) "+
+		"ON DUPLICATE KEY UPDATE version = IF(value IS NULL, version + 1, version), value = IFNULL(value, VALUES(value))", k, string(data))
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, ErrVersionConflict
+	}
+	var newVersion int64
+	if err = tx.QueryRowContext(ctx, "SELECT version FROM t_kv WHERE `key` = ?", k).Scan(&newVersion); err != nil {
+		return 0, err
+	}
+	return newVersion, tx.Commit()
+}
+
+// Entry is one observed state of a watched key. A deleted key has the version of its
+// tombstone.
+type Entry[T any] struct {
+	Value   T
+	Version int64
+	Deleted bool
+}
+
+// Watch polls a key and sends its current value if it exists, then every new version,
+// until ctx is done and the channel is closed. A deleted key is sent once with Deleted
+// set. Values that fail to decode are logged and skipped.
+func Watch[T any](ctx context.Context, store *Store, namespace, key string) <-chan Entry[T] {
+	ch := make(chan Entry[T], 1)
+	go func() {
+		defer close(ch)
+		var lastVersion int64 = -1
+		for {
+			value, version, err := store.getRaw(ctx, namespace, key)
+			var entry *Entry[T]
+			switch {
+			case errors.Is(err, ErrNotFound):
+				if lastVersion > 0 && version != lastVersion {
+					entry = &Entry[T]{Version: version, Deleted: true}
+				}
+				lastVersion = version
+			case err != nil:
+				if ctx.Err() == nil {
+					log.CtxErrorf(ctx, "watch %v error: %v", rowKey(namespace, key), err)
+				}
+			case version != lastVersion:
+				lastVersion = version
+				decoded, err := decode[T](value)
+				if err != nil {
+					log.CtxErrorf(ctx, "watch %v decode error: %v", rowKey(namespace, key), err)
+					break
+				}
+				entry = &Entry[T]{Value: decoded, Version: version}
+			}
+
+			if entry != nil {
+				select {
+				case ch <- *entry:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(store.pollInterval):
+			}
+		}
+	}()
+	return ch
+}
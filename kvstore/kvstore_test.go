@@ -0,0 +1,107 @@
+package kvstore
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type cursor struct {
+	Block int64 `json:"block"`
+}
+
+func TestDecode(t *testing.T) {
+	c, err := decode[cursor](`{"block": 12}`)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), c.Block)
+
+	s, err := decode[string](`plain text`)
+	require.NoError(t, err)
+	assert.Equal(t, "plain text", s)
+
+	s, err = decode[string](`"quoted"`)
+	require.NoError(t, err)
+	assert.Equal(t, "quoted", s)
+
+	n, err := decode(`0x10`, func(v string) (int64, error) { return strconv.ParseInt(v, 0, 64) })
+	require.NoError(t, err)
+	assert.Equal(t, int64(16), n)
+
+	_, err = decode[cursor](`not json`)
+	assert.Error(t, err)
+}
+
+// TestStoreMySQL needs a migrated database, e.g.
+// KVSTORE_TEST_DSN="root:@tcp(localhost:3306)/db_cs" go test ./kvstore
+func TestStoreMySQL(t *testing.T) {
+	dsn := os.Getenv("KVSTORE_TEST_DSN")
+	if dsn == "" {
+		t.Skip("KVSTORE_TEST_DSN not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := NewStore(db)
+	store.SetPollInterval(10 * time.Millisecond)
+	key := "cursor-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	// Delete leaves a tombstone behind
+	defer db.Exec("DELETE FROM t_kv WHERE `key` = ?", rowKey("test", key))
+
+	_, _, err = Get[cursor](ctx, store, "test", key)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	version, err := CompareAndSwap(ctx, store, "test", key, 0, cursor{Block: 1})
+	require.NoError(t, err)
+	_, err = CompareAndSwap(ctx, store, "test", key, 0, cursor{Block: 2})
+	assert.ErrorIs(t, err, ErrVersionConflict)
+
+	watch := Watch[cursor](ctx, store, "test", key)
+	assert.Equal(t, int64(1), (<-watch).Value.Block)
+
+	version, err = CompareAndSwap(ctx, store, "test", key, version, cursor{Block: 3})
+	require.NoError(t, err)
+	got, gotVersion, err := Get[cursor](ctx, store, "test", key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), got.Block)
+	assert.Equal(t, version, gotVersion)
+	assert.Equal(t, int64(3), (<-watch).Value.Block)
+
+	_, err = Set(ctx, store, "test", key, cursor{Block: 4})
+	require.NoError(t, err)
+	c, err := GetConfig[cursor](store, "test", key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), c.Block)
+	assert.Equal(t, int64(4), (<-watch).Value.Block)
+
+	require.NoError(t, store.Delete(ctx, "test", key))
+	deleted := <-watch
+	assert.True(t, deleted.Deleted)
+	_, _, err = Get[cursor](ctx, store, "test", key)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// a key written again after a delete never repeats a version, so a watcher that
+	// polls only after both writes still sees the new value
+	version, err = CompareAndSwap(ctx, store, "test", key, 0, cursor{Block: 5})
+	require.NoError(t, err)
+	assert.Greater(t, version, deleted.Version)
+	_, err = CompareAndSwap(ctx, store, "test", key, 0, cursor{Block: 6})
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	assert.Equal(t, int64(5), (<-watch).Value.Block)
+
+	require.NoError(t, store.Delete(ctx, "test", key))
+	_, err = CompareAndSwap(ctx, store, "test", key, 0, cursor{Block: 5})
+	require.NoError(t, err)
+	_, gotVersion, err = Get[cursor](ctx, store, "test", key)
+	require.NoError(t, err)
+	assert.Equal(t, version+2, gotVersion)
+}
@@ -21,6 +21,9 @@ type AccountManager struct {
 	db                 *sql.DB
 	alerter            alert.Alerter
 	mutex              *sync.RWMutex
+
+	*snapshotState
+	*readRouter
 }
 
 func NewAccountManager(db *sql.DB, alerter alert.Alerter) *AccountManager {
@@ -31,6 +34,9 @@ func NewAccountManager(db *sql.DB, alerter alert.Alerter) *AccountManager {
 		db:                 db,
 		alerter:            alerter,
 		mutex:              &sync.RWMutex{},
+
+		snapshotState: newSnapshotState(),
+		readRouter:    newReadRouter(db),
 	}
 }
 
@@ -72,9 +78,13 @@ func (mgr *AccountManager) GetAccountByAddressCid(address string, cid int64) (*A
 	return nil, false
 }
 
+const accountColumns = "id, chain_id, address"
+
 func (mgr *AccountManager) LoadAllAccounts() {
+	span := startReload("t_account")
+	defer span.End()
 	// Query the database to select only id and name fields
-	rows, err := mgr.db.Query("SELECT id, chain_id, address FROM t_account")
+	rows, err := mgr.scanDB().Query("SELECT " + accountColumns + " FROM t_account")
 
 	if err != nil || rows == nil {
 		mgr.alerter.AlertText("select t_account error", err)
@@ -83,36 +93,19 @@ func (mgr *AccountManager) LoadAllAccounts() {
 
 	defer rows.Close()
 
-	idAccounts := make(map[int64]*Account)
-	addressCidAccounts := make(map[string]map[int64]*Account)
-	cidAddressAccounts := make(map[int64]map[string]*Account)
+	allAccounts := make([]*Account, 0)
 	counter := 0
+	scanErrors := 0
 
 	// Iterate over the result set
 	for rows.Next() {
 		var acc Account
 		if err := rows.Scan(&acc.Id, &acc.ChainInfoId, &acc.Address); err != nil {
 			mgr.alerter.AlertText("scan t_account row error", err)
+			scanErrors++
 		} else {
 			acc.Address = strings.TrimSpace(acc.Address)
-
-			idAccounts[acc.Id] = &acc
-			lowerAddr := strings.ToLower(acc.Address)
-
-			accs, ok := addressCidAccounts[lowerAddr]
-			if !ok {
-				accs = make(map[int64]*Account)
-				addressCidAccounts[lowerAddr] = accs
-			}
-			accs[acc.ChainInfoId] = &acc
-
-			addraccs, ok := cidAddressAccounts[acc.ChainInfoId]
-			if !ok {
-				addraccs = make(map[string]*Account)
-				cidAddressAccounts[acc.ChainInfoId] = addraccs
-			}
-			addraccs[lowerAddr] = &acc
-
+			allAccounts = append(allAccounts, &acc)
 			counter++
 		}
 	}
@@ -123,6 +116,62 @@ func (mgr *AccountManager) LoadAllAccounts() {
 		return
 	}
 
+	mgr.mutex.RLock()
+	oldCount := len(mgr.idAccounts)
+	mgr.mutex.RUnlock()
+	if err := mgr.snapshotState.allowReplace(oldCount, counter, scanErrors); err != nil {
+		mgr.alerter.AlertText("t_account load rejected, keep current accounts", err)
+		return
+	}
+
+	mgr.setAccounts(allAccounts)
+	if err = saveSnapshot(mgr.snapshotState, allAccounts); err != nil {
+		mgr.alerter.AlertText("save t_account snapshot error", err)
+	}
+}
+
+// RestoreSnapshot fills an empty manager from the snapshot file, so a service can start
+// while the database is unavailable. StaleSince reports the snapshot time until the next
+// successful load.
+func (mgr *AccountManager) RestoreSnapshot() error {
+	mgr.mutex.RLock()
+	loaded := len(mgr.idAccounts) > 0
+	mgr.mutex.RUnlock()
+	if loaded {
+		return ErrAlreadyLoaded
+	}
+	allAccounts, err := readSnapshot[*Account](mgr.snapshotState)
+	if err != nil {
+		return err
+	}
+	mgr.setAccounts(allAccounts)
+	return nil
+}
+
+func (mgr *AccountManager) setAccounts(allAccounts []*Account) {
+	idAccounts := make(map[int64]*Account)
+	addressCidAccounts := make(map[string]map[int64]*Account)
+	cidAddressAccounts := make(map[int64]map[string]*Account)
+
+	for _, acc := range allAccounts {
+		idAccounts[acc.Id] = acc
+		lowerAddr := strings.ToLower(acc.Address)
+
+		accs, ok := addressCidAccounts[lowerAddr]
+		if !ok {
+			accs = make(map[int64]*Account)
+			addressCidAccounts[lowerAddr] = accs
+		}
+		accs[acc.ChainInfoId] = acc
+
+		addraccs, ok := cidAddressAccounts[acc.ChainInfoId]
+		if !ok {
+			addraccs = make(map[string]*Account)
+			cidAddressAccounts[acc.ChainInfoId] = addraccs
+		}
+		addraccs[lowerAddr] = acc
+	}
+
 	mgr.mutex.Lock()
 	mgr.idAccounts = idAccounts
 	mgr.addressCidAccounts = addressCidAccounts
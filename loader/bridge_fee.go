@@ -37,6 +37,10 @@ type BridgeFeeManager struct {
 	db      *sql.DB
 	alerter alert.Alerter
 	mutex   *sync.RWMutex
+
+	*changeNotifier
+	*snapshotState
+	*readRouter
 }
 
 func NewBridgeFeeManager(db *sql.DB, alerter alert.Alerter) *BridgeFeeManager {
@@ -46,6 +50,10 @@ func NewBridgeFeeManager(db *sql.DB, alerter alert.Alerter) *BridgeFeeManager {
 		db:      db,
 		alerter: alerter,
 		mutex:   &sync.RWMutex{},
+
+		changeNotifier: newChangeNotifier(),
+		snapshotState:  newSnapshotState(),
+		readRouter:     newReadRouter(db),
 	}
 }
 
@@ -64,16 +72,25 @@ func (mgr *BridgeFeeManager) GetBridgeFee(token string, from string, to string)
 	return nil, false
 }
 
+const (
+	bridgeFeeColumns        = "token_name, from_chain, to_chain, bridge_fee_ratio_lv1, bridge_fee_ratio_lv2, bridge_fee_ratio_lv3, bridge_fee_ratio_lv4, amount_lv1, amount_lv2, amount_lv3, amount_lv4"
+	bridgeFeeDecimalColumns = "token, keep_decimal"
+)
+
+// LoadAllBridgeFee reads the whole t_dynamic_bridge_fee table. Bridge fees have no delta
+// load like LoadChangedToken, so every reload is a full scan; schedule it accordingly.
 func (mgr *BridgeFeeManager) LoadAllBridgeFee(tokenInfoMgr TokenInfoManager) {
+	span := startReload("t_dynamic_bridge_fee")
+	defer span.End()
 	// Query the database to select only id and name fields
-	rows, err := mgr.db.Query("SELECT token_name, from_chain, to_chain, bridge_fee_ratio_lv1, bridge_fee_ratio_lv2, bridge_fee_ratio_lv3, bridge_fee_ratio_lv4, amount_lv1, amount_lv2, amount_lv3, amount_lv4 FROM t_dynamic_bridge_fee")
+	rows, err := mgr.scanDB().Query("SELECT " + bridgeFeeColumns + " FROM t_dynamic_bridge_fee")
 
 	if err != nil || rows == nil {
 		mgr.alerter.AlertText("select t_dynamic_bridge_fee error", err)
 		return
 	}
 
-	kdrows, kderr := mgr.db.Query("SELECT token, keep_decimal FROM t_bridge_fee_decimal")
+	kdrows, kderr := mgr.scanDB().Query("SELECT " + bridgeFeeDecimalColumns + " FROM t_bridge_fee_decimal")
 	if kderr != nil {
 		mgr.alerter.AlertText("select t_bridge_fee_decimal error", kderr)
 	}
@@ -93,8 +110,9 @@ func (mgr *BridgeFeeManager) LoadAllBridgeFee(tokenInfoMgr TokenInfoManager) {
 		}
 	}
 
-	tokenFromToBridgeFees := make(map[string]map[string]map[string]*BridgeFee)
+	allBridgeFees := make([]*BridgeFee, 0)
 	counter := 0
+	scanErrors := 0
 
 	// Iterate over the result set
 	for rows.Next() {
@@ -102,6 +120,7 @@ func (mgr *BridgeFeeManager) LoadAllBridgeFee(tokenInfoMgr TokenInfoManager) {
 
 		if err := rows.Scan(&bridgeFee.TokenName, &bridgeFee.FromChainName, &bridgeFee.ToChainName, &bridgeFee.BridgeFeeRatioLv1, &bridgeFee.BridgeFeeRatioLv2, &bridgeFee.BridgeFeeRatioLv3, &bridgeFee.BridgeFeeRatioLv4, &bridgeFee.AmountLv1Str, &bridgeFee.AmountLv2Str, &bridgeFee.AmountLv3Str, &bridgeFee.AmountLv4Str); err != nil {
 			mgr.alerter.AlertText("scan t_dynamic_bridge_fee row error", err)
+			scanErrors++
 		} else {
 			bridgeFee.FromChainName = strings.TrimSpace(bridgeFee.FromChainName)
 			bridgeFee.ToChainName = strings.TrimSpace(bridgeFee.ToChainName)
@@ -144,18 +163,7 @@ func (mgr *BridgeFeeManager) LoadAllBridgeFee(tokenInfoMgr TokenInfoManager) {
 				bridgeFee.KeepDecimal = int32(tokenInfo.Decimals)
 			}
 
-			ftInfos, ok := tokenFromToBridgeFees[strings.ToLower(bridgeFee.TokenName)]
-			if !ok {
-				ftInfos = make(map[string]map[string]*BridgeFee)
-				tokenFromToBridgeFees[strings.ToLower(bridgeFee.TokenName)] = ftInfos
-			}
-			infos, ok := ftInfos[strings.ToLower(bridgeFee.FromChainName)]
-			if !ok {
-				infos = make(map[string]*BridgeFee)
-				ftInfos[strings.ToLower(bridgeFee.FromChainName)] = infos
-			}
-			infos[strings.ToLower(bridgeFee.ToChainName)] = &bridgeFee
-
+			allBridgeFees = append(allBridgeFees, &bridgeFee)
 			counter++
 		}
 	}
@@ -166,11 +174,78 @@ func (mgr *BridgeFeeManager) LoadAllBridgeFee(tokenInfoMgr TokenInfoManager) {
 		return
 	}
 
+	mgr.mutex.RLock()
+	oldBridgeFees := flattenBridgeFees(mgr.tokenFromToBridgeFees)
+	mgr.mutex.RUnlock()
+	if err := mgr.snapshotState.allowReplace(len(oldBridgeFees), counter, scanErrors); err != nil {
+		mgr.alerter.AlertText("t_dynamic_bridge_fee load rejected, keep current bridge fees", err)
+		return
+	}
+
+	mgr.setBridgeFees(allBridgeFees)
+	if err = saveSnapshot(mgr.snapshotState, allBridgeFees); err != nil {
+		mgr.alerter.AlertText("save t_dynamic_bridge_fee snapshot error", err)
+	}
+
+	mgr.mutex.RLock()
+	newBridgeFees := flattenBridgeFees(mgr.tokenFromToBridgeFees)
+	mgr.mutex.RUnlock()
+	mgr.notify(diffChanges("t_dynamic_bridge_fee", oldBridgeFees, newBridgeFees, func(a *BridgeFee, b *BridgeFee) bool {
+		return *a == *b
+	}))
+}
+
+// RestoreSnapshot fills an empty manager from the snapshot file, so a service can start
+// while the database is unavailable. StaleSince reports the snapshot time until the next
+// successful load.
+func (mgr *BridgeFeeManager) RestoreSnapshot() error {
+	mgr.mutex.RLock()
+	loaded := len(mgr.tokenFromToBridgeFees) > 0
+	mgr.mutex.RUnlock()
+	if loaded {
+		return ErrAlreadyLoaded
+	}
+	allBridgeFees, err := readSnapshot[*BridgeFee](mgr.snapshotState)
+	if err != nil {
+		return err
+	}
+	mgr.setBridgeFees(allBridgeFees)
+	return nil
+}
+
+func (mgr *BridgeFeeManager) setBridgeFees(allBridgeFees []*BridgeFee) {
+	tokenFromToBridgeFees := make(map[string]map[string]map[string]*BridgeFee)
+	for _, bridgeFee := range allBridgeFees {
+		ftInfos, ok := tokenFromToBridgeFees[strings.ToLower(bridgeFee.TokenName)]
+		if !ok {
+			ftInfos = make(map[string]map[string]*BridgeFee)
+			tokenFromToBridgeFees[strings.ToLower(bridgeFee.TokenName)] = ftInfos
+		}
+		infos, ok := ftInfos[strings.ToLower(bridgeFee.FromChainName)]
+		if !ok {
+			infos = make(map[string]*BridgeFee)
+			ftInfos[strings.ToLower(bridgeFee.FromChainName)] = infos
+		}
+		infos[strings.ToLower(bridgeFee.ToChainName)] = bridgeFee
+	}
+
 	mgr.mutex.Lock()
 	mgr.tokenFromToBridgeFees = tokenFromToBridgeFees
 	mgr.mutex.Unlock()
 }
 
+func flattenBridgeFees(tokenFromToBridgeFees map[string]map[string]map[string]*BridgeFee) map[string]*BridgeFee {
+	bridgeFees := make(map[string]*BridgeFee)
+	for token, ftInfos := range tokenFromToBridgeFees {
+		for from, infos := range ftInfos {
+			for to, info := range infos {
+				bridgeFees[RouteKey(token, from, to)] = info
+			}
+		}
+	}
+	return bridgeFees
+}
+
 func (mgr *BridgeFeeManager) FromUiString(amount *big.Int, bridgeFee int64, decimal int32, keepDecimal int32) *big.Int {
 	value := big.NewInt(0)
 	value.Add(value, amount)
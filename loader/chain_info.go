@@ -3,9 +3,11 @@ package loader
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/NethermindEth/starknet.go/rpc"
 	"github.com/block-vision/sui-go-sdk/sui"
@@ -64,7 +66,7 @@ type ChainInfo struct {
 	TransferContractAddress sql.NullString
 	DepositContractAddress  sql.NullString
 	Layer1                  sql.NullString
-	Client                  interface{}
+	Client                  interface{} `json:"-"`
 }
 
 func (ci *ChainInfo) GetInt32ChainId() int32 {
@@ -97,6 +99,11 @@ type ChainInfoManager struct {
 	mutex   *sync.RWMutex
 
 	tonClient ton.APIClientWrapped
+
+	*changeNotifier
+	*snapshotState
+	incremental *incrementalState
+	*readRouter
 }
 
 func NewChainInfoManager(db *sql.DB, alerter alert.Alerter) *ChainInfoManager {
@@ -108,9 +115,26 @@ func NewChainInfoManager(db *sql.DB, alerter alert.Alerter) *ChainInfoManager {
 		db:            db,
 		alerter:       alerter,
 		mutex:         &sync.RWMutex{},
+
+		changeNotifier: newChangeNotifier(),
+		snapshotState:  newSnapshotState(),
+		incremental:    newIncrementalState(),
+		readRouter:     newReadRouter(db),
 	}
 }
 
+// SetFullReloadInterval changes how often LoadChangedChains falls back to a full reload.
+func (mgr *ChainInfoManager) SetFullReloadInterval(interval time.Duration) {
+	mgr.incremental.setFullReloadInterval(interval)
+}
+
+// SetDeltaLookback changes how far before the last applied update_timestamp LoadChangedChains
+// starts reading, see DefaultDeltaLookback. It should cover the longest transaction
+// writing the table plus the replica lag.
+func (mgr *ChainInfoManager) SetDeltaLookback(lookback time.Duration) {
+	mgr.incremental.setLookback(lookback)
+}
+
 func (mgr *ChainInfoManager) GetChainInfoAutoIds() []int64 {
 	mgr.mutex.RLock()
 	ids := make([]int64, 0, len(mgr.idChains))
@@ -168,97 +192,266 @@ func (mgr *ChainInfoManager) GetAllChains() []*ChainInfo {
 	return mgr.allChains
 }
 
+const chainInfoColumns = "id, chainid, real_chainid, name, alias_name, backend, eip1559, network_code, icon, block_interval, timeout, rpc_end_point, explorer_url, official_rpc, disabled, is_testnet, order_weight, gas_token_name, gas_token_address, gas_token_decimal, gas_token_icon, transfer_contract_address, deposit_contract_address, layer1, mev_rpc_url, UNIX_TIMESTAMP(update_timestamp)"
+
+func (ci *ChainInfo) sameAs(other *ChainInfo) bool {
+	a, b := *ci, *other
+	a.Client, b.Client = nil, nil
+	return a == b
+}
+
+func scanChainInfo(rows *sql.Rows) (*ChainInfo, int64, error) {
+	var chain ChainInfo
+	var updateTs int64
+	if err := rows.Scan(&chain.Id, &chain.ChainId, &chain.RealChainId, &chain.Name, &chain.AliasName, &chain.Backend,
+		&chain.Eip1559, &chain.NetworkCode, &chain.Icon, &chain.BlockInterval, &chain.Timeout, &chain.RpcEndPoint, &chain.ExplorerUrl,
+		&chain.OfficialRpc, &chain.Disabled, &chain.IsTestnet, &chain.OrderWeight, &chain.GasTokenName, &chain.GasTokenAddress,
+		&chain.GasTokenDecimal, &chain.GasTokenIcon, &chain.TransferContractAddress, &chain.DepositContractAddress,
+		&chain.Layer1, &chain.MevRpc, &updateTs); err != nil {
+		return nil, 0, err
+	}
+	chain.ChainId = strings.TrimSpace(chain.ChainId)
+	chain.RealChainId = strings.TrimSpace(chain.RealChainId)
+	chain.Name = strings.TrimSpace(chain.Name)
+	chain.AliasName = strings.TrimSpace(chain.AliasName)
+	chain.Icon = strings.TrimSpace(chain.Icon)
+	chain.RpcEndPoint = strings.TrimSpace(chain.RpcEndPoint)
+	chain.ExplorerUrl = strings.TrimSpace(chain.ExplorerUrl)
+	chain.OfficialRpc = strings.TrimSpace(chain.OfficialRpc)
+	chain.MevRpc = strings.TrimSpace(chain.MevRpc)
+	chain.GasTokenName = strings.TrimSpace(chain.GasTokenName)
+	chain.GasTokenAddress = strings.TrimSpace(chain.GasTokenAddress)
+	chain.GasTokenIcon = strings.TrimSpace(chain.GasTokenIcon)
+	chain.TransferContractAddress.String = strings.TrimSpace(chain.TransferContractAddress.String)
+	chain.DepositContractAddress.String = strings.TrimSpace(chain.DepositContractAddress.String)
+	chain.Layer1.String = strings.TrimSpace(chain.Layer1.String)
+	return &chain, updateTs, nil
+}
+
+// dialChain dials the client of a loaded chain. A chain that cannot be dialed is left
+// out of this load and alerted; the caller holds the watermark so the next delta load
+// retries it.
+func (mgr *ChainInfoManager) dialChain(chain *ChainInfo) bool {
+	if err := mgr.dialClient(chain); err != nil {
+		mgr.alerter.AlertText(fmt.Sprintf("dial chain %s failed, retry on next load", chain.Name), err)
+		return false
+	}
+	return true
+}
+
+func (mgr *ChainInfoManager) dialClient(chain *ChainInfo) error {
+	var err error
+	if chain.Backend == EthereumBackend {
+		chain.Client, err = ethclient.Dial(chain.RpcEndPoint)
+		if err != nil {
+			return fmt.Errorf("create evm client: %w", err)
+		}
+	} else if chain.Backend == StarknetBackend {
+		chain.Client, err = rpc.NewProvider(chain.RpcEndPoint)
+		if err != nil {
+			return fmt.Errorf("create starknet client: %w", err)
+		}
+	} else if chain.Backend == SolanaBackend {
+		chain.Client = solrpc.New(chain.RpcEndPoint)
+	} else if chain.Backend == SuiBackend {
+		chain.Client = sui.NewSuiClient(chain.RpcEndPoint)
+	} else if chain.Backend == TonBackend {
+		if mgr.tonClient == nil {
+			client := liteclient.NewConnectionPool()
+			configUrl := ConfigURLTestnet
+			if chain.IsTestnet == 0 {
+				configUrl = ConfigURLMainnet
+			}
+			err = client.AddConnectionsFromConfigUrl(context.Background(), configUrl)
+			if err != nil {
+				client.Stop()
+				return fmt.Errorf("create ton client: %w", err)
+			}
+			apiClient := ton.NewAPIClient(client).WithRetry()
+			chain.Client = apiClient
+			mgr.tonClient = apiClient
+		} else {
+			chain.Client = mgr.tonClient
+		}
+	} else if chain.Backend == FuelBackend {
+		chain.Client = fuel.NewClient(chain.RpcEndPoint)
+	}
+	return nil
+}
+
+// LoadAllChains reloads the whole t_chain_info table and notifies subscribers of
+// every chain that was added, changed or removed since the previous load.
 func (mgr *ChainInfoManager) LoadAllChains() {
-	// Query the database to select only id and name fields
-	rows, err := mgr.db.Query("SELECT id, chainid, real_chainid, name, alias_name, backend, eip1559, network_code, icon, block_interval, timeout, rpc_end_point, explorer_url, official_rpc, disabled, is_testnet, order_weight, gas_token_name, gas_token_address, gas_token_decimal, gas_token_icon, transfer_contract_address, deposit_contract_address, layer1, mev_rpc_url FROM t_chain_info")
+	span := startReload("t_chain_info")
+	defer span.End()
+	mgr.incremental.mutex.Lock()
+	defer mgr.incremental.mutex.Unlock()
+	mgr.loadAllChains()
+}
 
-	if err != nil || rows == nil {
-		mgr.alerter.AlertText("select t_chain_info error", err)
+// LoadChangedChains only reads chains whose update_timestamp moved since the last load,
+// less the delta lookback, falling back to LoadAllChains on the first call and every full
+// reload interval.
+func (mgr *ChainInfoManager) LoadChangedChains() {
+	span := startReload("t_chain_info")
+	defer span.End()
+	mgr.incremental.mutex.Lock()
+	defer mgr.incremental.mutex.Unlock()
+
+	if mgr.incremental.needFullLoad(time.Now()) {
+		mgr.loadAllChains()
 		return
 	}
 
+	rows, err := mgr.scanDB().Query("SELECT "+chainInfoColumns+" FROM t_chain_info WHERE update_timestamp >= FROM_UNIXTIME(?)", mgr.incremental.mark.since())
+	if err != nil || rows == nil {
+		mgr.alerter.AlertText("select changed t_chain_info error", err)
+		return
+	}
 	defer rows.Close()
 
+	mark := newMarkBuilder(mgr.incremental.mark)
+	changedChains := make([]*ChainInfo, 0)
+	for rows.Next() {
+		chain, updateTs, err := scanChainInfo(rows)
+		if err != nil {
+			mgr.alerter.AlertText("scan t_chain_info row error", err)
+			// the row's timestamp is unknown, read everything since the last mark again
+			mark.hold(mgr.incremental.mark.since())
+			continue
+		}
+		markKey := strconv.FormatInt(chain.Id, 10)
+		version := rowVersion(chain)
+		if mark.seen(markKey, updateTs, version) {
+			continue
+		}
+		if !mgr.dialChain(chain) {
+			mark.hold(updateTs)
+			continue
+		}
+		mark.apply(markKey, updateTs, version)
+		changedChains = append(changedChains, chain)
+	}
+
+	if err := rows.Err(); err != nil {
+		mgr.alerter.AlertText("get next t_chain_info row error", err)
+		return
+	}
+	mgr.incremental.mark = mark.build()
+	if len(changedChains) == 0 {
+		return
+	}
+
+	mgr.mutex.RLock()
+	allChains := make([]*ChainInfo, len(mgr.allChains))
+	copy(allChains, mgr.allChains)
+	oldIdChains := mgr.idChains
+	mgr.mutex.RUnlock()
+
+	for _, chain := range changedChains {
+		old, ok := oldIdChains[chain.Id]
+		if !ok {
+			allChains = append(allChains, chain)
+			continue
+		}
+		for i := range allChains {
+			if allChains[i] == old {
+				allChains[i] = chain
+			}
+		}
+	}
+
+	oldNameChains := mgr.setChains(allChains)
+	if err = saveSnapshot(mgr.snapshotState, allChains); err != nil {
+		mgr.alerter.AlertText("save t_chain_info snapshot error", err)
+	}
+	mgr.notify(diffChanges("t_chain_info", oldNameChains, mgr.getNameChains(), (*ChainInfo).sameAs))
+}
+
+// RestoreSnapshot fills an empty manager from the snapshot file, so a service can start
+// while the database is unavailable. StaleSince reports the snapshot time until the next
+// successful load.
+func (mgr *ChainInfoManager) RestoreSnapshot() error {
+	mgr.incremental.mutex.Lock()
+	defer mgr.incremental.mutex.Unlock()
+
+	if len(mgr.GetChainInfoAutoIds()) > 0 {
+		return ErrAlreadyLoaded
+	}
+	chains, err := readSnapshot[*ChainInfo](mgr.snapshotState)
+	if err != nil {
+		return err
+	}
+	allChains := make([]*ChainInfo, 0, len(chains))
+	for _, chain := range chains {
+		if !mgr.dialChain(chain) {
+			continue
+		}
+		allChains = append(allChains, chain)
+	}
+	mgr.setChains(allChains)
+	return nil
+}
+
+func (mgr *ChainInfoManager) getNameChains() map[string]*ChainInfo {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	return mgr.nameChains
+}
+
+// setChains rebuilds every index from allChains and swaps them in, returning the
+// previous name index.
+func (mgr *ChainInfoManager) setChains(allChains []*ChainInfo) map[string]*ChainInfo {
 	idChains := make(map[int64]*ChainInfo)
 	netcodeChains := make(map[int32]*ChainInfo)
 	chainIdChains := make(map[string]*ChainInfo)
 	nameChains := make(map[string]*ChainInfo)
-	allChains := make([]*ChainInfo, 0)
 
-	counter := 0
+	for _, chain := range allChains {
+		idChains[chain.Id] = chain
+		chainIdChains[strings.ToLower(chain.ChainId)] = chain
+		nameChains[strings.ToLower(chain.Name)] = chain
+		netcodeChains[chain.NetworkCode] = chain
+	}
+
+	mgr.mutex.Lock()
+	oldNameChains := mgr.nameChains
+	mgr.idChains = idChains
+	mgr.chainIdChains = chainIdChains
+	mgr.nameChains = nameChains
+	mgr.netcodeChains = netcodeChains
+	mgr.allChains = allChains
+	mgr.mutex.Unlock()
+	return oldNameChains
+}
+
+func (mgr *ChainInfoManager) loadAllChains() {
+	now := time.Now()
+	// Query the database to select only id and name fields
+	rows, err := mgr.scanDB().Query("SELECT " + chainInfoColumns + " FROM t_chain_info")
+
+	if err != nil || rows == nil {
+		mgr.alerter.AlertText("select t_chain_info error", err)
+		return
+	}
+
+	defer rows.Close()
+
+	type scannedChain struct {
+		chain    *ChainInfo
+		updateTs int64
+	}
+	scanned := make([]scannedChain, 0)
+	scanErrors := 0
 
 	// Iterate over the result set
 	for rows.Next() {
-		var chain ChainInfo
-
-		if err = rows.Scan(&chain.Id, &chain.ChainId, &chain.RealChainId, &chain.Name, &chain.AliasName, &chain.Backend,
-			&chain.Eip1559, &chain.NetworkCode, &chain.Icon, &chain.BlockInterval, &chain.Timeout, &chain.RpcEndPoint, &chain.ExplorerUrl,
-			&chain.OfficialRpc, &chain.Disabled, &chain.IsTestnet, &chain.OrderWeight, &chain.GasTokenName, &chain.GasTokenAddress,
-			&chain.GasTokenDecimal, &chain.GasTokenIcon, &chain.TransferContractAddress, &chain.DepositContractAddress,
-			&chain.Layer1, &chain.MevRpc); err != nil {
+		chain, updateTs, err := scanChainInfo(rows)
+		if err != nil {
 			mgr.alerter.AlertText("scan t_chain_info row error", err)
+			scanErrors++
 		} else {
-			chain.ChainId = strings.TrimSpace(chain.ChainId)
-			chain.RealChainId = strings.TrimSpace(chain.RealChainId)
-			chain.Name = strings.TrimSpace(chain.Name)
-			chain.AliasName = strings.TrimSpace(chain.AliasName)
-			chain.Icon = strings.TrimSpace(chain.Icon)
-			chain.RpcEndPoint = strings.TrimSpace(chain.RpcEndPoint)
-			chain.ExplorerUrl = strings.TrimSpace(chain.ExplorerUrl)
-			chain.OfficialRpc = strings.TrimSpace(chain.OfficialRpc)
-			chain.MevRpc = strings.TrimSpace(chain.MevRpc)
-			chain.GasTokenName = strings.TrimSpace(chain.GasTokenName)
-			chain.GasTokenAddress = strings.TrimSpace(chain.GasTokenAddress)
-			chain.GasTokenIcon = strings.TrimSpace(chain.GasTokenIcon)
-			chain.TransferContractAddress.String = strings.TrimSpace(chain.TransferContractAddress.String)
-			chain.DepositContractAddress.String = strings.TrimSpace(chain.DepositContractAddress.String)
-			chain.Layer1.String = strings.TrimSpace(chain.Layer1.String)
-
-			if chain.Backend == EthereumBackend {
-				chain.Client, err = ethclient.Dial(chain.RpcEndPoint)
-				if err != nil {
-					mgr.alerter.AlertText("create evm client error", err)
-					continue
-				}
-			} else if chain.Backend == StarknetBackend {
-				chain.Client, err = rpc.NewProvider(chain.RpcEndPoint)
-				if err != nil {
-					mgr.alerter.AlertText("create starknet client error", err)
-					continue
-				}
-			} else if chain.Backend == SolanaBackend {
-				chain.Client = solrpc.New(chain.RpcEndPoint)
-			} else if chain.Backend == SuiBackend {
-				chain.Client = sui.NewSuiClient(chain.RpcEndPoint)
-			} else if chain.Backend == TonBackend {
-				if mgr.tonClient == nil {
-					client := liteclient.NewConnectionPool()
-					configUrl := ConfigURLTestnet
-					if chain.IsTestnet == 0 {
-						configUrl = ConfigURLMainnet
-					}
-					err = client.AddConnectionsFromConfigUrl(context.Background(), configUrl)
-					if err != nil {
-						mgr.alerter.AlertText("create ton client error", err)
-						client.Stop()
-						continue
-					}
-					apiClient := ton.NewAPIClient(client).WithRetry()
-					chain.Client = apiClient
-					mgr.tonClient = apiClient
-				} else {
-					chain.Client = mgr.tonClient
-				}
-			} else if chain.Backend == FuelBackend {
-				chain.Client = fuel.NewClient(chain.RpcEndPoint)
-			}
-
-			idChains[chain.Id] = &chain
-			chainIdChains[strings.ToLower(chain.ChainId)] = &chain
-			nameChains[strings.ToLower(chain.Name)] = &chain
-			netcodeChains[chain.NetworkCode] = &chain
-			allChains = append(allChains, &chain)
-			counter++
+			scanned = append(scanned, scannedChain{chain: chain, updateTs: updateTs})
 		}
 	}
 
@@ -268,11 +461,30 @@ func (mgr *ChainInfoManager) LoadAllChains() {
 		return
 	}
 
-	mgr.mutex.Lock()
-	mgr.idChains = idChains
-	mgr.chainIdChains = chainIdChains
-	mgr.nameChains = nameChains
-	mgr.netcodeChains = netcodeChains
-	mgr.allChains = allChains
-	mgr.mutex.Unlock()
+	if err := mgr.snapshotState.allowReplace(len(mgr.GetChainInfoAutoIds()), len(scanned), scanErrors); err != nil {
+		mgr.alerter.AlertText("t_chain_info load rejected, keep current chains", err)
+		return
+	}
+
+	// dial only once the load is accepted, so a rejected load leaves no clients behind
+	allChains := make([]*ChainInfo, 0, len(scanned))
+	mark := newMarkBuilder(mgr.incremental.newMark())
+	for _, s := range scanned {
+		version := rowVersion(s.chain)
+		if !mgr.dialChain(s.chain) {
+			mark.hold(s.updateTs)
+			continue
+		}
+		mark.apply(strconv.FormatInt(s.chain.Id, 10), s.updateTs, version)
+		allChains = append(allChains, s.chain)
+	}
+
+	oldNameChains := mgr.setChains(allChains)
+	if err = saveSnapshot(mgr.snapshotState, allChains); err != nil {
+		mgr.alerter.AlertText("save t_chain_info snapshot error", err)
+	}
+
+	mgr.incremental.mark = mark.build()
+	mgr.incremental.lastFullLoad = now
+	mgr.notify(diffChanges("t_chain_info", oldNameChains, mgr.getNameChains(), (*ChainInfo).sameAs))
 }
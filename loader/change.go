@@ -0,0 +1,252 @@
+package loader
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultFullReloadInterval is how often a delta loader falls back to a full
+// table scan. Deltas only see rows whose update_timestamp moved, so deleted
+// rows are only noticed by a full reload.
+const DefaultFullReloadInterval = 10 * time.Minute
+
+// DefaultDeltaLookback is how far before the last applied update_timestamp a delta
+// load starts reading, so rows that commit late or reach a replica late are not skipped.
+const DefaultDeltaLookback = 30 * time.Second
+
+type ChangeType int32
+
+const (
+	ChangeUpsert ChangeType = iota + 1
+	ChangeDelete
+)
+
+// Change identifies a single row that was added, updated or removed between
+// two loads, keyed the same way the manager indexes it.
+type Change struct {
+	Table string
+	Key   string
+	Type  ChangeType
+}
+
+type ChangeHandler func(changes []Change)
+
+// TokenKey is the Change key used for token rows.
+func TokenKey(chainName string, tokenAddr string) string {
+	return strings.ToLower(strings.TrimSpace(chainName)) + "#" + strings.ToLower(strings.TrimSpace(tokenAddr))
+}
+
+// RouteKey is the Change key used for per route rows such as bridge fees, dtc and lp infos.
+func RouteKey(token string, from string, to string) string {
+	return strings.ToLower(strings.TrimSpace(token)) + "#" + strings.ToLower(strings.TrimSpace(from)) + "#" + strings.ToLower(strings.TrimSpace(to))
+}
+
+type changeNotifier struct {
+	handlers []ChangeHandler
+	mutex    *sync.RWMutex
+}
+
+func newChangeNotifier() *changeNotifier {
+	return &changeNotifier{
+		mutex: &sync.RWMutex{},
+	}
+}
+
+// Subscribe registers a handler called after every load that changed at least one row.
+// Handlers run synchronously on the loading goroutine and must not block.
+func (n *changeNotifier) Subscribe(handler ChangeHandler) {
+	n.mutex.Lock()
+	n.handlers = append(n.handlers, handler)
+	n.mutex.Unlock()
+}
+
+func (n *changeNotifier) notify(changes []Change) {
+	if len(changes) == 0 {
+		return
+	}
+	n.mutex.RLock()
+	handlers := n.handlers
+	n.mutex.RUnlock()
+	for _, handler := range handlers {
+		handler(changes)
+	}
+}
+
+// diffChanges compares two snapshots of the same table and reports every key that
+// was added, changed or removed.
+func diffChanges[V any](table string, oldItems map[string]V, newItems map[string]V, same func(a V, b V) bool) []Change {
+	changes := make([]Change, 0)
+	for key, newItem := range newItems {
+		oldItem, ok := oldItems[key]
+		if !ok || !same(oldItem, newItem) {
+			changes = append(changes, Change{Table: table, Key: key, Type: ChangeUpsert})
+		}
+	}
+	for key := range oldItems {
+		if _, ok := newItems[key]; !ok {
+			changes = append(changes, Change{Table: table, Key: key, Type: ChangeDelete})
+		}
+	}
+	return changes
+}
+
+type markVersion struct {
+	ts      int64
+	version string
+}
+
+// watermark is the highest update_timestamp (unix seconds) applied so far, along with
+// the version of every row applied within lookback seconds of it. Delta queries read
+// again from lookback seconds before the watermark, since a row is stamped when its
+// statement runs but only becomes visible when its transaction commits, and later still
+// on a lagging replica. The versions let us skip the rows we already have while still
+// picking up a second update of the same row within that window.
+type watermark struct {
+	ts       int64
+	lookback int64
+	keys     map[string]markVersion
+}
+
+// since is the update_timestamp the next delta query starts at.
+func (wm *watermark) since() int64 {
+	return wm.ts - wm.lookback
+}
+
+func (wm *watermark) clone() watermark {
+	keys := make(map[string]markVersion, len(wm.keys))
+	for k, v := range wm.keys {
+		keys[k] = v
+	}
+	return watermark{ts: wm.ts, lookback: wm.lookback, keys: keys}
+}
+
+func (wm *watermark) seen(key string, ts int64, version string) bool {
+	if ts < wm.since() {
+		return true
+	}
+	applied, ok := wm.keys[key]
+	return ok && applied.ts == ts && applied.version == version
+}
+
+func (wm *watermark) advance(key string, ts int64, version string) {
+	if wm.keys == nil {
+		wm.keys = make(map[string]markVersion)
+	}
+	if ts > wm.ts {
+		wm.ts = ts
+	}
+	if applied, ok := wm.keys[key]; !ok || ts >= applied.ts {
+		wm.keys[key] = markVersion{ts: ts, version: version}
+	}
+}
+
+// prune forgets the rows that fell out of the lookback window.
+func (wm *watermark) prune() {
+	since := wm.since()
+	for k, v := range wm.keys {
+		if v.ts < since {
+			delete(wm.keys, k)
+		}
+	}
+}
+
+// rowVersion identifies the content of a row for the watermark.
+func rowVersion(row interface{}) string {
+	b, _ := json.Marshal(row)
+	return string(b)
+}
+
+type markEntry struct {
+	key     string
+	ts      int64
+	version string
+}
+
+// markBuilder collects the rows one load applied and builds the next watermark. A row
+// that could not be applied holds the watermark at its update_timestamp, so the next
+// delta load reads it, and everything after it, again.
+type markBuilder struct {
+	base    watermark
+	applied []markEntry
+	holdTs  int64
+	held    bool
+}
+
+func newMarkBuilder(base watermark) *markBuilder {
+	return &markBuilder{base: base.clone()}
+}
+
+func (b *markBuilder) seen(key string, ts int64, version string) bool {
+	return b.base.seen(key, ts, version)
+}
+
+func (b *markBuilder) apply(key string, ts int64, version string) {
+	b.applied = append(b.applied, markEntry{key: key, ts: ts, version: version})
+}
+
+func (b *markBuilder) hold(ts int64) {
+	if !b.held || ts < b.holdTs {
+		b.holdTs = ts
+		b.held = true
+	}
+}
+
+func (b *markBuilder) build() watermark {
+	mark := b.base.clone()
+	for _, e := range b.applied {
+		if !b.held || e.ts <= b.holdTs {
+			mark.advance(e.key, e.ts, e.version)
+		}
+	}
+	mark.prune()
+	return mark
+}
+
+// incrementalState tracks where the last delta load stopped and when the last full
+// load happened. Its mutex serializes loads of one manager.
+type incrementalState struct {
+	fullReloadInterval time.Duration
+	lastFullLoad       time.Time
+	lookback           time.Duration
+	mark               watermark
+	mutex              *sync.Mutex
+}
+
+func newIncrementalState() *incrementalState {
+	return &incrementalState{
+		fullReloadInterval: DefaultFullReloadInterval,
+		lookback:           DefaultDeltaLookback,
+		mark:               watermark{lookback: int64(DefaultDeltaLookback / time.Second)},
+		mutex:              &sync.Mutex{},
+	}
+}
+
+// newMark returns the empty watermark a full load starts from.
+func (s *incrementalState) newMark() watermark {
+	return watermark{lookback: int64(s.lookback / time.Second)}
+}
+
+// setLookback changes how far before the watermark delta loads read again.
+func (s *incrementalState) setLookback(lookback time.Duration) {
+	s.mutex.Lock()
+	s.lookback = lookback
+	s.mark.lookback = int64(lookback / time.Second)
+	s.mutex.Unlock()
+}
+
+// setFullReloadInterval changes how often delta loads fall back to a full reload.
+// A non positive interval disables the periodic full reload.
+func (s *incrementalState) setFullReloadInterval(interval time.Duration) {
+	s.mutex.Lock()
+	s.fullReloadInterval = interval
+	s.mutex.Unlock()
+}
+
+func (s *incrementalState) needFullLoad(now time.Time) bool {
+	if s.lastFullLoad.IsZero() {
+		return true
+	}
+	return s.fullReloadInterval > 0 && now.Sub(s.lastFullLoad) >= s.fullReloadInterval
+}
@@ -0,0 +1,94 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatermark(t *testing.T) {
+	var mark watermark
+	mark.advance("1", 100, "a")
+	mark.advance("2", 100, "a")
+	assert.True(t, mark.seen("1", 100, "a"))
+	assert.False(t, mark.seen("3", 100, "a"))
+	assert.True(t, mark.seen("3", 99, "a"))
+	// a second update of the same row within the same second
+	assert.False(t, mark.seen("1", 100, "b"))
+
+	next := mark.clone()
+	next.advance("3", 101, "a")
+	assert.False(t, next.seen("1", 101, "a"))
+	assert.True(t, mark.seen("2", 100, "a"))
+	assert.Equal(t, int64(100), mark.ts)
+}
+
+func TestMarkBuilderHold(t *testing.T) {
+	var base watermark
+	base.advance("1", 100, "a")
+
+	b := newMarkBuilder(base)
+	b.apply("2", 100, "a")
+	b.hold(101)
+	b.apply("3", 101, "a")
+	b.apply("4", 105, "a")
+	mark := b.build()
+	// the failed row at 101 and everything after it are read again
+	assert.Equal(t, int64(101), mark.ts)
+	assert.True(t, mark.seen("3", 101, "a"))
+	assert.False(t, mark.seen("5", 101, "a"))
+	assert.False(t, mark.seen("4", 105, "a"))
+
+	b = newMarkBuilder(base)
+	b.apply("2", 102, "a")
+	mark = b.build()
+	assert.Equal(t, int64(102), mark.ts)
+	assert.True(t, mark.seen("1", 100, "a"))
+}
+
+func TestWatermarkLookback(t *testing.T) {
+	b := newMarkBuilder(watermark{lookback: 30})
+	b.apply("1", 100, "a")
+	b.apply("2", 60, "a")
+	b.apply("3", 75, "a")
+	mark := b.build()
+	assert.Equal(t, int64(70), mark.since())
+	assert.NotContains(t, mark.keys, "2")
+
+	// a row stamped before the watermark that committed after the last load is read
+	assert.False(t, mark.seen("4", 90, "a"))
+	assert.True(t, mark.seen("3", 75, "a"))
+	assert.False(t, mark.seen("3", 75, "b"))
+	assert.True(t, mark.seen("2", 60, "a"))
+
+	b = newMarkBuilder(mark)
+	b.apply("4", 90, "a")
+	next := b.build()
+	assert.Equal(t, int64(100), next.ts)
+	assert.True(t, next.seen("4", 90, "a"))
+	assert.True(t, next.seen("1", 100, "a"))
+}
+
+func TestDiffChanges(t *testing.T) {
+	oldFees := map[string]*BridgeFee{
+		RouteKey("ETH", "A", "B"): {TokenName: "ETH", BridgeFeeRatioLv1: 1},
+		RouteKey("ETH", "A", "C"): {TokenName: "ETH", BridgeFeeRatioLv1: 1},
+	}
+	newFees := map[string]*BridgeFee{
+		RouteKey("ETH", "A", "B"):  {TokenName: "ETH", BridgeFeeRatioLv1: 2},
+		RouteKey("USDC", "A", "C"): {TokenName: "USDC"},
+	}
+	changes := diffChanges("t_dynamic_bridge_fee", oldFees, newFees, func(a *BridgeFee, b *BridgeFee) bool {
+		return *a == *b
+	})
+
+	got := make(map[string]ChangeType)
+	for _, change := range changes {
+		got[change.Key] = change.Type
+	}
+	assert.Equal(t, map[string]ChangeType{
+		"eth#a#b":  ChangeUpsert,
+		"usdc#a#c": ChangeUpsert,
+		"eth#a#c":  ChangeDelete,
+	}, got)
+}
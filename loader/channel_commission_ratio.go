@@ -20,6 +20,8 @@ type ChannelCommissionRatioManager struct {
 	db      *sql.DB
 	alerter alert.Alerter
 	mutex   *sync.RWMutex
+
+	*readRouter
 }
 
 func NewChannelCommissionRatioManager(db *sql.DB, alerter alert.Alerter) *ChannelCommissionRatioManager {
@@ -30,6 +32,8 @@ func NewChannelCommissionRatioManager(db *sql.DB, alerter alert.Alerter) *Channe
 		db:      db,
 		alerter: alerter,
 		mutex:   &sync.RWMutex{},
+
+		readRouter: newReadRouter(db),
 	}
 }
 
@@ -48,8 +52,12 @@ func (mgr *ChannelCommissionRatioManager) GetRatioByChannelidAndCount(channelid
 	}
 }
 
+const commissionRatioColumns = "channel_id, tx_count, commission_ratio"
+
 func (mgr *ChannelCommissionRatioManager) LoadAllCommissionRatio() {
-	rows, err := mgr.db.Query("select channel_id, tx_count, commission_ratio from t_channel_commission_ratio order by tx_count asc")
+	span := startReload("t_channel_commission_ratio")
+	defer span.End()
+	rows, err := mgr.scanDB().Query("select " + commissionRatioColumns + " from t_channel_commission_ratio order by tx_count asc")
 
 	if err != nil || rows == nil {
 		mgr.alerter.AlertText("select t_channel_commission_ratio error", err)
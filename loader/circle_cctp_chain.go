@@ -7,7 +7,7 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/owlto-dao/utils-go/alert"
+	"github.com/realcaishen/utils-go/alert"
 )
 
 type CircleCctpChain struct {
@@ -40,6 +40,9 @@ type CircleCctpChainManager struct {
 	db            *sql.DB
 	alerter       alert.Alerter
 	mutex         *sync.RWMutex
+
+	*snapshotState
+	*readRouter
 }
 
 func NewCircleCctpChainManager(db *sql.DB, alerter alert.Alerter) *CircleCctpChainManager {
@@ -48,6 +51,9 @@ func NewCircleCctpChainManager(db *sql.DB, alerter alert.Alerter) *CircleCctpCha
 		db:            db,
 		alerter:       alerter,
 		mutex:         &sync.RWMutex{},
+
+		snapshotState: newSnapshotState(),
+		readRouter:    newReadRouter(db),
 	}
 }
 
@@ -77,9 +83,13 @@ func (mgr *CircleCctpChainManager) GetChainIds() []int32 {
 	return chainIds
 }
 
+const cctpChainColumns = "chainid, min_value, domain, token_messenger, message_transmitter, token_messengerv2, message_transmitterv2"
+
 func (mgr *CircleCctpChainManager) LoadAllChains() {
+	span := startReload("t_cctp_support_chain")
+	defer span.End()
 	// Query the database to select only id and name fields
-	rows, err := mgr.db.Query("SELECT chainid, min_value, domain, token_messenger, message_transmitter, token_messengerv2, message_transmitterv2 FROM t_cctp_support_chain")
+	rows, err := mgr.scanDB().Query("SELECT " + cctpChainColumns + " FROM t_cctp_support_chain")
 
 	if err != nil || rows == nil {
 		mgr.alerter.AlertText("select t_cctp_support_chain error", err)
@@ -88,14 +98,16 @@ func (mgr *CircleCctpChainManager) LoadAllChains() {
 
 	defer rows.Close()
 
-	chainIdChains := make(map[int32]*CircleCctpChain)
+	allChains := make([]*CircleCctpChain, 0)
 	counter := 0
+	scanErrors := 0
 
 	// Iterate over the result set
 	for rows.Next() {
 		var chain CircleCctpChain
 		if err := rows.Scan(&chain.ChainId, &chain.MinValue, &chain.Domain, &chain.TokenMessenger, &chain.MessageTransmitter, &chain.TokenMessengerV2, &chain.MessageTransmitterV2); err != nil {
 			mgr.alerter.AlertText("scan t_cctp_support_chain row error", err)
+			scanErrors++
 		} else {
 			chain.MessageTransmitter = strings.TrimSpace(chain.MessageTransmitter)
 			chain.TokenMessenger = strings.TrimSpace(chain.TokenMessenger)
@@ -106,7 +118,7 @@ func (mgr *CircleCctpChainManager) LoadAllChains() {
 				continue
 			}
 
-			chainIdChains[chain.ChainId] = &chain
+			allChains = append(allChains, &chain)
 			counter++
 		}
 	}
@@ -117,6 +129,38 @@ func (mgr *CircleCctpChainManager) LoadAllChains() {
 		return
 	}
 
+	if err := mgr.snapshotState.allowReplace(len(mgr.GetChainIds()), counter, scanErrors); err != nil {
+		mgr.alerter.AlertText("t_cctp_support_chain load rejected, keep current chains", err)
+		return
+	}
+
+	mgr.setChains(allChains)
+	if err = saveSnapshot(mgr.snapshotState, allChains); err != nil {
+		mgr.alerter.AlertText("save t_cctp_support_chain snapshot error", err)
+	}
+}
+
+// RestoreSnapshot fills an empty manager from the snapshot file, so a service can start
+// while the database is unavailable. StaleSince reports the snapshot time until the next
+// successful load.
+func (mgr *CircleCctpChainManager) RestoreSnapshot() error {
+	if len(mgr.GetChainIds()) > 0 {
+		return ErrAlreadyLoaded
+	}
+	allChains, err := readSnapshot[*CircleCctpChain](mgr.snapshotState)
+	if err != nil {
+		return err
+	}
+	mgr.setChains(allChains)
+	return nil
+}
+
+func (mgr *CircleCctpChainManager) setChains(allChains []*CircleCctpChain) {
+	chainIdChains := make(map[int32]*CircleCctpChain)
+	for _, chain := range allChains {
+		chainIdChains[chain.ChainId] = chain
+	}
+
 	mgr.mutex.Lock()
 	mgr.chainIdChains = chainIdChains
 	mgr.mutex.Unlock()
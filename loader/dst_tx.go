@@ -1,6 +1,7 @@
 package loader
 
 import (
+	"context"
 	"database/sql"
 	"strings"
 
@@ -8,6 +9,7 @@ import (
 )
 
 type DstTx struct {
+	Id                int64
 	SrcAction         string
 	SrcId             int64
 	SrcVersion        int32
@@ -39,6 +41,11 @@ func NewDstTxManager(db *sql.DB, alerter alert.Alerter) *DstTxManager {
 	}
 }
 
+const (
+	txGenColumns = "id, hash, confirmed_success"
+	dstTxColumns = "id, src_action, src_id, src_version, sender, body, fee_cap, transfer_token, transfer_recipient, transfer_amount"
+)
+
 func (mgr *DstTxManager) GetDoneTxGenBySrc(srcId int64, action string, version int32) *TxGen {
 	genId := mgr.GetDstTxConfirmGen(srcId, action, version)
 	if genId == 0 {
@@ -49,7 +56,7 @@ func (mgr *DstTxManager) GetDoneTxGenBySrc(srcId int64, action string, version i
 
 func (mgr *DstTxManager) GetDoneTxGen(genId int64) *TxGen {
 	var gen TxGen
-	err := mgr.db.QueryRow("SELECT id,hash, confirmed_success FROM t_dst_transaction_gen where id = ? and confirmed_success is not null", genId).Scan(&gen.Id, &gen.Hash, &gen.ConfirmedSuccess)
+	err := mgr.db.QueryRow("SELECT "+txGenColumns+" FROM t_dst_transaction_gen where id = ? and confirmed_success is not null", genId).Scan(&gen.Id, &gen.Hash, &gen.ConfirmedSuccess)
 	if err != nil {
 		return nil
 	}
@@ -72,17 +79,23 @@ func (mgr *DstTxManager) GetDstTxConfirmGen(srcId int64, action string, version
 	return genId
 }
 
-func (mgr *DstTxManager) Save(tx *DstTx) error {
+func (tx *DstTx) normalize() {
 	tx.SrcAction = strings.TrimSpace(tx.SrcAction)
 	tx.Body = strings.TrimSpace(tx.Body)
 	tx.FeeCap.String = strings.TrimSpace(tx.FeeCap.String)
 	tx.TransferToken.String = strings.TrimSpace(tx.TransferToken.String)
 	tx.TransferRecipient.String = strings.TrimSpace(tx.TransferRecipient.String)
 	tx.TransferAmount.String = strings.TrimSpace(tx.TransferAmount.String)
+}
 
-	query := `INSERT IGNORE INTO t_dst_transaction (src_action, src_id, src_version, sender, body, fee_cap, transfer_token, transfer_recipient, transfer_amount)
+const dstTxInsertColumns = `(src_action, src_id, src_version, sender, body, fee_cap, transfer_token, transfer_recipient, transfer_amount)
               VALUES (?, ?, ?, ?, ?, ?, ? , ?, ?)`
 
+func (mgr *DstTxManager) Save(tx *DstTx) error {
+	tx.normalize()
+
+	query := `INSERT IGNORE INTO t_dst_transaction ` + dstTxInsertColumns
+
 	// Execute the SQL statement with tx data
 	_, err := mgr.db.Exec(query, tx.SrcAction, tx.SrcId, tx.SrcVersion, tx.Sender, tx.Body, tx.FeeCap, tx.TransferToken, tx.TransferRecipient, tx.TransferAmount)
 	if err != nil {
@@ -92,3 +105,50 @@ func (mgr *DstTxManager) Save(tx *DstTx) error {
 	return nil
 
 }
+
+// Insert stores tx through q and sets tx.Id. Unlike Save, an existing
+// (src_action, src_id, src_version) is reported as ErrDuplicate instead of being ignored.
+func (mgr *DstTxManager) Insert(ctx context.Context, q DBTX, tx *DstTx) error {
+	tx.normalize()
+	result, err := q.ExecContext(ctx, "INSERT INTO t_dst_transaction "+dstTxInsertColumns,
+		tx.SrcAction, tx.SrcId, tx.SrcVersion, tx.Sender, tx.Body, tx.FeeCap, tx.TransferToken, tx.TransferRecipient, tx.TransferAmount)
+	if err != nil {
+		return repoError(err)
+	}
+	tx.Id, err = result.LastInsertId()
+	return err
+}
+
+// GetBySrc returns the dst transaction built for a src action or ErrNotFound.
+func (mgr *DstTxManager) GetBySrc(ctx context.Context, q DBTX, srcId int64, action string, version int32) (*DstTx, error) {
+	var tx DstTx
+	err := q.QueryRowContext(ctx, "SELECT "+dstTxColumns+" FROM t_dst_transaction WHERE src_action = ? AND src_id = ? AND src_version = ?",
+		strings.TrimSpace(action), srcId, version).
+		Scan(&tx.Id, &tx.SrcAction, &tx.SrcId, &tx.SrcVersion, &tx.Sender, &tx.Body, &tx.FeeCap, &tx.TransferToken, &tx.TransferRecipient, &tx.TransferAmount)
+	if err != nil {
+		return nil, repoError(err)
+	}
+	tx.normalize()
+	return &tx, nil
+}
+
+// SetConfirmedGen records which gen of a dst transaction got confirmed. It returns
+// ErrConflict if another gen was already recorded and ErrNotFound if the row is missing.
+func (mgr *DstTxManager) SetConfirmedGen(ctx context.Context, q DBTX, id int64, genId int64) (int64, error) {
+	result, err := q.ExecContext(ctx, "UPDATE t_dst_transaction SET confirmed_gen = ? WHERE id = ? AND confirmed_gen IS NULL", genId, id)
+	if err != nil {
+		return 0, repoError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected > 0 {
+		return affected, err
+	}
+	var confirmedGen sql.NullInt64
+	if err = q.QueryRowContext(ctx, "SELECT confirmed_gen FROM t_dst_transaction WHERE id = ?", id).Scan(&confirmedGen); err != nil {
+		return 0, repoError(err)
+	}
+	if confirmedGen.Int64 != genId {
+		return 0, ErrConflict
+	}
+	return 0, nil
+}
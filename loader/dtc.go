@@ -40,6 +40,10 @@ type DtcManager struct {
 	db      *sql.DB
 	alerter alert.Alerter
 	mutex   *sync.RWMutex
+
+	*changeNotifier
+	*snapshotState
+	*readRouter
 }
 
 func NewDtcManager(db *sql.DB, alerter alert.Alerter) *DtcManager {
@@ -49,6 +53,10 @@ func NewDtcManager(db *sql.DB, alerter alert.Alerter) *DtcManager {
 		db:      db,
 		alerter: alerter,
 		mutex:   &sync.RWMutex{},
+
+		changeNotifier: newChangeNotifier(),
+		snapshotState:  newSnapshotState(),
+		readRouter:     newReadRouter(db),
 	}
 }
 
@@ -73,9 +81,15 @@ func (mgr *DtcManager) GetDtc(token string, from string, to string) (*Dtc, bool)
 	return nil, false
 }
 
+const dtcColumns = "token_name, from_chain, to_chain, dtc_lv1, dtc_lv2, dtc_lv3, dtc_lv4, amount_lv1, amount_lv2, amount_lv3, amount_lv4"
+
+// LoadAllDtc reads the whole t_dynamic_dtc table. Dtcs have no delta load like
+// LoadChangedToken, so every reload is a full scan; schedule it accordingly.
 func (mgr *DtcManager) LoadAllDtc() {
+	span := startReload("t_dynamic_dtc")
+	defer span.End()
 	// Query the database to select only id and name fields
-	rows, err := mgr.db.Query("SELECT token_name, from_chain, to_chain, dtc_lv1, dtc_lv2, dtc_lv3, dtc_lv4, amount_lv1, amount_lv2, amount_lv3, amount_lv4 FROM t_dynamic_dtc")
+	rows, err := mgr.scanDB().Query("SELECT " + dtcColumns + " FROM t_dynamic_dtc")
 
 	if err != nil || rows == nil {
 		mgr.alerter.AlertText("select t_dynamic_dtc error", err)
@@ -84,8 +98,9 @@ func (mgr *DtcManager) LoadAllDtc() {
 
 	defer rows.Close()
 
-	tokenFromToDtcs := make(map[string]map[string]map[string]*Dtc)
+	allDtcs := make([]*Dtc, 0)
 	counter := 0
+	scanErrors := 0
 
 	// Iterate over the result set
 	for rows.Next() {
@@ -93,6 +108,7 @@ func (mgr *DtcManager) LoadAllDtc() {
 
 		if err := rows.Scan(&dtc.TokenName, &dtc.FromChainName, &dtc.ToChainName, &dtc.DtcLv1Str, &dtc.DtcLv2Str, &dtc.DtcLv3Str, &dtc.DtcLv4Str, &dtc.AmountLv1Str, &dtc.AmountLv2Str, &dtc.AmountLv3Str, &dtc.AmountLv4Str); err != nil {
 			mgr.alerter.AlertText("scan t_dynamic_dtc row error", err)
+			scanErrors++
 		} else {
 			dtc.FromChainName = strings.TrimSpace(dtc.FromChainName)
 			dtc.ToChainName = strings.TrimSpace(dtc.ToChainName)
@@ -149,18 +165,7 @@ func (mgr *DtcManager) LoadAllDtc() {
 			dtc.AmountLv3 = amount3
 			dtc.AmountLv4 = amount4
 
-			ftInfos, ok := tokenFromToDtcs[strings.ToLower(dtc.TokenName)]
-			if !ok {
-				ftInfos = make(map[string]map[string]*Dtc)
-				tokenFromToDtcs[strings.ToLower(dtc.TokenName)] = ftInfos
-			}
-			infos, ok := ftInfos[strings.ToLower(dtc.FromChainName)]
-			if !ok {
-				infos = make(map[string]*Dtc)
-				ftInfos[strings.ToLower(dtc.FromChainName)] = infos
-			}
-			infos[strings.ToLower(dtc.ToChainName)] = &dtc
-
+			allDtcs = append(allDtcs, &dtc)
 			counter++
 		}
 	}
@@ -171,11 +176,78 @@ func (mgr *DtcManager) LoadAllDtc() {
 		return
 	}
 
+	mgr.mutex.RLock()
+	oldDtcs := flattenDtcs(mgr.tokenFromToDtcs)
+	mgr.mutex.RUnlock()
+	if err := mgr.snapshotState.allowReplace(len(oldDtcs), counter, scanErrors); err != nil {
+		mgr.alerter.AlertText("t_dynamic_dtc load rejected, keep current dtcs", err)
+		return
+	}
+
+	mgr.setDtcs(allDtcs)
+	if err = saveSnapshot(mgr.snapshotState, allDtcs); err != nil {
+		mgr.alerter.AlertText("save t_dynamic_dtc snapshot error", err)
+	}
+
+	mgr.mutex.RLock()
+	newDtcs := flattenDtcs(mgr.tokenFromToDtcs)
+	mgr.mutex.RUnlock()
+	mgr.notify(diffChanges("t_dynamic_dtc", oldDtcs, newDtcs, func(a *Dtc, b *Dtc) bool {
+		return *a == *b
+	}))
+}
+
+// RestoreSnapshot fills an empty manager from the snapshot file, so a service can start
+// while the database is unavailable. StaleSince reports the snapshot time until the next
+// successful load.
+func (mgr *DtcManager) RestoreSnapshot() error {
+	mgr.mutex.RLock()
+	loaded := len(mgr.tokenFromToDtcs) > 0
+	mgr.mutex.RUnlock()
+	if loaded {
+		return ErrAlreadyLoaded
+	}
+	allDtcs, err := readSnapshot[*Dtc](mgr.snapshotState)
+	if err != nil {
+		return err
+	}
+	mgr.setDtcs(allDtcs)
+	return nil
+}
+
+func (mgr *DtcManager) setDtcs(allDtcs []*Dtc) {
+	tokenFromToDtcs := make(map[string]map[string]map[string]*Dtc)
+	for _, dtc := range allDtcs {
+		ftInfos, ok := tokenFromToDtcs[strings.ToLower(dtc.TokenName)]
+		if !ok {
+			ftInfos = make(map[string]map[string]*Dtc)
+			tokenFromToDtcs[strings.ToLower(dtc.TokenName)] = ftInfos
+		}
+		infos, ok := ftInfos[strings.ToLower(dtc.FromChainName)]
+		if !ok {
+			infos = make(map[string]*Dtc)
+			ftInfos[strings.ToLower(dtc.FromChainName)] = infos
+		}
+		infos[strings.ToLower(dtc.ToChainName)] = dtc
+	}
+
 	mgr.mutex.Lock()
 	mgr.tokenFromToDtcs = tokenFromToDtcs
 	mgr.mutex.Unlock()
 }
 
+func flattenDtcs(tokenFromToDtcs map[string]map[string]map[string]*Dtc) map[string]*Dtc {
+	dtcs := make(map[string]*Dtc)
+	for token, ftInfos := range tokenFromToDtcs {
+		for from, infos := range ftInfos {
+			for to, info := range infos {
+				dtcs[RouteKey(token, from, to)] = info
+			}
+		}
+	}
+	return dtcs
+}
+
 func (mgr *DtcManager) GetIncludedDtc(tokenName string, fromChainName string, toChainName string, value float64) (float64, string, bool) {
 	dtc, ok := mgr.GetDtc(tokenName, fromChainName, toChainName)
 	if !ok {
@@ -24,6 +24,9 @@ type ExchangeInfoManager struct {
 	db            *sql.DB
 	alerter       alert.Alerter
 	mutex         *sync.RWMutex
+
+	*snapshotState
+	*readRouter
 }
 
 func NewExchangeInfoManager(db *sql.DB, alerter alert.Alerter) *ExchangeInfoManager {
@@ -34,6 +37,9 @@ func NewExchangeInfoManager(db *sql.DB, alerter alert.Alerter) *ExchangeInfoMana
 		db:            db,
 		alerter:       alerter,
 		mutex:         &sync.RWMutex{},
+
+		snapshotState: newSnapshotState(),
+		readRouter:    newReadRouter(db),
 	}
 }
 
@@ -58,9 +64,13 @@ func (mgr *ExchangeInfoManager) GetExchangeInfoByName(name string) (*ExchangeInf
 	return xchg, ok
 }
 
+const exchangeInfoColumns = "id, name, icon, disabled, official_url, order_weight"
+
 func (mgr *ExchangeInfoManager) LoadAllExchanges() {
+	span := startReload("t_exchange_info")
+	defer span.End()
 	// Query the database to select only id and name fields
-	rows, err := mgr.db.Query("SELECT id, name, icon, disabled, official_url, order_weight FROM t_exchange_info")
+	rows, err := mgr.scanDB().Query("SELECT " + exchangeInfoColumns + " FROM t_exchange_info")
 
 	if err != nil || rows == nil {
 		mgr.alerter.AlertText("select t_exchange_info error", err)
@@ -69,20 +79,18 @@ func (mgr *ExchangeInfoManager) LoadAllExchanges() {
 
 	defer rows.Close()
 
-	idExchanges := make(map[int32]*ExchangeInfo)
-	nameExchanges := make(map[string]*ExchangeInfo)
 	allExchanges := make([]*ExchangeInfo, 0, 100)
 	counter := 0
+	scanErrors := 0
 
 	// Iterate over the result set
 	for rows.Next() {
 		var xchg ExchangeInfo
 		if err := rows.Scan(&xchg.Id, &xchg.Name, &xchg.Icon, &xchg.Disabled, &xchg.OfficialUrl, &xchg.OrderWeight); err != nil {
 			mgr.alerter.AlertText("scan t_exchange_info row error", err)
+			scanErrors++
 		} else {
 			xchg.Name = strings.TrimSpace(xchg.Name)
-			idExchanges[xchg.Id] = &xchg
-			nameExchanges[strings.ToLower(xchg.Name)] = &xchg
 			allExchanges = append(allExchanges, &xchg)
 			counter++
 		}
@@ -94,6 +102,40 @@ func (mgr *ExchangeInfoManager) LoadAllExchanges() {
 		return
 	}
 
+	if err := mgr.snapshotState.allowReplace(len(mgr.GetAllExchanges()), counter, scanErrors); err != nil {
+		mgr.alerter.AlertText("t_exchange_info load rejected, keep current exchanges", err)
+		return
+	}
+
+	mgr.setExchanges(allExchanges)
+	if err = saveSnapshot(mgr.snapshotState, allExchanges); err != nil {
+		mgr.alerter.AlertText("save t_exchange_info snapshot error", err)
+	}
+}
+
+// RestoreSnapshot fills an empty manager from the snapshot file, so a service can start
+// while the database is unavailable. StaleSince reports the snapshot time until the next
+// successful load.
+func (mgr *ExchangeInfoManager) RestoreSnapshot() error {
+	if len(mgr.GetAllExchanges()) > 0 {
+		return ErrAlreadyLoaded
+	}
+	allExchanges, err := readSnapshot[*ExchangeInfo](mgr.snapshotState)
+	if err != nil {
+		return err
+	}
+	mgr.setExchanges(allExchanges)
+	return nil
+}
+
+func (mgr *ExchangeInfoManager) setExchanges(allExchanges []*ExchangeInfo) {
+	idExchanges := make(map[int32]*ExchangeInfo)
+	nameExchanges := make(map[string]*ExchangeInfo)
+	for _, xchg := range allExchanges {
+		idExchanges[xchg.Id] = xchg
+		nameExchanges[strings.ToLower(xchg.Name)] = xchg
+	}
+
 	mgr.mutex.Lock()
 	mgr.idExchanges = idExchanges
 	mgr.nameExchanges = nameExchanges
@@ -0,0 +1,146 @@
+package loader
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+)
+
+// fakeDB is a database/sql connector that records every statement and answers queries
+// with canned rows, so the SQL paths can be tested without MySQL.
+type fakeDB struct {
+	mutex sync.Mutex
+	// log holds BEGIN, COMMIT, ROLLBACK and every statement, in order
+	log  []string
+	args [][]driver.Value
+	// rows answers the first query that contains the key
+	rows map[string][][]driver.Value
+	// errs fails the first statement that contains the key
+	errs map[string]error
+	// affected is the rows affected of an exec containing the key, 1 otherwise
+	affected map[string]int64
+}
+
+func newFakeDB() (*sql.DB, *fakeDB) {
+	fake := &fakeDB{
+		rows:     make(map[string][][]driver.Value),
+		errs:     make(map[string]error),
+		affected: make(map[string]int64),
+	}
+	return sql.OpenDB(fake), fake
+}
+
+func (f *fakeDB) record(entry string, args []driver.Value) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.log = append(f.log, entry)
+	f.args = append(f.args, args)
+	for key, err := range f.errs {
+		if strings.Contains(entry, key) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeDB) statements() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.log...)
+}
+
+// matching returns the recorded statements containing key with their args.
+func (f *fakeDB) matching(key string) ([]string, [][]driver.Value) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var statements []string
+	var args [][]driver.Value
+	for i, entry := range f.log {
+		if strings.Contains(entry, key) {
+			statements = append(statements, entry)
+			args = append(args, f.args[i])
+		}
+	}
+	return statements, args
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c, c.db.record("BEGIN", nil)
+}
+func (c *fakeConn) Commit() error {
+	return c.db.record("COMMIT", nil)
+}
+func (c *fakeConn) Rollback() error {
+	return c.db.record("ROLLBACK", nil)
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := s.db.record(s.query, args); err != nil {
+		return nil, err
+	}
+	s.db.mutex.Lock()
+	defer s.db.mutex.Unlock()
+	for key, affected := range s.db.affected {
+		if strings.Contains(s.query, key) {
+			return driver.RowsAffected(affected), nil
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if err := s.db.record(s.query, args); err != nil {
+		return nil, err
+	}
+	s.db.mutex.Lock()
+	defer s.db.mutex.Unlock()
+	for key, rows := range s.db.rows {
+		if strings.Contains(s.query, key) {
+			return &fakeRows{rows: rows}, nil
+		}
+	}
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return []string{""}
+	}
+	return make([]string, len(r.rows[0]))
+}
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,38 @@
+package loader
+
+import (
+	"sync"
+
+	"github.com/realcaishen/utils-go/invalidate"
+)
+
+// ReloadOn calls reload whenever bus delivers a message for table, whatever its key,
+// since loaders keep whole tables. reload runs on its own goroutine and messages that
+// arrive while it runs are coalesced into a single extra reload.
+func ReloadOn(bus invalidate.Bus, table string, reload func()) (unsubscribe func()) {
+	var mutex sync.Mutex
+	running, pending := false, false
+	var run func()
+	run = func() {
+		reload()
+		mutex.Lock()
+		if pending {
+			pending = false
+			mutex.Unlock()
+			run()
+			return
+		}
+		running = false
+		mutex.Unlock()
+	}
+	return bus.Subscribe(table, func(msg invalidate.Message) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if running {
+			pending = true
+			return
+		}
+		running = true
+		go run()
+	})
+}
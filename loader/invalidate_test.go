@@ -0,0 +1,38 @@
+package loader
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/realcaishen/utils-go/invalidate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloadOn(t *testing.T) {
+	bus := invalidate.NewMemoryBus()
+	release := make(chan struct{})
+	var reloads atomic.Int32
+	unsubscribe := ReloadOn(bus, "t_dtc", func() {
+		reloads.Add(1)
+		<-release
+	})
+
+	ctx := context.Background()
+	bus.Publish(ctx, invalidate.Key("t_dtc", "a"))
+	assert.Eventually(t, func() bool { return reloads.Load() == 1 }, time.Second, time.Millisecond)
+	// these arrive during the first reload and collapse into one more
+	bus.Publish(ctx, invalidate.Key("t_dtc", "b"))
+	bus.Publish(ctx, invalidate.All("t_dtc"))
+	bus.Publish(ctx, invalidate.All("t_token_info"))
+	close(release)
+	assert.Eventually(t, func() bool { return reloads.Load() == 2 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(2), reloads.Load())
+
+	unsubscribe()
+	bus.Publish(ctx, invalidate.All("t_dtc"))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(2), reloads.Load())
+}
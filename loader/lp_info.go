@@ -34,6 +34,10 @@ type LpInfoManager struct {
 	db         *sql.DB
 	alerter    alert.Alerter
 	mutex      *sync.RWMutex
+
+	*changeNotifier
+	*snapshotState
+	*readRouter
 }
 
 func NewLpInfoManager(db *sql.DB, alerter alert.Alerter) *LpInfoManager {
@@ -43,6 +47,10 @@ func NewLpInfoManager(db *sql.DB, alerter alert.Alerter) *LpInfoManager {
 		db:         db,
 		alerter:    alerter,
 		mutex:      &sync.RWMutex{},
+
+		changeNotifier: newChangeNotifier(),
+		snapshotState:  newSnapshotState(),
+		readRouter:     newReadRouter(db),
 	}
 }
 
@@ -129,9 +137,15 @@ func (mgr *LpInfoManager) GetTokensByLp(version int32, from string, to string) (
 	return getTokensByLp, true
 }
 
+const lpInfoColumns = "version, token_name, from_chain, to_chain, maker_address, min_value, max_value, is_disabled, bridge_fee_ratio"
+
+// LoadAllLpInfo reads the whole t_lp_info table. Lp infos have no delta load like
+// LoadChangedToken, so every reload is a full scan; schedule it accordingly.
 func (mgr *LpInfoManager) LoadAllLpInfo() {
+	span := startReload("t_lp_info")
+	defer span.End()
 	// Query the database to select only id and name fields
-	rows, err := mgr.db.Query("SELECT version, token_name, from_chain, to_chain, maker_address, min_value, max_value, is_disabled, bridge_fee_ratio FROM t_lp_info")
+	rows, err := mgr.scanDB().Query("SELECT " + lpInfoColumns + " FROM t_lp_info")
 
 	if err != nil || rows == nil {
 		mgr.alerter.AlertText("select t_lp_info error", err)
@@ -140,15 +154,16 @@ func (mgr *LpInfoManager) LoadAllLpInfo() {
 
 	defer rows.Close()
 
-	lpInfos := make(map[int32]map[string]map[string]map[string]map[string]*LpInfo)
 	allLpInfos := make([]*LpInfo, 0, 100)
 	counter := 0
+	scanErrors := 0
 
 	// Iterate over the result set
 	for rows.Next() {
 		var info LpInfo
 		if err := rows.Scan(&info.Version, &info.TokenName, &info.FromChainName, &info.ToChainName, &info.MakerAddress, &info.MinValueStr, &info.MaxValueStr, &info.IsDisabled, &info.BridgeFeeRatioStr); err != nil {
 			mgr.alerter.AlertText("scan t_lp_info row error", err)
+			scanErrors++
 		} else {
 
 			info.FromChainName = strings.TrimSpace(info.FromChainName)
@@ -176,28 +191,6 @@ func (mgr *LpInfoManager) LoadAllLpInfo() {
 			info.MaxValue = max
 			info.BridgeFeeRatio = bdgfee
 
-			versions, ok := lpInfos[info.Version]
-			if !ok {
-				versions = make(map[string]map[string]map[string]map[string]*LpInfo)
-				lpInfos[info.Version] = versions
-			}
-
-			ftInfos, ok := versions[strings.ToLower(info.TokenName)]
-			if !ok {
-				ftInfos = make(map[string]map[string]map[string]*LpInfo)
-				versions[strings.ToLower(info.TokenName)] = ftInfos
-			}
-			infos, ok := ftInfos[strings.ToLower(info.FromChainName)]
-			if !ok {
-				infos = make(map[string]map[string]*LpInfo)
-				ftInfos[strings.ToLower(info.FromChainName)] = infos
-			}
-			makers, ok := infos[strings.ToLower(info.ToChainName)]
-			if !ok {
-				makers = make(map[string]*LpInfo)
-				infos[strings.ToLower(info.ToChainName)] = makers
-			}
-			makers[strings.ToLower(info.MakerAddress)] = &info
 			allLpInfos = append(allLpInfos, &info)
 			counter++
 		}
@@ -209,8 +202,108 @@ func (mgr *LpInfoManager) LoadAllLpInfo() {
 		return
 	}
 
+	if err := mgr.snapshotState.allowReplace(len(mgr.GetAllLpInfos()), counter, scanErrors); err != nil {
+		mgr.alerter.AlertText("t_lp_info load rejected, keep current lp infos", err)
+		return
+	}
+
+	oldLpInfos := mgr.setLpInfos(allLpInfos)
+	if err = saveSnapshot(mgr.snapshotState, allLpInfos); err != nil {
+		mgr.alerter.AlertText("save t_lp_info snapshot error", err)
+	}
+
+	mgr.mutex.RLock()
+	newLpInfos := mgr.lpInfos
+	mgr.mutex.RUnlock()
+	mgr.notify(diffChanges("t_lp_info", flattenLpInfos(oldLpInfos), flattenLpInfos(newLpInfos), sameLpInfos))
+}
+
+// RestoreSnapshot fills an empty manager from the snapshot file, so a service can start
+// while the database is unavailable. StaleSince reports the snapshot time until the next
+// successful load.
+func (mgr *LpInfoManager) RestoreSnapshot() error {
+	if len(mgr.GetAllLpInfos()) > 0 {
+		return ErrAlreadyLoaded
+	}
+	allLpInfos, err := readSnapshot[*LpInfo](mgr.snapshotState)
+	if err != nil {
+		return err
+	}
+	mgr.setLpInfos(allLpInfos)
+	return nil
+}
+
+// setLpInfos rebuilds the lp info index from allLpInfos and swaps it in, returning the
+// previous index.
+func (mgr *LpInfoManager) setLpInfos(allLpInfos []*LpInfo) map[int32]map[string]map[string]map[string]map[string]*LpInfo {
+	lpInfos := make(map[int32]map[string]map[string]map[string]map[string]*LpInfo)
+	for _, info := range allLpInfos {
+		versions, ok := lpInfos[info.Version]
+		if !ok {
+			versions = make(map[string]map[string]map[string]map[string]*LpInfo)
+			lpInfos[info.Version] = versions
+		}
+
+		ftInfos, ok := versions[strings.ToLower(info.TokenName)]
+		if !ok {
+			ftInfos = make(map[string]map[string]map[string]*LpInfo)
+			versions[strings.ToLower(info.TokenName)] = ftInfos
+		}
+		infos, ok := ftInfos[strings.ToLower(info.FromChainName)]
+		if !ok {
+			infos = make(map[string]map[string]*LpInfo)
+			ftInfos[strings.ToLower(info.FromChainName)] = infos
+		}
+		makers, ok := infos[strings.ToLower(info.ToChainName)]
+		if !ok {
+			makers = make(map[string]*LpInfo)
+			infos[strings.ToLower(info.ToChainName)] = makers
+		}
+		makers[strings.ToLower(info.MakerAddress)] = info
+	}
+
 	mgr.mutex.Lock()
+	oldLpInfos := mgr.lpInfos
 	mgr.lpInfos = lpInfos
 	mgr.allLpInfos = allLpInfos
 	mgr.mutex.Unlock()
+	return oldLpInfos
+}
+
+// flattenLpInfos groups lp infos of all versions and makers by route, so a change of
+// any maker on a route is reported once under its RouteKey.
+func flattenLpInfos(lpInfos map[int32]map[string]map[string]map[string]map[string]*LpInfo) map[string][]LpInfo {
+	routes := make(map[string][]LpInfo)
+	for _, versionInfos := range lpInfos {
+		for token, ftInfos := range versionInfos {
+			for from, infos := range ftInfos {
+				for to, makers := range infos {
+					key := RouteKey(token, from, to)
+					for _, info := range makers {
+						routes[key] = append(routes[key], *info)
+					}
+				}
+			}
+		}
+	}
+	return routes
+}
+
+func sameLpInfos(a []LpInfo, b []LpInfo) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, x := range a {
+		found := false
+		for _, y := range b {
+			if x == y {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
 }
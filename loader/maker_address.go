@@ -2,7 +2,6 @@ package loader
 
 import (
 	"database/sql"
-	"github.com/realcaishen/utils-go/log"
 )
 
 type MakerAddressGroupPO struct {
@@ -32,6 +31,8 @@ type MakerAddressManager struct {
 	backendAddressToGroup map[Backend]map[string]int64
 
 	db *sql.DB
+
+	*readRouter
 }
 
 func NewMakerAddressManager(db *sql.DB) *MakerAddressManager {
@@ -40,14 +41,24 @@ func NewMakerAddressManager(db *sql.DB) *MakerAddressManager {
 		envGroup:              make(map[string][]*MakerAddress),
 		backendAddressToGroup: make(map[Backend]map[string]int64),
 		db:                    db,
+
+		readRouter: newReadRouter(db),
 	}
 }
 
+const (
+	makerGroupColumns = "id, group_name, env"
+	// makerAddressColumns are shared by t_maker_addresses and t_security_addresses
+	makerAddressColumns = "id, group_id, backend, address"
+)
+
 func (mgr *MakerAddressManager) LoadAllMakerAddresses() {
+	span := startReload("t_maker_address_groups")
+	defer span.End()
 	// Query the database for all maker address groups
-	groupRows, err := mgr.db.Query("SELECT id, group_name, env FROM t_maker_address_groups")
+	groupRows, err := mgr.scanDB().Query("SELECT " + makerGroupColumns + " FROM t_maker_address_groups")
 	if err != nil || groupRows == nil {
-		log.Errorf("select maker_address_groups error: %v", err)
+		logger.Errorf("select maker_address_groups error: %v", err)
 		return
 	}
 	defer groupRows.Close()
@@ -56,7 +67,7 @@ func (mgr *MakerAddressManager) LoadAllMakerAddresses() {
 	for groupRows.Next() {
 		var group MakerAddressGroupPO
 		if err = groupRows.Scan(&group.Id, &group.GroupName, &group.Env); err != nil {
-			log.Errorf("scan maker_address_groups row error: %v", err)
+			logger.Errorf("scan maker_address_groups row error: %v", err)
 			continue
 		}
 
@@ -71,14 +82,14 @@ func (mgr *MakerAddressManager) LoadAllMakerAddresses() {
 
 	// Check for errors from iterating over rows
 	if err = groupRows.Err(); err != nil {
-		log.Errorf("get next maker_address_groups row error: %v", err)
+		logger.Errorf("get next maker_address_groups row error: %v", err)
 		return
 	}
 
 	// Query the database for all maker addresses
-	addressRows, err := mgr.db.Query("SELECT id, group_id, backend, address FROM t_maker_addresses")
+	addressRows, err := mgr.scanDB().Query("SELECT " + makerAddressColumns + " FROM t_maker_addresses")
 	if err != nil || addressRows == nil {
-		log.Errorf("select maker_addresses error: %v", err)
+		logger.Errorf("select maker_addresses error: %v", err)
 		return
 	}
 	defer addressRows.Close()
@@ -88,7 +99,7 @@ func (mgr *MakerAddressManager) LoadAllMakerAddresses() {
 	for addressRows.Next() {
 		var address MakerAddressPO
 		if err = addressRows.Scan(&address.Id, &address.GroupId, &address.Backend, &address.Address); err != nil {
-			log.Errorf("scan maker_addresses row error: %v", err)
+			logger.Errorf("scan maker_addresses row error: %v", err)
 			continue
 		}
 
@@ -104,14 +115,14 @@ func (mgr *MakerAddressManager) LoadAllMakerAddresses() {
 	}
 
 	if err = addressRows.Err(); err != nil {
-		log.Errorf("get next maker_addresses row error: %v", err)
+		logger.Errorf("get next maker_addresses row error: %v", err)
 		return
 	}
 
 	// Query the database for all security addresses
-	securityAddressRows, err := mgr.db.Query("SELECT id, group_id, backend, address FROM t_security_addresses")
+	securityAddressRows, err := mgr.scanDB().Query("SELECT " + makerAddressColumns + " FROM t_security_addresses")
 	if err != nil || securityAddressRows == nil {
-		log.Errorf("select security_addresses error: %v", err)
+		logger.Errorf("select security_addresses error: %v", err)
 		return
 	}
 	defer securityAddressRows.Close()
@@ -119,7 +130,7 @@ func (mgr *MakerAddressManager) LoadAllMakerAddresses() {
 	for securityAddressRows.Next() {
 		var securityAddress MakerAddressPO
 		if err = securityAddressRows.Scan(&securityAddress.Id, &securityAddress.GroupId, &securityAddress.Backend, &securityAddress.Address); err != nil {
-			log.Errorf("scan security_addresses row error: %v", err)
+			logger.Errorf("scan security_addresses row error: %v", err)
 			continue
 		}
 
@@ -129,7 +140,7 @@ func (mgr *MakerAddressManager) LoadAllMakerAddresses() {
 	}
 
 	if err = securityAddressRows.Err(); err != nil {
-		log.Errorf("get next security_addresses row error: %v", err)
+		logger.Errorf("get next security_addresses row error: %v", err)
 		return
 	}
 
@@ -0,0 +1,101 @@
+package loader
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/realcaishen/utils-go/telemetry"
+	"github.com/realcaishen/utils-go/util"
+)
+
+// BalanceReader reads on-chain balances, e.g. the rpc.Rpc of a chain.
+type BalanceReader interface {
+	GetBalance(ctx context.Context, ownerAddr string, tokenAddr string) (*big.Int, error)
+}
+
+// BalanceReaderFunc returns the BalanceReader of a chain, e.g. by calling rpc.GetRpc.
+type BalanceReaderFunc func(chain *ChainInfo) (BalanceReader, error)
+
+type makerToken struct {
+	chainName string
+	maker     string
+	tokenName string
+}
+
+// MakerBalanceReporter reports the balance of every maker on the chains it pays out on,
+// as listed by the enabled rows of t_lp_info, as telemetry.MetricMakerBalance.
+type MakerBalanceReporter struct {
+	chainManager *ChainInfoManager
+	tokenManager *TokenInfoManager
+	lpManager    *LpInfoManager
+	readers      BalanceReaderFunc
+}
+
+func NewMakerBalanceReporter(chainManager *ChainInfoManager, tokenManager *TokenInfoManager, lpManager *LpInfoManager, readers BalanceReaderFunc) *MakerBalanceReporter {
+	return &MakerBalanceReporter{
+		chainManager: chainManager,
+		tokenManager: tokenManager,
+		lpManager:    lpManager,
+		readers:      readers,
+	}
+}
+
+// makerTokens lists every (dst chain, maker, token) of the enabled lp infos once.
+func (r *MakerBalanceReporter) makerTokens() []makerToken {
+	seen := make(map[makerToken]struct{})
+	list := make([]makerToken, 0)
+	for _, info := range r.lpManager.GetAllLpInfos() {
+		if info.IsDisabled != 0 || info.MakerAddress == "" {
+			continue
+		}
+		mt := makerToken{
+			chainName: strings.ToLower(info.ToChainName),
+			maker:     info.MakerAddress,
+			tokenName: strings.ToLower(info.TokenName),
+		}
+		if _, ok := seen[mt]; ok {
+			continue
+		}
+		seen[mt] = struct{}{}
+		list = append(list, mt)
+	}
+	return list
+}
+
+// Report reads every maker balance once and records it. It is meant to be driven by
+// task.PeriodicTask; balances that cannot be read are logged and skipped.
+func (r *MakerBalanceReporter) Report(ctx context.Context) {
+	readers := make(map[string]BalanceReader)
+	for _, mt := range r.makerTokens() {
+		chain, ok := r.chainManager.GetChainInfoByName(mt.chainName)
+		if !ok || chain.Disabled != 0 {
+			continue
+		}
+		token, ok := r.tokenManager.GetByChainNameTokenName(mt.chainName, mt.tokenName)
+		if !ok {
+			continue
+		}
+		reader, ok := readers[mt.chainName]
+		if !ok {
+			var err error
+			if reader, err = r.readers(chain); err != nil {
+				// skip the chain for the rest of this pass
+				logger.CtxWarnf(ctx, "get balance reader of %v: %v", chain.Name, err)
+				reader = nil
+			}
+			readers[mt.chainName] = reader
+		}
+		if reader == nil {
+			continue
+		}
+
+		balance, err := reader.GetBalance(ctx, mt.maker, token.TokenAddress)
+		if err != nil {
+			logger.CtxWarnf(ctx, "get %v balance of maker %v on %v: %v", token.TokenName, mt.maker, chain.Name, err)
+			continue
+		}
+		amount, _ := util.BigIntToUi(balance, token.Decimals).Float64()
+		telemetry.MakerBalance(chain.ChainId, mt.maker, token.TokenName, amount)
+	}
+}
@@ -0,0 +1,72 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBalances map[string]*big.Int
+
+func (f fakeBalances) GetBalance(ctx context.Context, ownerAddr string, tokenAddr string) (*big.Int, error) {
+	balance, ok := f[ownerAddr+"/"+tokenAddr]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return balance, nil
+}
+
+func TestMakerBalanceReporter(t *testing.T) {
+	sink := metrics.NewInmemSink(time.Minute, time.Minute)
+	cfg := metrics.DefaultConfig("")
+	cfg.EnableHostname = false
+	cfg.EnableRuntimeMetrics = false
+	_, err := metrics.NewGlobal(cfg, sink)
+	require.NoError(t, err)
+	defer metrics.NewGlobal(cfg, &metrics.BlackholeSink{})
+
+	chainManager := NewChainInfoManager(nil, nil)
+	chainManager.setChains([]*ChainInfo{
+		{Id: 1, ChainId: "42161", Name: "Arbitrum"},
+		{Id: 2, ChainId: "10", Name: "Optimism"},
+		{Id: 3, ChainId: "56", Name: "Bsc", Disabled: 1},
+	})
+	tokenManager := NewTokenInfoManager(nil, nil)
+	tokenManager.AddToken("Arbitrum", "USDC", "0xusdc", 6)
+	tokenManager.AddToken("Optimism", "USDC", "0xusdcop", 6)
+	tokenManager.AddToken("Bsc", "USDC", "0xusdcbsc", 18)
+	lpManager := NewLpInfoManager(nil, nil)
+	lpManager.setLpInfos([]*LpInfo{
+		{Version: LpInfoVersion, TokenName: "USDC", FromChainName: "Ethereum", ToChainName: "Arbitrum", MakerAddress: "0xmaker"},
+		{Version: LpInfoVersion, TokenName: "USDC", FromChainName: "Base", ToChainName: "Arbitrum", MakerAddress: "0xmaker"},
+		{Version: LpInfoVersion, TokenName: "USDC", FromChainName: "Ethereum", ToChainName: "Optimism", MakerAddress: "0xmaker"},
+		{Version: LpInfoVersion, TokenName: "USDC", FromChainName: "Ethereum", ToChainName: "Bsc", MakerAddress: "0xmaker"},
+		{Version: LpInfoVersion, TokenName: "USDC", FromChainName: "Base", ToChainName: "Optimism", MakerAddress: "0xoff", IsDisabled: 1},
+	})
+
+	calls := 0
+	reporter := NewMakerBalanceReporter(chainManager, tokenManager, lpManager, func(chain *ChainInfo) (BalanceReader, error) {
+		calls++
+		if chain.Name == "Optimism" {
+			return nil, errors.New("dial failed")
+		}
+		return fakeBalances{"0xmaker/0xusdc": big.NewInt(2_500_000)}, nil
+	})
+	assert.Len(t, reporter.makerTokens(), 3)
+	reporter.Report(context.Background())
+	assert.Equal(t, 2, calls)
+
+	data := sink.Data()
+	require.NotEmpty(t, data)
+	interval := data[len(data)-1]
+	interval.RLock()
+	defer interval.RUnlock()
+	assert.Equal(t, float32(2.5), interval.Gauges["bridge.maker.balance;chain=42161;maker=0xmaker;token=USDC"].Value)
+	assert.Len(t, interval.Gauges, 1)
+}
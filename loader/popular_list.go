@@ -19,6 +19,8 @@ type PopularListManager struct {
 	db      *sql.DB
 	alerter alert.Alerter
 	mutex   *sync.RWMutex
+
+	*readRouter
 }
 
 func NewPopularListManager(db *sql.DB, alerter alert.Alerter) *PopularListManager {
@@ -28,6 +30,8 @@ func NewPopularListManager(db *sql.DB, alerter alert.Alerter) *PopularListManage
 		db:      db,
 		alerter: alerter,
 		mutex:   &sync.RWMutex{},
+
+		readRouter: newReadRouter(db),
 	}
 }
 
@@ -44,9 +48,13 @@ func (mgr *PopularListManager) GetPopularWeight(weights map[string]int32, chain
 	return false
 }
 
+const popularListColumns = "chain_name, popular_weight, tag"
+
 func (mgr *PopularListManager) LoadAllPopularList() {
+	span := startReload("t_popular_list")
+	defer span.End()
 	// Query the database to select only id and name fields
-	rows, err := mgr.db.Query("SELECT chain_name, popular_weight, tag FROM t_popular_list")
+	rows, err := mgr.scanDB().Query("SELECT " + popularListColumns + " FROM t_popular_list")
 
 	if err != nil || rows == nil {
 		mgr.alerter.AlertText("select t_popular_list error", err)
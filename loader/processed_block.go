@@ -0,0 +1,130 @@
+package loader
+
+import (
+	"database/sql"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/realcaishen/utils-go/alert"
+	"github.com/realcaishen/utils-go/telemetry"
+)
+
+// ProcessedBlock is the progress of one event scanner (appid) on one chain, as kept in
+// t_event_processed_block.
+type ProcessedBlock struct {
+	ChainId              int32
+	AppId                int32
+	BlockNumber          int64
+	LatestBlockNumber    int64
+	BacktrackBlockNumber int64
+	UpdateTimestamp      int64
+}
+
+// Lag is how many blocks the scanner is behind the chain head it last saw, 0 when the
+// head is unknown. Both numbers come from the scanner, so a stalled scanner keeps its
+// last lag; Staleness tells.
+func (b *ProcessedBlock) Lag() int64 {
+	if b.LatestBlockNumber <= b.BlockNumber {
+		return 0
+	}
+	return b.LatestBlockNumber - b.BlockNumber
+}
+
+// Staleness is how long ago the scanner last recorded progress, as of now.
+func (b *ProcessedBlock) Staleness(now time.Time) time.Duration {
+	if b.UpdateTimestamp <= 0 || b.UpdateTimestamp > now.Unix() {
+		return 0
+	}
+	return now.Sub(time.Unix(b.UpdateTimestamp, 0))
+}
+
+// ProcessedBlockManager loads scanner progress and reports the lag and staleness of every
+// scanner as telemetry.MetricScannerLag and telemetry.MetricScannerStaleness on each load.
+type ProcessedBlockManager struct {
+	blocks  map[int32]map[int32]*ProcessedBlock
+	db      *sql.DB
+	alerter alert.Alerter
+	mutex   *sync.RWMutex
+}
+
+func NewProcessedBlockManager(db *sql.DB, alerter alert.Alerter) *ProcessedBlockManager {
+	return &ProcessedBlockManager{
+		blocks:  make(map[int32]map[int32]*ProcessedBlock),
+		db:      db,
+		alerter: alerter,
+		mutex:   &sync.RWMutex{},
+	}
+}
+
+func (mgr *ProcessedBlockManager) GetProcessedBlock(chainId int32, appId int32) (*ProcessedBlock, bool) {
+	mgr.mutex.RLock()
+	block, ok := mgr.blocks[chainId][appId]
+	mgr.mutex.RUnlock()
+	return block, ok
+}
+
+func (mgr *ProcessedBlockManager) GetAllProcessedBlocks() []*ProcessedBlock {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	blocks := make([]*ProcessedBlock, 0)
+	for _, appBlocks := range mgr.blocks {
+		for _, block := range appBlocks {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+const processedBlockColumns = "chainid, appid, block_number, IFNULL(latest_block_number, 0), backtrack_block_number, UNIX_TIMESTAMP(update_timestamp)"
+
+// LoadAllProcessedBlocks reads the primary rather than a replica, whose own lag would
+// show up as scanner lag.
+func (mgr *ProcessedBlockManager) LoadAllProcessedBlocks() {
+	span := startReload("t_event_processed_block")
+	defer span.End()
+	rows, err := mgr.db.Query("SELECT " + processedBlockColumns + " FROM t_event_processed_block")
+	if err != nil || rows == nil {
+		mgr.alerter.AlertText("select t_event_processed_block error", err)
+		return
+	}
+	defer rows.Close()
+
+	allBlocks := make([]*ProcessedBlock, 0)
+	for rows.Next() {
+		var block ProcessedBlock
+		if err := rows.Scan(&block.ChainId, &block.AppId, &block.BlockNumber, &block.LatestBlockNumber, &block.BacktrackBlockNumber, &block.UpdateTimestamp); err != nil {
+			mgr.alerter.AlertText("scan t_event_processed_block row error", err)
+		} else {
+			allBlocks = append(allBlocks, &block)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		mgr.alerter.AlertText("get next t_event_processed_block row error", err)
+		return
+	}
+
+	mgr.setProcessedBlocks(allBlocks)
+}
+
+func (mgr *ProcessedBlockManager) setProcessedBlocks(allBlocks []*ProcessedBlock) {
+	now := time.Now()
+	blocks := make(map[int32]map[int32]*ProcessedBlock)
+	for _, block := range allBlocks {
+		if _, ok := blocks[block.ChainId]; !ok {
+			blocks[block.ChainId] = make(map[int32]*ProcessedBlock)
+		}
+		blocks[block.ChainId][block.AppId] = block
+		chain := strconv.FormatInt(int64(block.ChainId), 10)
+		if block.LatestBlockNumber > 0 {
+			telemetry.ScannerLag(chain, block.AppId, block.Lag())
+		}
+		if block.UpdateTimestamp > 0 {
+			telemetry.ScannerStaleness(chain, block.AppId, block.Staleness(now))
+		}
+	}
+
+	mgr.mutex.Lock()
+	mgr.blocks = blocks
+	mgr.mutex.Unlock()
+}
@@ -0,0 +1,42 @@
+package loader
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/realcaishen/utils-go/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessedBlocks(t *testing.T) {
+	assert.Equal(t, int64(0), (&ProcessedBlock{BlockNumber: 100}).Lag())
+	assert.Equal(t, int64(0), (&ProcessedBlock{BlockNumber: 100, LatestBlockNumber: 90}).Lag())
+	assert.Equal(t, int64(5), (&ProcessedBlock{BlockNumber: 100, LatestBlockNumber: 105}).Lag())
+
+	now := time.Unix(1_700_000_060, 0)
+	assert.Equal(t, time.Minute, (&ProcessedBlock{UpdateTimestamp: 1_700_000_000}).Staleness(now))
+	assert.Equal(t, time.Duration(0), (&ProcessedBlock{}).Staleness(now))
+	assert.Equal(t, time.Duration(0), (&ProcessedBlock{UpdateTimestamp: now.Unix() + 5}).Staleness(now))
+
+	mgr := NewProcessedBlockManager(nil, nil)
+	mgr.setProcessedBlocks([]*ProcessedBlock{
+		{ChainId: 1, AppId: 1, BlockNumber: 100, LatestBlockNumber: 105},
+		{ChainId: 1, AppId: 2, BlockNumber: 90},
+		{ChainId: 10, AppId: 1, BlockNumber: 7},
+	})
+	block, ok := mgr.GetProcessedBlock(1, 2)
+	assert.True(t, ok)
+	assert.Equal(t, int64(90), block.BlockNumber)
+	_, ok = mgr.GetProcessedBlock(10, 2)
+	assert.False(t, ok)
+	assert.Len(t, mgr.GetAllProcessedBlocks(), 3)
+}
+
+func TestSrcTxRoute(t *testing.T) {
+	tx := &SrcTx{ChainId: 1, Token: "0xa0b8", DstChainid: sql.NullInt32{Int32: 42161, Valid: true}}
+	assert.Equal(t, telemetry.Route{SrcChain: "1", DstChain: "42161", Token: "0xa0b8"}, tx.route())
+	tx.SrcTokenName = sql.NullString{String: "USDC", Valid: true}
+	tx.DstChainid = sql.NullInt32{}
+	assert.Equal(t, telemetry.Route{SrcChain: "1", Token: "USDC"}, tx.route())
+}
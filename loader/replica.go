@@ -0,0 +1,47 @@
+package loader
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// ReplicaSet picks the database heavy reads go to: a replica that is within the lag
+// threshold, or the primary when none is. *dal.Cluster implements it.
+type ReplicaSet interface {
+	Reader() *sql.DB
+}
+
+// readRouter sends full table scans to a ReplicaSet when one is set. Writes and
+// read-after-write lookups keep using the manager's own db, which is the primary.
+type readRouter struct {
+	primary  *sql.DB
+	replicas ReplicaSet
+	mutex    *sync.RWMutex
+}
+
+func newReadRouter(primary *sql.DB) *readRouter {
+	return &readRouter{
+		primary: primary,
+		mutex:   &sync.RWMutex{},
+	}
+}
+
+// SetReplicaSet routes the manager's loads to replicas. Nil routes them back to the primary.
+func (r *readRouter) SetReplicaSet(replicas ReplicaSet) {
+	r.mutex.Lock()
+	r.replicas = replicas
+	r.mutex.Unlock()
+}
+
+func (r *readRouter) scanDB() *sql.DB {
+	r.mutex.RLock()
+	replicas := r.replicas
+	r.mutex.RUnlock()
+	if replicas == nil {
+		return r.primary
+	}
+	if db := replicas.Reader(); db != nil {
+		return db
+	}
+	return r.primary
+}
@@ -0,0 +1,105 @@
+package loader
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/realcaishen/utils-go/log"
+)
+
+var logger = log.Module("loader")
+
+var (
+	ErrDuplicate = errors.New("row already exists")
+	ErrNotFound  = errors.New("row not found")
+	ErrConflict  = errors.New("row was changed concurrently")
+)
+
+const mysqlErrDupEntry = 1062
+
+// DBTX is the part of *sql.DB and *sql.Tx the repository methods need, so the same
+// call can run standalone or join a caller's transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// WithTx runs fn in a transaction, committing if it returns nil and rolling back otherwise.
+// The hooks registered with AfterCommit on the transaction run once it committed.
+func WithTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	hooks := &commitHooks{}
+	txHooks.Store(tx, hooks)
+	defer txHooks.Delete(tx)
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	hooks.run()
+	return nil
+}
+
+type commitHooks struct {
+	mutex sync.Mutex
+	fns   []func()
+}
+
+func (h *commitHooks) add(fn func()) {
+	h.mutex.Lock()
+	h.fns = append(h.fns, fn)
+	h.mutex.Unlock()
+}
+
+func (h *commitHooks) run() {
+	h.mutex.Lock()
+	fns := h.fns
+	h.mutex.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// txHooks holds the commit hooks of the transactions WithTx is running.
+var txHooks sync.Map
+
+// AfterCommit runs fn once the writes made through q are committed: right away when q
+// is not a transaction, after the commit of a transaction started by WithTx, and never
+// if that transaction rolls back. It reports false, without running fn, for a
+// transaction WithTx did not start, whose commit it cannot see.
+func AfterCommit(q DBTX, fn func()) bool {
+	tx, ok := q.(*sql.Tx)
+	if !ok {
+		fn()
+		return true
+	}
+	hooks, ok := txHooks.Load(tx)
+	if !ok {
+		return false
+	}
+	hooks.(*commitHooks).add(fn)
+	return true
+}
+
+// repoError maps driver errors onto the typed repository errors.
+func repoError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDupEntry {
+		return fmt.Errorf("%w: %v", ErrDuplicate, err)
+	}
+	return err
+}
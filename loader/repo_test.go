@@ -0,0 +1,153 @@
+package loader
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepoError(t *testing.T) {
+	assert.Nil(t, repoError(nil))
+	assert.ErrorIs(t, repoError(sql.ErrNoRows), ErrNotFound)
+	assert.ErrorIs(t, repoError(&mysql.MySQLError{Number: mysqlErrDupEntry, Message: "Duplicate entry"}), ErrDuplicate)
+
+	other := errors.New("boom")
+	assert.Equal(t, other, repoError(other))
+	assert.ErrorIs(t, ErrTransitionConflict, ErrConflict)
+}
+
+func TestInsertDuplicate(t *testing.T) {
+	db, fake := newFakeDB()
+	defer db.Close()
+	ctx := context.Background()
+	fake.errs["INSERT INTO t_src_transaction"] = &mysql.MySQLError{Number: mysqlErrDupEntry, Message: "Duplicate entry"}
+	fake.errs["INSERT INTO t_dst_transaction"] = &mysql.MySQLError{Number: mysqlErrDupEntry, Message: "Duplicate entry"}
+
+	_, err := NewSrcTxManager(db, nil).Insert(ctx, db, &SrcTx{ChainId: 1, TxHash: "0xabc"})
+	assert.ErrorIs(t, err, ErrDuplicate)
+	err = NewDstTxManager(db, nil).Insert(ctx, db, &DstTx{SrcAction: "transfer", SrcId: 7})
+	assert.ErrorIs(t, err, ErrDuplicate)
+}
+
+func TestSetConfirmedGen(t *testing.T) {
+	db, fake := newFakeDB()
+	defer db.Close()
+	ctx := context.Background()
+	mgr := NewDstTxManager(db, nil)
+
+	affected, err := mgr.SetConfirmedGen(ctx, db, 7, 5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	// another gen was confirmed first
+	fake.affected["UPDATE t_dst_transaction"] = 0
+	fake.rows["SELECT confirmed_gen"] = [][]driver.Value{{int64(4)}}
+	_, err = mgr.SetConfirmedGen(ctx, db, 7, 5)
+	assert.ErrorIs(t, err, ErrConflict)
+
+	// the same gen again is a no-op
+	fake.rows["SELECT confirmed_gen"] = [][]driver.Value{{int64(5)}}
+	affected, err = mgr.SetConfirmedGen(ctx, db, 7, 5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), affected)
+
+	delete(fake.rows, "SELECT confirmed_gen")
+	_, err = mgr.SetConfirmedGen(ctx, db, 7, 5)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestUpdateResultNotFound(t *testing.T) {
+	db, fake := newFakeDB()
+	defer db.Close()
+	ctx := context.Background()
+	mgr := NewSrcTxManager(db, nil)
+	fake.affected["UPDATE t_src_transaction"] = 0
+
+	_, err := mgr.UpdateResult(ctx, db, 1, "0xmissing", 0, 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	fake.rows["SELECT id FROM t_src_transaction"] = [][]driver.Value{{int64(7)}}
+	affected, err := mgr.UpdateResult(ctx, db, 1, "0xabc", 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), affected)
+}
+
+func TestTransitionTxRollsBackWithCaller(t *testing.T) {
+	ctx := context.Background()
+	verified := &OrderTransition{SrcId: 7, From: OrderProcessed, To: OrderVerified, Operator: "test"}
+	callerWrite := func(mgr *SrcTxManager, db *sql.DB) error {
+		return WithTx(ctx, db, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, "UPDATE t_src_transaction SET dst_value = ? WHERE id = ?", "1000", 7); err != nil {
+				return err
+			}
+			return mgr.TransitionTx(ctx, tx, verified)
+		})
+	}
+
+	// the order moved on meanwhile: the caller's write is rolled back with it
+	db, fake := newFakeDB()
+	fake.affected["is_verified = 1"] = 0
+	err := callerWrite(NewSrcTxManager(db, nil), db)
+	assert.ErrorIs(t, err, ErrTransitionConflict)
+	assert.Equal(t, []string{"BEGIN", "UPDATE t_src_transaction SET dst_value = ? WHERE id = ?"}, fake.statements()[:2])
+	assert.Equal(t, "ROLLBACK", fake.statements()[3])
+	assert.NotContains(t, fake.statements(), "COMMIT")
+	assert.Len(t, fake.statements(), 4)
+	db.Close()
+
+	// a failing audit insert rolls everything back too
+	db, fake = newFakeDB()
+	fake.errs["INSERT INTO t_src_transaction_transition"] = errors.New("boom")
+	err = callerWrite(NewSrcTxManager(db, nil), db)
+	assert.Error(t, err)
+	assert.Equal(t, "ROLLBACK", fake.statements()[len(fake.statements())-1])
+	assert.NotContains(t, fake.statements(), "COMMIT")
+	db.Close()
+
+	db, fake = newFakeDB()
+	require.NoError(t, callerWrite(NewSrcTxManager(db, nil), db))
+	statements := fake.statements()
+	assert.Equal(t, "COMMIT", statements[4])
+	assert.Contains(t, statements[3], "INSERT INTO t_src_transaction_transition")
+	// the settled metric reads the order back once committed
+	require.Len(t, statements, 6)
+	assert.Contains(t, statements[5], "SELECT "+orderMetricColumns)
+	db.Close()
+}
+
+func TestAfterCommit(t *testing.T) {
+	ctx := context.Background()
+	db, _ := newFakeDB()
+	defer db.Close()
+
+	ran := 0
+	assert.True(t, AfterCommit(db, func() { ran++ }))
+	assert.Equal(t, 1, ran)
+
+	err := WithTx(ctx, db, func(tx *sql.Tx) error {
+		assert.True(t, AfterCommit(tx, func() { ran++ }))
+		assert.Equal(t, 1, ran)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, ran)
+
+	err = WithTx(ctx, db, func(tx *sql.Tx) error {
+		AfterCommit(tx, func() { ran++ })
+		return errors.New("boom")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 2, ran)
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	assert.False(t, AfterCommit(tx, func() { ran++ }))
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, 2, ran)
+}
@@ -0,0 +1,157 @@
+package loader
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/realcaishen/utils-go/invalidate"
+	"github.com/realcaishen/utils-go/kvstore"
+)
+
+// LoaderColumns lists, per table, the columns the managers in this package and the t_kv
+// and t_invalidation stores use. It is parsed from the column constants the queries are
+// built from, so it cannot drift from them; columns only named in WHERE or SET clauses
+// are listed by name. CheckSchema and the migration tests rely on it.
+var LoaderColumns = buildColumns(map[string][]string{
+	"t_account":                  {accountColumns},
+	"t_bridge_fee_decimal":       {bridgeFeeDecimalColumns},
+	"t_cctp_support_chain":       {cctpChainColumns},
+	"t_channel_commission_ratio": {commissionRatioColumns},
+	"t_chain_info":               {chainInfoColumns},
+	"t_dst_transaction":          {dstTxColumns, dstTxInsertColumns, "confirmed_gen"},
+	"t_dst_transaction_gen":      {txGenColumns},
+	"t_dynamic_bridge_fee":       {bridgeFeeColumns},
+	"t_dynamic_dtc":              {dtcColumns},
+	"t_event_processed_block":    {processedBlockColumns},
+	"t_exchange_info":            {exchangeInfoColumns},
+	"t_invalidation":             {invalidate.Columns, "insert_timestamp"},
+	"t_kv":                       {kvstore.Columns},
+	"t_lp_info":                  {lpInfoColumns},
+	"t_maker_address_groups":     {makerGroupColumns},
+	"t_maker_addresses":          {makerAddressColumns},
+	"t_object_tag":               {objectTagColumns},
+	"t_popular_list":             {popularListColumns},
+	"t_security_addresses":       {makerAddressColumns},
+	"t_src_transaction": append([]string{srcOrderColumns, srcTxInsertColumns, orderMetricColumns, "update_timestamp"},
+		stateClauses()...),
+	"t_src_transaction_transition": {transitionLogColumns, transitionInsertColumns},
+	"t_swap_token_info":            {swapTokenColumns},
+	"t_tag":                        {tagColumns},
+	"t_token_info":                 {tokenInfoColumns},
+	"t_transfer":                   {transferColumns, "reason", "next_retry_time"},
+	"t_update_price":               {updatePriceColumns},
+})
+
+// stateClauses returns the WHERE and SET clauses of the order states.
+func stateClauses() []string {
+	var clauses []string
+	for _, where := range orderStateWhere {
+		clauses = append(clauses, where)
+	}
+	for _, where := range orderTransitionWhere {
+		clauses = append(clauses, where)
+	}
+	for _, set := range orderStateSet {
+		clauses = append(clauses, set)
+	}
+	return clauses
+}
+
+func buildColumns(lists map[string][]string) map[string][]string {
+	columns := make(map[string][]string, len(lists))
+	for table, list := range lists {
+		seen := make(map[string]struct{})
+		for _, name := range columnNames(strings.Join(list, ", ")) {
+			if _, ok := seen[name]; !ok {
+				seen[name] = struct{}{}
+				columns[table] = append(columns[table], name)
+			}
+		}
+		sort.Strings(columns[table])
+	}
+	return columns
+}
+
+var (
+	sqlLiteralPattern    = regexp.MustCompile(`'[^']*'`)
+	sqlIdentifierPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*(\s*\()?`)
+)
+
+// sqlKeywords are the words in the column constants and state clauses that are not
+// column names.
+var sqlKeywords = map[string]struct{}{
+	"AND": {}, "OR": {}, "NOT": {}, "IS": {}, "NULL": {}, "AS": {}, "IN": {}, "VALUES": {},
+	"SIGNED": {}, "INTERVAL": {}, "SECOND": {},
+}
+
+// columnNames returns the column names in a select list, an insert column list with
+// its VALUES, or a WHERE or SET clause: function names, keywords, literals and
+// placeholders are skipped.
+func columnNames(list string) []string {
+	list = strings.ReplaceAll(sqlLiteralPattern.ReplaceAllString(list, ""), "`", "")
+	var names []string
+	for _, match := range sqlIdentifierPattern.FindAllString(list, -1) {
+		if strings.HasSuffix(match, "(") {
+			continue
+		}
+		if _, ok := sqlKeywords[strings.ToUpper(match)]; ok {
+			continue
+		}
+		names = append(names, match)
+	}
+	return names
+}
+
+// CheckSchema compares the live schema of the current database with LoaderColumns and
+// returns an error naming every missing table or column. Services call it at startup so
+// a stale schema fails fast instead of on the first load. Only the given tables are
+// checked, or all of LoaderColumns if none are given.
+func CheckSchema(ctx context.Context, db *sql.DB, tables ...string) error {
+	if len(tables) == 0 {
+		for table := range LoaderColumns {
+			tables = append(tables, table)
+		}
+	}
+	sort.Strings(tables)
+
+	rows, err := db.QueryContext(ctx, "SELECT table_name, column_name FROM information_schema.columns WHERE table_schema = DATABASE()")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	live := make(map[string]map[string]struct{})
+	for rows.Next() {
+		var table, column string
+		if err = rows.Scan(&table, &column); err != nil {
+			return err
+		}
+		if live[table] == nil {
+			live[table] = make(map[string]struct{})
+		}
+		live[table][column] = struct{}{}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, table := range tables {
+		columns, ok := live[table]
+		if !ok {
+			errs = append(errs, fmt.Errorf("table %v missing", table))
+			continue
+		}
+		for _, column := range LoaderColumns[table] {
+			if _, ok = columns[column]; !ok {
+				errs = append(errs, fmt.Errorf("column %v.%v missing", table, column))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,32 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumnNames(t *testing.T) {
+	cases := []struct {
+		list   string
+		expect []string
+	}{
+		{"id, chain_id, address", []string{"id", "chain_id", "address"}},
+		{"chainid, IFNULL(latest_block_number, 0), UNIX_TIMESTAMP(update_timestamp)", []string{"chainid", "latest_block_number", "update_timestamp"}},
+		{"IFNULL(NULLIF(src_token_name, ''), token)", []string{"src_token_name", "token"}},
+		{"(src_id, reason) VALUES (?, ?)", []string{"src_id", "reason"}},
+		{"`key`, value, version", []string{"key", "value", "version"}},
+		{"is_invalid = 0 AND IFNULL(is_manual, 0) = 0 AND dst_tx_hash IS NULL", []string{"is_invalid", "is_manual", "dst_tx_hash"}},
+		{"is_locked = 0, process_timestamp = UNIX_TIMESTAMP(), next_time = ?", []string{"is_locked", "process_timestamp", "next_time"}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expect, columnNames(c.list), c.list)
+	}
+}
+
+func TestLoaderColumnsCoverStateClauses(t *testing.T) {
+	assert.Contains(t, LoaderColumns["t_src_transaction"], "verified_timestamp")
+	assert.Contains(t, LoaderColumns["t_kv"], "version")
+	assert.Contains(t, LoaderColumns["t_event_processed_block"], "backtrack_block_number")
+	assert.NotContains(t, LoaderColumns["t_src_transaction"], "UNIX_TIMESTAMP")
+}
@@ -0,0 +1,167 @@
+package loader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/realcaishen/utils-go/system"
+)
+
+var (
+	ErrSnapshotDisabled = errors.New("loader snapshot path not set")
+	ErrAlreadyLoaded    = errors.New("loader already has data, snapshot not restored")
+)
+
+// snapshotFile is the on-disk format of a loader snapshot.
+type snapshotFile[T any] struct {
+	SavedAt time.Time `json:"saved_at"`
+	Items   []T       `json:"items"`
+}
+
+// DefaultMaxShrink is the largest fraction of the in-memory items a full load may drop
+// before it is rejected.
+const DefaultMaxShrink = 0.5
+
+const snapshotFileMode = 0600
+
+// snapshotState persists the last good data set of a loader so a service can warm
+// start from disk when MySQL is unavailable, and guards in-memory data against
+// being wiped by an empty, partial or shrunken load.
+type snapshotState struct {
+	path       string
+	staleSince time.Time
+	loadedAt   time.Time
+	force      bool
+	maxShrink  float64
+	mutex      *sync.RWMutex
+}
+
+func newSnapshotState() *snapshotState {
+	return &snapshotState{
+		maxShrink: DefaultMaxShrink,
+		mutex:     &sync.RWMutex{},
+	}
+}
+
+// SetSnapshotPath makes the loader write its data to path after every successful load.
+// An empty path disables snapshots.
+func (s *snapshotState) SetSnapshotPath(path string) {
+	s.mutex.Lock()
+	s.path = path
+	s.mutex.Unlock()
+}
+
+// StaleSince returns the time the in-memory data was saved if it was restored from a
+// snapshot and no database load succeeded since. A zero time means the data is fresh.
+func (s *snapshotState) StaleSince() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.staleSince
+}
+
+// LoadedAt returns the time of the last successful database load, zero until the first
+// one, e.g. for a readiness check.
+func (s *snapshotState) LoadedAt() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.loadedAt
+}
+
+// SetMaxShrink sets the largest fraction (0 to 1) of the in-memory items a full load may
+// drop; 1 turns the check off except for empty loads.
+func (s *snapshotState) SetMaxShrink(fraction float64) {
+	s.mutex.Lock()
+	s.maxShrink = fraction
+	s.mutex.Unlock()
+}
+
+// ForceLoad lets the next load replace the in-memory data even if it is empty, had
+// rows that failed to scan, or shrank beyond the max shrink.
+func (s *snapshotState) ForceLoad() {
+	s.mutex.Lock()
+	s.force = true
+	s.mutex.Unlock()
+}
+
+// allowReplace returns nil if a load that produced newCount items, with scanErrors rows
+// that could not be scanned, may replace the oldCount items currently in memory. A
+// forced load is always allowed and clears the force.
+func (s *snapshotState) allowReplace(oldCount int, newCount int, scanErrors int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.force || oldCount == 0 {
+		s.force = false
+		return nil
+	}
+	if newCount == 0 {
+		return errors.New("load returned no rows")
+	}
+	if scanErrors > 0 {
+		return fmt.Errorf("%d rows failed to scan", scanErrors)
+	}
+	if float64(oldCount-newCount) > float64(oldCount)*s.maxShrink {
+		return fmt.Errorf("load shrank from %d to %d items", oldCount, newCount)
+	}
+	return nil
+}
+
+// saveSnapshot marks the data as freshly loaded and writes it to the snapshot file if
+// enabled.
+func saveSnapshot[T any](s *snapshotState, items []T) error {
+	s.mutex.Lock()
+	s.staleSince = time.Time{}
+	s.loadedAt = time.Now()
+	path := s.path
+	s.mutex.Unlock()
+
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(snapshotFile[T]{SavedAt: time.Now(), Items: items})
+	if err != nil {
+		return err
+	}
+	if err = system.MakeDirAll(filepath.Dir(path)); err != nil {
+		return err
+	}
+	// write then rename so a crash never leaves a truncated snapshot behind. Snapshots
+	// hold rpc endpoints with their api keys, so only the owner may read them; the
+	// chmod covers a tmp file left behind with a wider mode.
+	tmpPath := path + ".tmp"
+	if err = os.WriteFile(tmpPath, data, snapshotFileMode); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpPath, snapshotFileMode); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// readSnapshot reads the snapshot file and marks the data as stale since it was saved.
+func readSnapshot[T any](s *snapshotState) ([]T, error) {
+	s.mutex.RLock()
+	path := s.path
+	s.mutex.RUnlock()
+
+	if path == "" {
+		return nil, ErrSnapshotDisabled
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file snapshotFile[T]
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	s.staleSince = file.SavedAt
+	s.mutex.Unlock()
+	return file.Items, nil
+}
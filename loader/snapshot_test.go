@@ -0,0 +1,57 @@
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot", "bridge_fee.json")
+
+	mgr := NewBridgeFeeManager(nil, nil)
+	mgr.SetSnapshotPath(path)
+	assert.True(t, mgr.LoadedAt().IsZero())
+	allBridgeFees := []*BridgeFee{{TokenName: "USDC", FromChainName: "A", ToChainName: "B", BridgeFeeRatioLv1: 3}}
+	mgr.setBridgeFees(allBridgeFees)
+	assert.NoError(t, saveSnapshot(mgr.snapshotState, allBridgeFees))
+	assert.True(t, mgr.StaleSince().IsZero())
+	assert.False(t, mgr.LoadedAt().IsZero())
+	// snapshots of t_chain_info hold rpc api keys
+	if info, err := os.Stat(path); assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	restored := NewBridgeFeeManager(nil, nil)
+	restored.SetSnapshotPath(path)
+	assert.NoError(t, restored.RestoreSnapshot())
+	assert.False(t, restored.StaleSince().IsZero())
+	assert.True(t, restored.LoadedAt().IsZero())
+	fee, ok := restored.GetBridgeFee("usdc", "a", "b")
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), fee.BridgeFeeRatioLv1)
+
+	assert.ErrorIs(t, restored.RestoreSnapshot(), ErrAlreadyLoaded)
+}
+
+func TestSnapshotAllowReplace(t *testing.T) {
+	state := newSnapshotState()
+	assert.NoError(t, state.allowReplace(0, 0, 0))
+	assert.NoError(t, state.allowReplace(0, 3, 2))
+	assert.NoError(t, state.allowReplace(5, 3, 0))
+	assert.Error(t, state.allowReplace(5, 0, 0))
+	assert.Error(t, state.allowReplace(5, 5, 1))
+	assert.Error(t, state.allowReplace(5, 2, 0))
+
+	state.ForceLoad()
+	assert.NoError(t, state.allowReplace(5, 0, 0))
+	assert.Error(t, state.allowReplace(5, 0, 0))
+	state.ForceLoad()
+	assert.NoError(t, state.allowReplace(5, 5, 1))
+
+	state.SetMaxShrink(1)
+	assert.NoError(t, state.allowReplace(5, 1, 0))
+	assert.Error(t, state.allowReplace(5, 0, 0))
+}
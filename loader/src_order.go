@@ -0,0 +1,438 @@
+package loader
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/realcaishen/utils-go/telemetry"
+	"github.com/realcaishen/utils-go/util"
+)
+
+// OrderState is the lifecycle state of a bridge order, derived from the flag columns of
+// t_src_transaction.
+type OrderState int32
+
+const (
+	// OrderPending waits for a worker, possibly not before next_time.
+	OrderPending OrderState = iota + 1
+	// OrderLocked is claimed by a worker that is building the dst transaction.
+	OrderLocked
+	// OrderProcessed has a dst transaction sent and waits for verification.
+	OrderProcessed
+	// OrderVerified has a dst transaction confirmed on chain.
+	OrderVerified
+	// OrderInvalid is rejected and will never be processed.
+	OrderInvalid
+	// OrderManual is parked for a human to look at.
+	OrderManual
+)
+
+var (
+	ErrInvalidTransition  = errors.New("order state transition not allowed")
+	ErrTransitionConflict = fmt.Errorf("%w: order is no longer in the expected state", ErrConflict)
+)
+
+// orderTransitions lists the allowed moves. A processed order already has a dst
+// transaction out, so it never goes straight back to pending: retrying it could pay
+// twice. An operator moves it to manual first and decides from there.
+var orderTransitions = map[OrderState][]OrderState{
+	OrderPending:   {OrderLocked, OrderInvalid, OrderManual},
+	OrderLocked:    {OrderPending, OrderProcessed, OrderInvalid, OrderManual},
+	OrderProcessed: {OrderVerified, OrderInvalid, OrderManual},
+	OrderManual:    {OrderPending, OrderVerified, OrderInvalid},
+}
+
+// orderStateWhere selects rows currently in a state, orderStateSet moves a row into it.
+var orderStateWhere = map[OrderState]string{
+	OrderPending:   "is_invalid = 0 AND IFNULL(is_manual, 0) = 0 AND IFNULL(is_verified, 0) = 0 AND is_processed = 0 AND is_locked = 0",
+	OrderLocked:    "is_invalid = 0 AND IFNULL(is_manual, 0) = 0 AND IFNULL(is_verified, 0) = 0 AND is_processed = 0 AND is_locked = 1",
+	OrderProcessed: "is_invalid = 0 AND IFNULL(is_manual, 0) = 0 AND IFNULL(is_verified, 0) = 0 AND is_processed = 1",
+	OrderVerified:  "is_invalid = 0 AND is_verified = 1",
+	OrderInvalid:   "is_invalid = 1",
+	OrderManual:    "is_invalid = 0 AND IFNULL(is_verified, 0) = 0 AND is_manual = 1",
+}
+
+// orderTransitionWhere narrows orderStateWhere of the from state for single moves. A
+// manual order only goes back to pending if no dst transaction was ever sent for it:
+// one parked after processing keeps is_processed and dst_tx_hash, and retrying it
+// could pay twice.
+var orderTransitionWhere = map[[2]OrderState]string{
+	{OrderManual, OrderPending}: "is_processed = 0 AND dst_tx_hash IS NULL",
+}
+
+func transitionWhere(from OrderState, to OrderState) string {
+	where := orderStateWhere[from]
+	if extra, ok := orderTransitionWhere[[2]OrderState{from, to}]; ok {
+		where += " AND " + extra
+	}
+	return where
+}
+
+var orderStateSet = map[OrderState]string{
+	OrderPending:   "is_locked = 0, is_processed = 0, is_manual = 0, next_time = ?",
+	OrderLocked:    "is_locked = 1",
+	OrderProcessed: "is_locked = 0, is_processed = 1, process_timestamp = UNIX_TIMESTAMP(), dst_tx_hash = IFNULL(?, dst_tx_hash)",
+	OrderVerified:  "is_locked = 0, is_manual = 0, is_verified = 1, verified_timestamp = UNIX_TIMESTAMP()",
+	OrderInvalid:   "is_locked = 0, is_invalid = 1",
+	OrderManual:    "is_locked = 0, is_manual = 1",
+}
+
+func (s OrderState) String() string {
+	switch s {
+	case OrderPending:
+		return "pending"
+	case OrderLocked:
+		return "locked"
+	case OrderProcessed:
+		return "processed"
+	case OrderVerified:
+		return "verified"
+	case OrderInvalid:
+		return "invalid"
+	case OrderManual:
+		return "manual"
+	default:
+		return fmt.Sprintf("unknown(%d)", int32(s))
+	}
+}
+
+// CanTransition reports whether an order may move from one state to another.
+func CanTransition(from OrderState, to OrderState) bool {
+	for _, allowed := range orderTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// SrcOrder is a t_src_transaction row seen through its lifecycle columns.
+type SrcOrder struct {
+	Id          int64
+	ChainId     int32
+	TxHash      string
+	Sender      string
+	Receiver    string
+	Token       string
+	Value       string
+	DstChainid  sql.NullInt32
+	DstTxHash   sql.NullString
+	IsProcessed int32
+	IsInvalid   int32
+	IsVerified  sql.NullInt32
+	IsLocked    int32
+	IsManual    sql.NullInt32
+	IsCctp      int32
+	CctpStatus  int32
+	NextTime    int32
+}
+
+// State derives the lifecycle state from the flag columns.
+func (o *SrcOrder) State() OrderState {
+	if o.IsInvalid != 0 {
+		return OrderInvalid
+	}
+	if o.IsVerified.Int32 != 0 {
+		return OrderVerified
+	}
+	if o.IsManual.Int32 != 0 {
+		return OrderManual
+	}
+	if o.IsProcessed != 0 {
+		return OrderProcessed
+	}
+	if o.IsLocked != 0 {
+		return OrderLocked
+	}
+	return OrderPending
+}
+
+// OrderTransition describes a requested state change. DstTxHash is only written when
+// moving to OrderProcessed and NextTime only when moving to OrderPending. DstTimestamp,
+// the unix time of the block holding the dst transaction, is not stored: a move to
+// OrderVerified reports the settlement latency up to it, and none without it.
+type OrderTransition struct {
+	SrcId        int64
+	From         OrderState
+	To           OrderState
+	Operator     string
+	Reason       string
+	DstTxHash    sql.NullString
+	NextTime     int64
+	DstTimestamp int64
+}
+
+// OrderTransitionLog is one row of the t_src_transaction_transition audit trail.
+type OrderTransitionLog struct {
+	Id              int64
+	SrcId           int64
+	FromState       OrderState
+	ToState         OrderState
+	Operator        string
+	Reason          string
+	InsertTimestamp int64
+}
+
+const (
+	transitionInsertColumns = "(src_id, from_state, to_state, operator, reason) VALUES (?, ?, ?, ?, ?)"
+	transitionLogColumns    = "id, src_id, from_state, to_state, operator, reason, UNIX_TIMESTAMP(insert_timestamp)"
+	// orderMetricColumns are read once an order settles or fails, for the bridge metrics
+	orderMetricColumns = "chainid, dst_chainid, IFNULL(NULLIF(src_token_name, ''), token), tx_timestamp, bridge_fee, src_token_decimal"
+)
+
+const srcOrderColumns = "id, chainid, tx_hash, sender, receiver, token, value, dst_chainid, dst_tx_hash, is_processed, is_invalid, is_verified, is_locked, is_manual, is_cctp, cctp_status, next_time"
+
+func scanSrcOrder(row interface{ Scan(dest ...any) error }) (*SrcOrder, error) {
+	var order SrcOrder
+	if err := row.Scan(&order.Id, &order.ChainId, &order.TxHash, &order.Sender, &order.Receiver, &order.Token, &order.Value,
+		&order.DstChainid, &order.DstTxHash, &order.IsProcessed, &order.IsInvalid, &order.IsVerified, &order.IsLocked,
+		&order.IsManual, &order.IsCctp, &order.CctpStatus, &order.NextTime); err != nil {
+		return nil, err
+	}
+	order.TxHash = strings.TrimSpace(order.TxHash)
+	order.Sender = strings.TrimSpace(order.Sender)
+	order.Receiver = strings.TrimSpace(order.Receiver)
+	order.Token = strings.TrimSpace(order.Token)
+	order.Value = strings.TrimSpace(order.Value)
+	order.DstTxHash.String = strings.TrimSpace(order.DstTxHash.String)
+	return &order, nil
+}
+
+func (mgr *SrcTxManager) GetOrder(id int64) (*SrcOrder, error) {
+	return scanSrcOrder(mgr.db.QueryRow("SELECT "+srcOrderColumns+" FROM t_src_transaction WHERE id = ?", id))
+}
+
+// Transition moves an order between states. The update only applies if the row is still
+// in t.From, so two workers racing on the same order cannot both win; the loser gets
+// ErrTransitionConflict, as does a manual order that cannot go back to pending because
+// a dst transaction was already sent for it. Every applied transition is recorded in
+// t_src_transaction_transition in the same SQL transaction.
+func (mgr *SrcTxManager) Transition(t *OrderTransition) error {
+	ctx := context.Background()
+	err := WithTx(ctx, mgr.db, func(tx *sql.Tx) error {
+		return mgr.transitionTx(ctx, tx, t)
+	})
+	if err != nil && !errors.Is(err, ErrConflict) && !errors.Is(err, ErrInvalidTransition) {
+		mgr.alerter.AlertText("order transition error", err)
+	}
+	if err == nil {
+		mgr.CountTransition(ctx, t)
+	}
+	return err
+}
+
+// TransitionTx is Transition through q, for callers that want the state change to
+// commit together with their own writes, e.g. saving the DstTx of a processed order.
+// When q is not a transaction the audit row is written separately. The bridge metrics
+// are emitted once q commits, see AfterCommit; within a transaction not started by
+// WithTx the caller calls CountTransition once it committed.
+func (mgr *SrcTxManager) TransitionTx(ctx context.Context, q DBTX, t *OrderTransition) error {
+	if err := mgr.transitionTx(ctx, q, t); err != nil {
+		return err
+	}
+	AfterCommit(q, func() { mgr.CountTransition(ctx, t) })
+	return nil
+}
+
+func (mgr *SrcTxManager) transitionTx(ctx context.Context, q DBTX, t *OrderTransition) error {
+	if !CanTransition(t.From, t.To) {
+		return fmt.Errorf("%w: %v -> %v", ErrInvalidTransition, t.From, t.To)
+	}
+
+	args := make([]interface{}, 0, 3)
+	switch t.To {
+	case OrderPending:
+		args = append(args, t.NextTime)
+	case OrderProcessed:
+		t.DstTxHash.String = strings.TrimSpace(t.DstTxHash.String)
+		args = append(args, t.DstTxHash)
+	}
+	args = append(args, t.SrcId)
+
+	result, err := q.ExecContext(ctx, "UPDATE t_src_transaction SET "+orderStateSet[t.To]+" WHERE id = ? AND "+transitionWhere(t.From, t.To), args...)
+	if err != nil {
+		return repoError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrTransitionConflict
+	}
+
+	_, err = q.ExecContext(ctx, "INSERT INTO t_src_transaction_transition "+transitionInsertColumns,
+		t.SrcId, t.From, t.To, strings.TrimSpace(t.Operator), strings.TrimSpace(t.Reason))
+	return repoError(err)
+}
+
+// CountTransition emits the settled or failed metric of a committed transition to
+// OrderVerified or OrderInvalid, and the bridge fee of a settled order; other
+// transitions are not counted. The metrics are best effort: an order that cannot be read
+// back is not counted.
+func (mgr *SrcTxManager) CountTransition(ctx context.Context, t *OrderTransition) {
+	if t.To != OrderVerified && t.To != OrderInvalid {
+		return
+	}
+	var (
+		chainId     int32
+		dstChainId  sql.NullInt32
+		token       string
+		txTimestamp int64
+		bridgeFee   string
+		decimals    sql.NullInt32
+	)
+	err := mgr.db.QueryRowContext(ctx, "SELECT "+orderMetricColumns+" FROM t_src_transaction WHERE id = ?", t.SrcId).
+		Scan(&chainId, &dstChainId, &token, &txTimestamp, &bridgeFee, &decimals)
+	if err != nil {
+		logger.CtxWarnf(ctx, "read order %d for metrics: %v", t.SrcId, err)
+		return
+	}
+	route := orderRoute(chainId, dstChainId, strings.TrimSpace(token))
+	if t.To == OrderInvalid {
+		telemetry.OrderFailed(route)
+		return
+	}
+	telemetry.OrderSettled(route, settlementLatency(txTimestamp, t.DstTimestamp))
+	if fee, ok := feeAmount(bridgeFee, decimals); ok {
+		telemetry.FeeCollected(route.SrcChain, route.Token, fee)
+	}
+}
+
+// feeAmount converts a bridge_fee in the src token's smallest unit to token units. It
+// fails for a zero or unparsable fee and when the token decimals are unknown.
+func feeAmount(bridgeFee string, decimals sql.NullInt32) (float64, bool) {
+	if !decimals.Valid {
+		return 0, false
+	}
+	amount, err := util.StringToUi(strings.TrimSpace(bridgeFee), decimals.Int32)
+	if err != nil || amount.Sign() <= 0 {
+		return 0, false
+	}
+	fee, _ := amount.Float64()
+	return fee, true
+}
+
+// settlementLatency is the time from the src transaction to the dst transaction, 0 when
+// either time is unset, as is tx_timestamp at 0 or its 2147483647 column default, or
+// the dst transaction is older.
+func settlementLatency(txTimestamp int64, dstTimestamp int64) time.Duration {
+	if txTimestamp <= 0 || dstTimestamp <= 0 || txTimestamp > dstTimestamp {
+		return 0
+	}
+	return time.Duration(dstTimestamp-txTimestamp) * time.Second
+}
+
+// ScheduleRetry releases a locked order back to pending, to be picked up again no earlier
+// than delay from now.
+func (mgr *SrcTxManager) ScheduleRetry(srcId int64, operator string, reason string, delay time.Duration) error {
+	return mgr.Transition(&OrderTransition{
+		SrcId:    srcId,
+		From:     OrderLocked,
+		To:       OrderPending,
+		Operator: operator,
+		Reason:   reason,
+		NextTime: time.Now().Add(delay).Unix(),
+	})
+}
+
+// ClaimProcessable locks up to limit pending orders for dstChainId whose next_time has
+// passed and returns them. Orders claimed concurrently by another worker are skipped.
+func (mgr *SrcTxManager) ClaimProcessable(dstChainId int32, limit int, operator string) ([]*SrcOrder, error) {
+	rows, err := mgr.db.Query("SELECT id FROM t_src_transaction WHERE dst_chainid = ? AND next_time <= ? AND "+orderStateWhere[OrderPending]+" ORDER BY id LIMIT ?",
+		dstChainId, time.Now().Unix(), limit)
+	if err != nil {
+		mgr.alerter.AlertText("select processable t_src_transaction error", err)
+		return nil, err
+	}
+	ids := make([]int64, 0, limit)
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	orders := make([]*SrcOrder, 0, len(ids))
+	for _, id := range ids {
+		err = mgr.Transition(&OrderTransition{SrcId: id, From: OrderPending, To: OrderLocked, Operator: operator, Reason: "claim"})
+		if errors.Is(err, ErrTransitionConflict) {
+			continue
+		}
+		if err != nil {
+			return orders, err
+		}
+		order, err := mgr.GetOrder(id)
+		if err != nil {
+			return orders, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+// ReleaseStaleLocks returns orders that stayed locked longer than timeout to pending,
+// for workers that died while holding them. It returns the number of released orders.
+func (mgr *SrcTxManager) ReleaseStaleLocks(timeout time.Duration, operator string) (int, error) {
+	rows, err := mgr.db.Query("SELECT id FROM t_src_transaction WHERE "+orderStateWhere[OrderLocked]+" AND update_timestamp < NOW() - INTERVAL ? SECOND",
+		int64(timeout.Seconds()))
+	if err != nil {
+		mgr.alerter.AlertText("select stale locked t_src_transaction error", err)
+		return 0, err
+	}
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, id := range ids {
+		err = mgr.Transition(&OrderTransition{SrcId: id, From: OrderLocked, To: OrderPending, Operator: operator, Reason: "lock timeout"})
+		if errors.Is(err, ErrTransitionConflict) {
+			continue
+		}
+		if err != nil {
+			return released, err
+		}
+		released++
+	}
+	return released, nil
+}
+
+// GetTransitions returns the audit trail of an order, oldest first.
+func (mgr *SrcTxManager) GetTransitions(srcId int64) ([]*OrderTransitionLog, error) {
+	rows, err := mgr.db.Query("SELECT "+transitionLogColumns+" FROM t_src_transaction_transition WHERE src_id = ? ORDER BY id", srcId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := make([]*OrderTransitionLog, 0)
+	for rows.Next() {
+		var log OrderTransitionLog
+		if err = rows.Scan(&log.Id, &log.SrcId, &log.FromState, &log.ToState, &log.Operator, &log.Reason, &log.InsertTimestamp); err != nil {
+			return nil, err
+		}
+		logs = append(logs, &log)
+	}
+	return logs, rows.Err()
+}
@@ -0,0 +1,86 @@
+package loader
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransition(t *testing.T) {
+	assert.True(t, CanTransition(OrderPending, OrderLocked))
+	assert.True(t, CanTransition(OrderLocked, OrderPending))
+	assert.True(t, CanTransition(OrderLocked, OrderProcessed))
+	assert.True(t, CanTransition(OrderProcessed, OrderVerified))
+	assert.True(t, CanTransition(OrderManual, OrderPending))
+
+	assert.False(t, CanTransition(OrderPending, OrderProcessed))
+	assert.False(t, CanTransition(OrderProcessed, OrderPending))
+	assert.False(t, CanTransition(OrderPending, OrderVerified))
+	assert.False(t, CanTransition(OrderVerified, OrderPending))
+	assert.False(t, CanTransition(OrderInvalid, OrderPending))
+	assert.False(t, CanTransition(OrderLocked, OrderLocked))
+
+	for state := range orderTransitions {
+		assert.NotEmpty(t, orderStateWhere[state], state.String())
+		assert.NotEmpty(t, orderStateSet[state], state.String())
+	}
+}
+
+func TestManualToPendingGuard(t *testing.T) {
+	assert.NotContains(t, transitionWhere(OrderManual, OrderVerified), "dst_tx_hash")
+
+	// an order parked after its dst tx went out never goes back to pending
+	db, fake := newFakeDB()
+	defer db.Close()
+	fake.affected["UPDATE t_src_transaction"] = 0
+	mgr := NewSrcTxManager(db, nil)
+	err := mgr.Transition(&OrderTransition{SrcId: 7, From: OrderManual, To: OrderPending, Operator: "ops"})
+	assert.ErrorIs(t, err, ErrTransitionConflict)
+
+	statements, _ := fake.matching("UPDATE t_src_transaction")
+	if assert.Len(t, statements, 1) {
+		assert.Contains(t, statements[0], orderStateWhere[OrderManual]+" AND is_processed = 0 AND dst_tx_hash IS NULL")
+	}
+	assert.Equal(t, "ROLLBACK", fake.statements()[len(fake.statements())-1])
+}
+
+func TestSrcOrderState(t *testing.T) {
+	order := &SrcOrder{}
+	assert.Equal(t, OrderPending, order.State())
+	order.IsLocked = 1
+	assert.Equal(t, OrderLocked, order.State())
+	order.IsProcessed = 1
+	assert.Equal(t, OrderProcessed, order.State())
+	order.IsManual = sql.NullInt32{Int32: 1, Valid: true}
+	assert.Equal(t, OrderManual, order.State())
+	order.IsVerified = sql.NullInt32{Int32: 1, Valid: true}
+	assert.Equal(t, OrderVerified, order.State())
+	order.IsInvalid = 1
+	assert.Equal(t, OrderInvalid, order.State())
+}
+
+func TestSettlementLatency(t *testing.T) {
+	dst := int64(1_700_000_090)
+	assert.Equal(t, 90*time.Second, settlementLatency(1_700_000_000, dst))
+	assert.Equal(t, time.Duration(0), settlementLatency(0, dst))
+	// the tx_timestamp column default
+	assert.Equal(t, time.Duration(0), settlementLatency(2147483647, dst))
+	assert.Equal(t, time.Duration(0), settlementLatency(dst+1, dst))
+	// the block time of the dst transaction is unknown
+	assert.Equal(t, time.Duration(0), settlementLatency(1_700_000_000, 0))
+}
+
+func TestFeeAmount(t *testing.T) {
+	fee, ok := feeAmount("1500000", sql.NullInt32{Int32: 6, Valid: true})
+	assert.True(t, ok)
+	assert.Equal(t, 1.5, fee)
+
+	_, ok = feeAmount("1500000", sql.NullInt32{})
+	assert.False(t, ok)
+	_, ok = feeAmount("0", sql.NullInt32{Int32: 6, Valid: true})
+	assert.False(t, ok)
+	_, ok = feeAmount("", sql.NullInt32{Int32: 6, Valid: true})
+	assert.False(t, ok)
+}
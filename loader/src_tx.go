@@ -1,10 +1,13 @@
 package loader
 
 import (
+	"context"
 	"database/sql"
+	"strconv"
 	"strings"
 
 	"github.com/realcaishen/utils-go/alert"
+	"github.com/realcaishen/utils-go/telemetry"
 )
 
 type SrcTx struct {
@@ -64,7 +67,7 @@ func (mgr *SrcTxManager) SetResultWithDstHash(txHash string, isInvalid int32, is
 	return nil
 }
 
-func (mgr *SrcTxManager) Save(tx *SrcTx) error {
+func (tx *SrcTx) normalize() {
 	tx.TxHash = strings.TrimSpace(tx.TxHash)
 	tx.Sender = strings.TrimSpace(tx.Sender)
 	tx.Receiver = strings.TrimSpace(tx.Receiver)
@@ -72,17 +75,94 @@ func (mgr *SrcTxManager) Save(tx *SrcTx) error {
 	tx.Value = strings.TrimSpace(tx.Value)
 	tx.TargetAddress.String = strings.TrimSpace(tx.TargetAddress.String)
 	tx.SrcTokenName.String = strings.TrimSpace(tx.SrcTokenName.String)
+}
+
+func (tx *SrcTx) route() telemetry.Route {
+	token := tx.Token
+	if tx.SrcTokenName.Valid && tx.SrcTokenName.String != "" {
+		token = tx.SrcTokenName.String
+	}
+	return orderRoute(tx.ChainId, tx.DstChainid, token)
+}
+
+// orderRoute labels the bridge metrics of an order with chain ids, as stored.
+func orderRoute(chainId int32, dstChainId sql.NullInt32, token string) telemetry.Route {
+	route := telemetry.Route{SrcChain: strconv.FormatInt(int64(chainId), 10), Token: token}
+	if dstChainId.Valid {
+		route.DstChain = strconv.FormatInt(int64(dstChainId.Int32), 10)
+	}
+	return route
+}
 
-	query := `INSERT IGNORE INTO t_src_transaction (chainid, tx_hash, sender, receiver, target_address, token, value, dst_chainid, is_testnet, tx_timestamp, src_token_name, src_token_decimal, is_cctp, src_nonce, thirdparty_channel, to_exchange)
+const srcTxInsertColumns = `(chainid, tx_hash, sender, receiver, target_address, token, value, dst_chainid, is_testnet, tx_timestamp, src_token_name, src_token_decimal, is_cctp, src_nonce, thirdparty_channel, to_exchange)
               VALUES (?, ?, ?, ?, ?, ?, ? , ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
+func (mgr *SrcTxManager) Save(tx *SrcTx) error {
+	tx.normalize()
+
+	query := `INSERT IGNORE INTO t_src_transaction ` + srcTxInsertColumns
+
 	// Execute the SQL statement with tx data
-	_, err := mgr.db.Exec(query, tx.ChainId, tx.TxHash, tx.Sender, tx.Receiver, tx.TargetAddress, tx.Token, tx.Value, tx.DstChainid, tx.IsTestnet, tx.TxTimestamp, tx.SrcTokenName, tx.SrcTokenDecimal, tx.IsCctp, tx.SrcNonce, tx.ThirdpartyChannel, tx.ToExchange)
+	result, err := mgr.db.Exec(query, tx.ChainId, tx.TxHash, tx.Sender, tx.Receiver, tx.TargetAddress, tx.Token, tx.Value, tx.DstChainid, tx.IsTestnet, tx.TxTimestamp, tx.SrcTokenName, tx.SrcTokenDecimal, tx.IsCctp, tx.SrcNonce, tx.ThirdpartyChannel, tx.ToExchange)
 	if err != nil {
 		mgr.alerter.AlertText("failed to insert src transaction", err)
 		return err
 	}
+	// an ignored duplicate is not a new order
+	if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+		mgr.CountReceived(tx)
+	}
 
 	return nil
 
 }
+
+// Insert stores tx through q and returns the new id. Unlike Save, an existing
+// (chainid, tx_hash) is reported as ErrDuplicate instead of being ignored. The received
+// metric is emitted once q commits, see AfterCommit; within a transaction not started by
+// WithTx the caller calls CountReceived once it committed.
+func (mgr *SrcTxManager) Insert(ctx context.Context, q DBTX, tx *SrcTx) (int64, error) {
+	tx.normalize()
+	result, err := q.ExecContext(ctx, "INSERT INTO t_src_transaction "+srcTxInsertColumns,
+		tx.ChainId, tx.TxHash, tx.Sender, tx.Receiver, tx.TargetAddress, tx.Token, tx.Value, tx.DstChainid, tx.IsTestnet, tx.TxTimestamp, tx.SrcTokenName, tx.SrcTokenDecimal, tx.IsCctp, tx.SrcNonce, tx.ThirdpartyChannel, tx.ToExchange)
+	if err != nil {
+		return 0, repoError(err)
+	}
+	AfterCommit(q, func() { mgr.CountReceived(tx) })
+	return result.LastInsertId()
+}
+
+// CountReceived emits the received metric of a committed src transaction.
+func (mgr *SrcTxManager) CountReceived(tx *SrcTx) {
+	telemetry.OrderReceived(tx.route())
+}
+
+// GetByHash returns the order for a src transaction or ErrNotFound.
+func (mgr *SrcTxManager) GetByHash(ctx context.Context, q DBTX, chainId int32, txHash string) (*SrcOrder, error) {
+	order, err := scanSrcOrder(q.QueryRowContext(ctx, "SELECT "+srcOrderColumns+" FROM t_src_transaction WHERE chainid = ? AND tx_hash = ?", chainId, strings.TrimSpace(txHash)))
+	if err != nil {
+		return nil, repoError(err)
+	}
+	return order, nil
+}
+
+// UpdateResult is SetResult through q, scoped to one chain. It returns the number of
+// rows changed, which is 0 when the flags already had these values, and ErrNotFound
+// if there is no such src transaction.
+func (mgr *SrcTxManager) UpdateResult(ctx context.Context, q DBTX, chainId int32, txHash string, isInvalid int32, isVerified int32) (int64, error) {
+	txHash = strings.TrimSpace(txHash)
+	result, err := q.ExecContext(ctx, "UPDATE t_src_transaction SET is_invalid = ?, is_verified = ? WHERE chainid = ? AND tx_hash = ?", isInvalid, isVerified, chainId, txHash)
+	if err != nil {
+		return 0, repoError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected > 0 {
+		return affected, err
+	}
+	// MySQL reports 0 rows for an update that changed nothing, tell that from a missing row
+	var id int64
+	if err = q.QueryRowContext(ctx, "SELECT id FROM t_src_transaction WHERE chainid = ? AND tx_hash = ?", chainId, txHash).Scan(&id); err != nil {
+		return 0, repoError(err)
+	}
+	return 0, nil
+}
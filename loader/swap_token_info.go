@@ -1,10 +1,12 @@
 package loader
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/realcaishen/utils-go/asynccache"
-	"github.com/realcaishen/utils-go/log"
+	"github.com/realcaishen/utils-go/invalidate"
 	"strings"
 	"sync"
 	"time"
@@ -17,57 +19,109 @@ type SwapTokenInfoManager struct {
 	db                         *sql.DB
 	alerter                    alert.Alerter
 	mutex                      *sync.RWMutex
-	chainNameTokenAddressCache asynccache.AsyncCache
+	chainNameTokenAddressCache asynccache.Cache[swapTokenKey, *TokenInfo]
+}
+
+const swapTokenCacheSize = 10000
+
+type swapTokenKey struct {
+	chainName string
+	tokenAddr string
 }
 
 func NewSwapTokenInfoManager(db *sql.DB, alerter alert.Alerter) *SwapTokenInfoManager {
-	chainNameTokenAddressCacheOption := asynccache.Options{
+	chainNameTokenAddressCacheOption := asynccache.CacheOptions[swapTokenKey, *TokenInfo]{
+		Name:            "swap_token_info",
 		RefreshDuration: 1 * time.Hour,
-		Fetcher: func(key string) (interface{}, error) {
-			s := strings.Split(key, "#")
-			if len(s) != 2 {
-				return nil, fmt.Errorf("invalid key: %s", key)
-			}
-			token, err := GetByChainNameTokenAddrFromDb(db, s[0], s[1])
+		FetchTimeout:    5 * time.Second,
+		RefreshJitter:   0.1,
+		BatchFetcher: func(ctx context.Context, keys []swapTokenKey) (map[swapTokenKey]*TokenInfo, error) {
+			tokens, err := getSwapTokensFromDb(ctx, db, keys)
 			if err != nil {
-				log.Errorf("chain %v addr %v query db error: %v", s[0], s[1], err)
+				logger.Errorf("query %v swap tokens from db error: %v", len(keys), err)
 				return nil, err
 			}
-			return token, nil
+			return tokens, nil
 		},
+		// unknown addresses are common, remember them for a while instead of querying per request
+		NegativeTTL:    5 * time.Minute,
 		EnableExpire:   true,
 		ExpireDuration: 30 * time.Minute,
+		// lookups come from user input, so random addresses must not grow it unbounded
+		MaxEntries:     swapTokenCacheSize,
+		EvictionPolicy: asynccache.EvictLRU,
 	}
 
 	return &SwapTokenInfoManager{
 		db:                         db,
 		alerter:                    alerter,
 		mutex:                      &sync.RWMutex{},
-		chainNameTokenAddressCache: asynccache.NewAsyncCache(chainNameTokenAddressCacheOption),
+		chainNameTokenAddressCache: asynccache.NewCache(chainNameTokenAddressCacheOption),
 	}
 }
 
 func (mgr *SwapTokenInfoManager) GetByChainNameTokenAddr(chainName string, tokenAddr string) (*TokenInfo, bool) {
-	key := chainName + "#" + tokenAddr
-	value, err := mgr.chainNameTokenAddressCache.Get(key)
-	if err != nil {
-		log.Errorf("GetByChainNameTokenAddr chainName %v tokenAddr %v err: %v", chainName, tokenAddr, err)
+	key := swapTokenKey{chainName: strings.ToLower(strings.TrimSpace(chainName)), tokenAddr: strings.ToLower(strings.TrimSpace(tokenAddr))}
+	token, err := mgr.chainNameTokenAddressCache.Get(key)
+	if errors.Is(err, asynccache.ErrNotFound) {
 		return nil, false
 	}
-	if token, ok := value.(*TokenInfo); ok {
-		return token, true
-	} else {
-		log.Errorf("swap token cache value wrong type, key %v", key)
+	if err != nil {
+		logger.Errorf("GetByChainNameTokenAddr chainName %v tokenAddr %v err: %v", chainName, tokenAddr, err)
 		return nil, false
 	}
+	return token, token != nil
+}
+
+// SubscribeInvalidation drops cached tokens when bus carries a t_swap_token_info message
+// keyed by TokenKey, or by a "<chain>#" prefix for a whole chain.
+func (mgr *SwapTokenInfoManager) SubscribeInvalidation(bus invalidate.Bus) (unsubscribe func()) {
+	return asynccache.InvalidateOn(mgr.chainNameTokenAddressCache, bus, "t_swap_token_info", func(key swapTokenKey) string {
+		return TokenKey(key.chainName, key.tokenAddr)
+	})
 }
 
+const swapTokenColumns = "token_name, chain_name, token_address, decimals, icon"
+
 func GetByChainNameTokenAddrFromDb(db *sql.DB, chainName string, tokenAddr string) (*TokenInfo, error) {
 	var token TokenInfo
-	err := db.QueryRow("SELECT token_name, chain_name, token_address, decimals, icon FROM t_swap_token_info where chain_name = ? and token_address = ?", chainName, tokenAddr).
+	err := db.QueryRow("SELECT "+swapTokenColumns+" FROM t_swap_token_info where chain_name = ? and token_address = ?", chainName, tokenAddr).
 		Scan(&token.TokenName, &token.ChainName, &token.TokenAddress, &token.Decimals, &token.Icon)
 	if err != nil {
 		return nil, fmt.Errorf("get token info by chainName %v token Addr err: %v", chainName, err)
 	}
 	return &token, nil
 }
+
+// getSwapTokensFromDb looks up many (chain, address) pairs in one query. Keys must be
+// lower cased; tokens that do not exist are left out of the result.
+func getSwapTokensFromDb(ctx context.Context, db *sql.DB, keys []swapTokenKey) (map[swapTokenKey]*TokenInfo, error) {
+	tokens := make(map[swapTokenKey]*TokenInfo, len(keys))
+	if len(keys) == 0 {
+		return tokens, nil
+	}
+	args := make([]interface{}, 0, len(keys)*2)
+	for _, key := range keys {
+		args = append(args, key.chainName, key.tokenAddr)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("(?, ?), ", len(keys)), ", ")
+	rows, err := db.QueryContext(ctx, "SELECT "+swapTokenColumns+" FROM t_swap_token_info WHERE (chain_name, token_address) IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var token TokenInfo
+		if err = rows.Scan(&token.TokenName, &token.ChainName, &token.TokenAddress, &token.Decimals, &token.Icon); err != nil {
+			return nil, err
+		}
+		token.ChainName = strings.TrimSpace(token.ChainName)
+		token.TokenAddress = strings.TrimSpace(token.TokenAddress)
+		token.TokenName = strings.TrimSpace(token.TokenName)
+		// the table collation is case insensitive, so map rows back by lower cased key
+		key := swapTokenKey{chainName: strings.ToLower(token.ChainName), tokenAddr: strings.ToLower(token.TokenAddress)}
+		tokens[key] = &token
+	}
+	return tokens, rows.Err()
+}
@@ -0,0 +1,264 @@
+package loader
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/realcaishen/utils-go/alert"
+	"github.com/realcaishen/utils-go/dal/model"
+)
+
+type Tag = model.TTag
+
+// Object tables commonly tagged. Any table name may be used as long as its rows have a
+// bigint id.
+const (
+	TagObjectToken = "t_token_info"
+	TagObjectChain = "t_chain_info"
+	TagObjectMaker = "t_maker_addresses"
+	TagObjectOrder = "t_src_transaction"
+)
+
+type TagManager struct {
+	idTags   map[int64]*Tag
+	nameTags map[string]*Tag
+	// object table -> object id -> lower cased tag names
+	objectTags map[string]map[int64]map[string]struct{}
+	db         *sql.DB
+	alerter    alert.Alerter
+	mutex      *sync.RWMutex
+
+	*readRouter
+}
+
+const (
+	tagColumns       = "id, tag_name"
+	objectTagColumns = "object_table, object_id, tag_id"
+)
+
+func NewTagManager(db *sql.DB, alerter alert.Alerter) *TagManager {
+	return &TagManager{
+		idTags:     make(map[int64]*Tag),
+		nameTags:   make(map[string]*Tag),
+		objectTags: make(map[string]map[int64]map[string]struct{}),
+		db:         db,
+		alerter:    alerter,
+		mutex:      &sync.RWMutex{},
+
+		readRouter: newReadRouter(db),
+	}
+}
+
+func normalizeTagName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func (mgr *TagManager) GetTagByName(name string) (*Tag, bool) {
+	mgr.mutex.RLock()
+	tag, ok := mgr.nameTags[normalizeTagName(name)]
+	mgr.mutex.RUnlock()
+	return tag, ok
+}
+
+func (mgr *TagManager) GetAllTags() []*Tag {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	tags := make([]*Tag, 0, len(mgr.idTags))
+	for _, tag := range mgr.idTags {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].TagName < tags[j].TagName })
+	return tags
+}
+
+// GetObjectTags returns the sorted tag names of an object.
+func (mgr *TagManager) GetObjectTags(objectTable string, objectId int64) []string {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	names := make([]string, 0)
+	for name := range mgr.objectTags[objectTable][objectId] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HasTag reports whether an object carries a tag.
+func (mgr *TagManager) HasTag(objectTable string, objectId int64, tagName string) bool {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	_, ok := mgr.objectTags[objectTable][objectId][normalizeTagName(tagName)]
+	return ok
+}
+
+// MatchObject reports whether an object satisfies expr. Objects without any tag are
+// matched against the empty tag set, so NOT expressions include them.
+func (mgr *TagManager) MatchObject(objectTable string, objectId int64, expr *TagExpr) bool {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	return expr.Match(mgr.objectTags[objectTable][objectId])
+}
+
+// FindObjects returns the sorted ids of tagged objects in objectTable matching the
+// expression. Objects that carry no tag at all are unknown to the index and never
+// returned; use MatchObject over your own candidates when NOT should include them.
+func (mgr *TagManager) FindObjects(objectTable string, expr string) ([]int64, error) {
+	parsed, err := ParseTagExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	mgr.mutex.RLock()
+	ids := make([]int64, 0)
+	for id, tags := range mgr.objectTags[objectTable] {
+		if parsed.Match(tags) {
+			ids = append(ids, id)
+		}
+	}
+	mgr.mutex.RUnlock()
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// CreateTag returns the tag called name, creating it if needed.
+func (mgr *TagManager) CreateTag(ctx context.Context, name string) (*Tag, error) {
+	name = normalizeTagName(name)
+	if tag, ok := mgr.GetTagByName(name); ok {
+		return tag, nil
+	}
+	if _, err := mgr.db.ExecContext(ctx, "INSERT IGNORE INTO t_tag (tag_name) VALUES (?)", name); err != nil {
+		mgr.alerter.AlertText("insert t_tag error", err)
+		return nil, err
+	}
+	var tag Tag
+	err := mgr.db.QueryRowContext(ctx, "SELECT "+tagColumns+" FROM t_tag WHERE tag_name = ?", name).Scan(&tag.ID, &tag.TagName)
+	if err != nil {
+		return nil, repoError(err)
+	}
+	tag.TagName = normalizeTagName(tag.TagName)
+
+	mgr.mutex.Lock()
+	mgr.idTags[tag.ID] = &tag
+	mgr.nameTags[tag.TagName] = &tag
+	mgr.mutex.Unlock()
+	return &tag, nil
+}
+
+// TagObject attaches a tag to an object, creating the tag if needed. The in-memory
+// index is updated right away; other instances see it after their next load.
+func (mgr *TagManager) TagObject(ctx context.Context, objectTable string, objectId int64, tagName string) error {
+	tag, err := mgr.CreateTag(ctx, tagName)
+	if err != nil {
+		return err
+	}
+	_, err = mgr.db.ExecContext(ctx, "INSERT IGNORE INTO t_object_tag (object_table, object_id, tag_id) VALUES (?, ?, ?)", objectTable, objectId, tag.ID)
+	if err != nil {
+		mgr.alerter.AlertText("insert t_object_tag error", err)
+		return err
+	}
+
+	mgr.mutex.Lock()
+	addObjectTag(mgr.objectTags, objectTable, objectId, tag.TagName)
+	mgr.mutex.Unlock()
+	return nil
+}
+
+// UntagObject removes a tag from an object. Removing a tag the object does not carry
+// is not an error.
+func (mgr *TagManager) UntagObject(ctx context.Context, objectTable string, objectId int64, tagName string) error {
+	tag, ok := mgr.GetTagByName(tagName)
+	if !ok {
+		return nil
+	}
+	_, err := mgr.db.ExecContext(ctx, "DELETE FROM t_object_tag WHERE object_table = ? AND object_id = ? AND tag_id = ?", objectTable, objectId, tag.ID)
+	if err != nil {
+		mgr.alerter.AlertText("delete t_object_tag error", err)
+		return err
+	}
+
+	mgr.mutex.Lock()
+	if tags, ok := mgr.objectTags[objectTable][objectId]; ok {
+		delete(tags, tag.TagName)
+		if len(tags) == 0 {
+			delete(mgr.objectTags[objectTable], objectId)
+		}
+	}
+	mgr.mutex.Unlock()
+	return nil
+}
+
+func addObjectTag(objectTags map[string]map[int64]map[string]struct{}, objectTable string, objectId int64, tagName string) {
+	objects, ok := objectTags[objectTable]
+	if !ok {
+		objects = make(map[int64]map[string]struct{})
+		objectTags[objectTable] = objects
+	}
+	tags, ok := objects[objectId]
+	if !ok {
+		tags = make(map[string]struct{})
+		objects[objectId] = tags
+	}
+	tags[tagName] = struct{}{}
+}
+
+func (mgr *TagManager) LoadAllTags() {
+	span := startReload("t_tag")
+	defer span.End()
+	rows, err := mgr.scanDB().Query("SELECT " + tagColumns + " FROM t_tag")
+	if err != nil || rows == nil {
+		mgr.alerter.AlertText("select t_tag error", err)
+		return
+	}
+	defer rows.Close()
+
+	idTags := make(map[int64]*Tag)
+	nameTags := make(map[string]*Tag)
+	for rows.Next() {
+		var tag Tag
+		if err = rows.Scan(&tag.ID, &tag.TagName); err != nil {
+			mgr.alerter.AlertText("scan t_tag row error", err)
+			continue
+		}
+		tag.TagName = normalizeTagName(tag.TagName)
+		idTags[tag.ID] = &tag
+		nameTags[tag.TagName] = &tag
+	}
+	if err = rows.Err(); err != nil {
+		mgr.alerter.AlertText("get next t_tag row error", err)
+		return
+	}
+
+	objectRows, err := mgr.scanDB().Query("SELECT " + objectTagColumns + " FROM t_object_tag")
+	if err != nil || objectRows == nil {
+		mgr.alerter.AlertText("select t_object_tag error", err)
+		return
+	}
+	defer objectRows.Close()
+
+	objectTags := make(map[string]map[int64]map[string]struct{})
+	for objectRows.Next() {
+		var objectTable string
+		var objectId, tagId int64
+		if err = objectRows.Scan(&objectTable, &objectId, &tagId); err != nil {
+			mgr.alerter.AlertText("scan t_object_tag row error", err)
+			continue
+		}
+		tag, ok := idTags[tagId]
+		if !ok {
+			continue
+		}
+		addObjectTag(objectTags, strings.TrimSpace(objectTable), objectId, tag.TagName)
+	}
+	if err = objectRows.Err(); err != nil {
+		mgr.alerter.AlertText("get next t_object_tag row error", err)
+		return
+	}
+
+	mgr.mutex.Lock()
+	mgr.idTags = idTags
+	mgr.nameTags = nameTags
+	mgr.objectTags = objectTags
+	mgr.mutex.Unlock()
+}
@@ -0,0 +1,193 @@
+package loader
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// TagExpr is a parsed tag expression such as "stable AND (native OR NOT deprecated)".
+// Operators are AND, OR and NOT (case insensitive, also & | !), NOT binds tightest and
+// OR loosest. Tag names are matched case insensitively.
+type TagExpr struct {
+	op       string
+	tag      string
+	operands []*TagExpr
+}
+
+const (
+	tagOpTag = "tag"
+	tagOpAnd = "AND"
+	tagOpOr  = "OR"
+	tagOpNot = "NOT"
+)
+
+// Match reports whether an object carrying tags satisfies the expression. tags must hold
+// lower cased names.
+func (e *TagExpr) Match(tags map[string]struct{}) bool {
+	switch e.op {
+	case tagOpTag:
+		_, ok := tags[e.tag]
+		return ok
+	case tagOpNot:
+		return !e.operands[0].Match(tags)
+	case tagOpAnd:
+		for _, operand := range e.operands {
+			if !operand.Match(tags) {
+				return false
+			}
+		}
+		return true
+	case tagOpOr:
+		for _, operand := range e.operands {
+			if operand.Match(tags) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func (e *TagExpr) String() string {
+	switch e.op {
+	case tagOpTag:
+		return e.tag
+	case tagOpNot:
+		return "NOT " + e.operands[0].String()
+	}
+	parts := make([]string, 0, len(e.operands))
+	for _, operand := range e.operands {
+		parts = append(parts, operand.String())
+	}
+	return "(" + strings.Join(parts, " "+e.op+" ") + ")"
+}
+
+func tokenizeTagExpr(s string) []string {
+	tokens := make([]string, 0)
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case r == '(' || r == ')' || r == '&' || r == '|' || r == '!':
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+type tagExprParser struct {
+	tokens []string
+	pos    int
+}
+
+// ParseTagExpr parses a tag expression.
+func ParseTagExpr(s string) (*TagExpr, error) {
+	p := &tagExprParser{tokens: tokenizeTagExpr(s)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty tag expression")
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in tag expression %q", p.tokens[p.pos], s)
+	}
+	return expr, nil
+}
+
+func (p *tagExprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *tagExprParser) accept(words ...string) bool {
+	token := p.peek()
+	for _, word := range words {
+		if strings.EqualFold(token, word) {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *tagExprParser) parseOr() (*TagExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	operands := []*TagExpr{left}
+	for p.accept(tagOpOr, "|") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, right)
+	}
+	if len(operands) == 1 {
+		return left, nil
+	}
+	return &TagExpr{op: tagOpOr, operands: operands}, nil
+}
+
+func (p *tagExprParser) parseAnd() (*TagExpr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	operands := []*TagExpr{left}
+	for p.accept(tagOpAnd, "&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, right)
+	}
+	if len(operands) == 1 {
+		return left, nil
+	}
+	return &TagExpr{op: tagOpAnd, operands: operands}, nil
+}
+
+func (p *tagExprParser) parseNot() (*TagExpr, error) {
+	if p.accept(tagOpNot, "!") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &TagExpr{op: tagOpNot, operands: []*TagExpr{operand}}, nil
+	}
+	if p.accept("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing ) in tag expression")
+		}
+		return expr, nil
+	}
+
+	token := p.peek()
+	switch strings.ToUpper(token) {
+	case "", tagOpAnd, tagOpOr, ")", "&", "|":
+		return nil, fmt.Errorf("expected a tag, got %q", token)
+	}
+	p.pos++
+	return &TagExpr{op: tagOpTag, tag: strings.ToLower(token)}, nil
+}
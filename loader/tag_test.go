@@ -0,0 +1,107 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tagSet(names ...string) map[string]struct{} {
+	tags := make(map[string]struct{})
+	for _, name := range names {
+		tags[name] = struct{}{}
+	}
+	return tags
+}
+
+func TestParseTagExpr(t *testing.T) {
+	cases := []struct {
+		expr   string
+		tags   map[string]struct{}
+		expect bool
+	}{
+		{"stable", tagSet("stable"), true},
+		{"Stable", tagSet("stable"), true},
+		{"stable", tagSet("native"), false},
+		{"stable AND native", tagSet("stable"), false},
+		{"stable and native", tagSet("stable", "native"), true},
+		{"stable OR native", tagSet("native"), true},
+		{"NOT deprecated", nil, true},
+		{"!deprecated", tagSet("deprecated"), false},
+		{"stable & !deprecated", tagSet("stable", "deprecated"), false},
+		{"stable | native & deprecated", tagSet("stable"), true},
+		{"(stable | native) & deprecated", tagSet("stable"), false},
+		{"NOT (stable OR native)", tagSet("other"), true},
+		{"NOT NOT stable", tagSet("stable"), true},
+	}
+	for _, c := range cases {
+		expr, err := ParseTagExpr(c.expr)
+		if !assert.NoError(t, err, c.expr) {
+			continue
+		}
+		assert.Equal(t, c.expect, expr.Match(c.tags), c.expr)
+	}
+
+	for _, bad := range []string{"", "stable AND", "(stable", "stable)", "OR stable", "stable native", "NOT"} {
+		_, err := ParseTagExpr(bad)
+		assert.Error(t, err, bad)
+	}
+
+	expr, err := ParseTagExpr("a | b & !c")
+	assert.NoError(t, err)
+	assert.Equal(t, "(a OR (b AND NOT c))", expr.String())
+}
+
+func TestTagManagerIndex(t *testing.T) {
+	mgr := NewTagManager(nil, nil)
+	addObjectTag(mgr.objectTags, TagObjectToken, 1, "stable")
+	addObjectTag(mgr.objectTags, TagObjectToken, 1, "native")
+	addObjectTag(mgr.objectTags, TagObjectToken, 2, "stable")
+	addObjectTag(mgr.objectTags, TagObjectToken, 3, "deprecated")
+	addObjectTag(mgr.objectTags, TagObjectChain, 1, "deprecated")
+
+	assert.Equal(t, []string{"native", "stable"}, mgr.GetObjectTags(TagObjectToken, 1))
+	assert.Empty(t, mgr.GetObjectTags(TagObjectToken, 4))
+	assert.True(t, mgr.HasTag(TagObjectChain, 1, "Deprecated"))
+	assert.False(t, mgr.HasTag(TagObjectChain, 2, "deprecated"))
+
+	ids, err := mgr.FindObjects(TagObjectToken, "stable")
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, ids)
+
+	ids, err = mgr.FindObjects(TagObjectToken, "NOT native")
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 3}, ids)
+
+	_, err = mgr.FindObjects(TagObjectToken, "stable AND")
+	assert.Error(t, err)
+
+	expr, _ := ParseTagExpr("NOT deprecated")
+	assert.True(t, mgr.MatchObject(TagObjectToken, 4, expr))
+	assert.False(t, mgr.MatchObject(TagObjectToken, 3, expr))
+}
+
+func TestGetTokensByTag(t *testing.T) {
+	tagManager := NewTagManager(nil, nil)
+	addObjectTag(tagManager.objectTags, TagObjectToken, 1, "stable")
+	addObjectTag(tagManager.objectTags, TagObjectToken, 2, "stable")
+
+	mgr := NewTokenInfoManager(nil, nil)
+	mgr.AddTokenInfo(&TokenInfo{ID: 1, ChainName: "Ethereum", TokenName: "USDT", TokenAddress: "0x1"})
+	mgr.AddTokenInfo(&TokenInfo{ID: 2, ChainName: "Ethereum", TokenName: "USDC", TokenAddress: "0x2"})
+	mgr.AddTokenInfo(&TokenInfo{ID: 0, ChainName: "Ethereum", TokenName: "ETH", TokenAddress: "0x0"})
+	mgr.AddTokenInfo(&TokenInfo{ID: 3, ChainName: "Base", TokenName: "USDC", TokenAddress: "0x3"})
+
+	tokens, err := mgr.GetTokensByTag("ethereum", "stable", tagManager)
+	assert.NoError(t, err)
+	if assert.Len(t, tokens, 2) {
+		assert.Equal(t, "USDC", tokens[0].TokenName)
+		assert.Equal(t, "USDT", tokens[1].TokenName)
+	}
+
+	tokens, err = mgr.GetTokensByTag("ethereum", "NOT stable", tagManager)
+	assert.NoError(t, err)
+	if assert.Len(t, tokens, 1) {
+		assert.Equal(t, "ETH", tokens[0].TokenName)
+	}
+}
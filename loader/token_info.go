@@ -2,8 +2,11 @@ package loader
 
 import (
 	"database/sql"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/realcaishen/utils-go/alert"
 	"github.com/realcaishen/utils-go/dal/model"
@@ -18,6 +21,11 @@ type TokenInfoManager struct {
 	db                  *sql.DB
 	alerter             alert.Alerter
 	mutex               *sync.RWMutex
+
+	*changeNotifier
+	*snapshotState
+	incremental *incrementalState
+	*readRouter
 }
 
 func NewTokenInfoManager(db *sql.DB, alerter alert.Alerter) *TokenInfoManager {
@@ -28,9 +36,26 @@ func NewTokenInfoManager(db *sql.DB, alerter alert.Alerter) *TokenInfoManager {
 		db:                  db,
 		alerter:             alerter,
 		mutex:               &sync.RWMutex{},
+
+		changeNotifier: newChangeNotifier(),
+		snapshotState:  newSnapshotState(),
+		incremental:    newIncrementalState(),
+		readRouter:     newReadRouter(db),
 	}
 }
 
+// SetFullReloadInterval changes how often LoadChangedToken falls back to a full reload.
+func (mgr *TokenInfoManager) SetFullReloadInterval(interval time.Duration) {
+	mgr.incremental.setFullReloadInterval(interval)
+}
+
+// SetDeltaLookback changes how far before the last applied update_timestamp LoadChangedToken
+// starts reading, see DefaultDeltaLookback. It should cover the longest transaction
+// writing the table plus the replica lag.
+func (mgr *TokenInfoManager) SetDeltaLookback(lookback time.Duration) {
+	mgr.incremental.setLookback(lookback)
+}
+
 func (mgr *TokenInfoManager) GetByChainNameTokenAddr(chainName string, tokenAddr string) (*TokenInfo, bool) {
 	mgr.mutex.RLock()
 	defer mgr.mutex.RUnlock()
@@ -110,15 +135,231 @@ func (mgr *TokenInfoManager) GetTokenAddresses(chainName string) []string {
 }
 
 func (mgr *TokenInfoManager) GetAllTokens() []*TokenInfo {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
 	return mgr.allTokens
 }
 
+// GetTokensByTag returns the tokens of chainName matching the tag expression, e.g.
+// "stable AND NOT deprecated", sorted by token name. Gas tokens have no t_token_info row
+// and are matched as untagged.
+func (mgr *TokenInfoManager) GetTokensByTag(chainName string, expr string, tagManager *TagManager) ([]*TokenInfo, error) {
+	parsed, err := ParseTagExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]*TokenInfo, 0)
+	mgr.mutex.RLock()
+	for _, token := range mgr.chainNameTokenAddrs[strings.ToLower(strings.TrimSpace(chainName))] {
+		if token.ID == 0 {
+			if parsed.Match(nil) {
+				tokens = append(tokens, token)
+			}
+			continue
+		}
+		if tagManager.MatchObject(TagObjectToken, token.ID, parsed) {
+			tokens = append(tokens, token)
+		}
+	}
+	mgr.mutex.RUnlock()
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].TokenName < tokens[j].TokenName })
+	return tokens, nil
+}
+
+const tokenInfoColumns = "id, token_name, chain_name, token_address, decimals, icon, UNIX_TIMESTAMP(update_timestamp)"
+
+func sameTokenInfo(a *TokenInfo, b *TokenInfo) bool {
+	return a.TokenName == b.TokenName && a.ChainName == b.ChainName && a.TokenAddress == b.TokenAddress &&
+		a.Decimals == b.Decimals && a.Icon == b.Icon
+}
+
+func scanTokenInfo(rows *sql.Rows) (*TokenInfo, int64, error) {
+	var token TokenInfo
+	var updateTs int64
+	if err := rows.Scan(&token.ID, &token.TokenName, &token.ChainName, &token.TokenAddress, &token.Decimals, &token.Icon, &updateTs); err != nil {
+		return nil, 0, err
+	}
+	token.ChainName = strings.TrimSpace(token.ChainName)
+	token.TokenAddress = strings.TrimSpace(token.TokenAddress)
+	token.TokenName = strings.TrimSpace(token.TokenName)
+	token.Icon = strings.TrimSpace(token.Icon)
+	return &token, updateTs, nil
+}
+
+// LoadAllToken reloads the whole t_token_info table plus the gas tokens of every chain
+// and notifies subscribers of every token that was added, changed or removed.
 func (mgr *TokenInfoManager) LoadAllToken(chainManager *ChainInfoManager) {
+	span := startReload("t_token_info")
+	defer span.End()
+	if chainManager == nil {
+		panic("chainManager is required")
+	}
+	mgr.incremental.mutex.Lock()
+	defer mgr.incremental.mutex.Unlock()
+	mgr.loadAllToken(chainManager)
+}
+
+// LoadChangedToken only reads tokens whose update_timestamp moved since the last load,
+// less the delta lookback, falling back to LoadAllToken on the first call and every full
+// reload interval.
+func (mgr *TokenInfoManager) LoadChangedToken(chainManager *ChainInfoManager) {
+	span := startReload("t_token_info")
+	defer span.End()
 	if chainManager == nil {
 		panic("chainManager is required")
 	}
+	mgr.incremental.mutex.Lock()
+	defer mgr.incremental.mutex.Unlock()
+
+	if mgr.incremental.needFullLoad(time.Now()) {
+		mgr.loadAllToken(chainManager)
+		return
+	}
+
+	rows, err := mgr.scanDB().Query("SELECT "+tokenInfoColumns+" FROM t_token_info WHERE update_timestamp >= FROM_UNIXTIME(?)", mgr.incremental.mark.since())
+	if err != nil || rows == nil {
+		mgr.alerter.AlertText("select changed t_token_info error", err)
+		return
+	}
+	defer rows.Close()
+
+	mark := newMarkBuilder(mgr.incremental.mark)
+	changedTokens := make(map[int64]*TokenInfo)
+	for rows.Next() {
+		token, updateTs, err := scanTokenInfo(rows)
+		if err != nil {
+			mgr.alerter.AlertText("scan t_token_info row error", err)
+			// the row's timestamp is unknown, read everything since the last mark again
+			mark.hold(mgr.incremental.mark.since())
+			continue
+		}
+		markKey := strconv.FormatInt(token.ID, 10)
+		version := rowVersion(token)
+		if mark.seen(markKey, updateTs, version) {
+			continue
+		}
+		mark.apply(markKey, updateTs, version)
+		changedTokens[token.ID] = token
+	}
+
+	if err = rows.Err(); err != nil {
+		mgr.alerter.AlertText("get next t_token_info row error", err)
+		return
+	}
+	mgr.incremental.mark = mark.build()
+	if len(changedTokens) == 0 {
+		return
+	}
+
+	mgr.mutex.RLock()
+	allTokens := make([]*TokenInfo, 0, len(mgr.allTokens)+len(changedTokens))
+	for _, token := range mgr.allTokens {
+		if changed, ok := changedTokens[token.ID]; ok && token.ID != 0 {
+			allTokens = append(allTokens, changed)
+			delete(changedTokens, token.ID)
+		} else {
+			allTokens = append(allTokens, token)
+		}
+	}
+	mgr.mutex.RUnlock()
+	for _, token := range changedTokens {
+		allTokens = append(allTokens, token)
+	}
+
+	oldTokenAddrs := mgr.setTokens(allTokens)
+	if err = saveSnapshot(mgr.snapshotState, mgr.GetAllTokens()); err != nil {
+		mgr.alerter.AlertText("save t_token_info snapshot error", err)
+	}
+	mgr.notify(diffChanges("t_token_info", flattenTokens(oldTokenAddrs), flattenTokens(mgr.getTokenAddrs()), sameTokenInfo))
+}
+
+// RestoreSnapshot fills an empty manager from the snapshot file, so a service can start
+// while the database is unavailable. StaleSince reports the snapshot time until the next
+// successful load.
+func (mgr *TokenInfoManager) RestoreSnapshot() error {
+	mgr.incremental.mutex.Lock()
+	defer mgr.incremental.mutex.Unlock()
+
+	if len(mgr.GetAllTokens()) > 0 {
+		return ErrAlreadyLoaded
+	}
+	tokens, err := readSnapshot[*TokenInfo](mgr.snapshotState)
+	if err != nil {
+		return err
+	}
+	mgr.setTokens(tokens)
+	return nil
+}
+
+func (mgr *TokenInfoManager) getTokenAddrs() map[string]map[string]*TokenInfo {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	return mgr.chainNameTokenAddrs
+}
+
+// setTokens rebuilds both indexes from tokens and swaps them in, returning the previous
+// address index. Rows from t_token_info (non zero ID) win over chain gas tokens with the
+// same name, gas tokens that lose are dropped.
+func (mgr *TokenInfoManager) setTokens(tokens []*TokenInfo) map[string]map[string]*TokenInfo {
+	chainNameTokenAddrs := make(map[string]map[string]*TokenInfo)
+	chainNameTokenNames := make(map[string]map[string]*TokenInfo)
+	allTokens := make([]*TokenInfo, 0, len(tokens))
+
+	for _, token := range tokens {
+		if token.ID == 0 {
+			continue
+		}
+		tokenAddrs, ok := chainNameTokenAddrs[strings.ToLower(token.ChainName)]
+		if !ok {
+			tokenAddrs = make(map[string]*TokenInfo)
+			chainNameTokenAddrs[strings.ToLower(token.ChainName)] = tokenAddrs
+		}
+		tokenAddrs[strings.ToLower(token.TokenAddress)] = token
+
+		tokenNames, ok := chainNameTokenNames[strings.ToLower(token.ChainName)]
+		if !ok {
+			tokenNames = make(map[string]*TokenInfo)
+			chainNameTokenNames[strings.ToLower(token.ChainName)] = tokenNames
+		}
+		tokenNames[strings.ToLower(token.TokenName)] = token
+		allTokens = append(allTokens, token)
+	}
+
+	for _, token := range tokens {
+		if token.ID != 0 {
+			continue
+		}
+		tokenAddrs, ok := chainNameTokenAddrs[strings.ToLower(token.ChainName)]
+		if !ok {
+			tokenAddrs = make(map[string]*TokenInfo)
+			chainNameTokenAddrs[strings.ToLower(token.ChainName)] = tokenAddrs
+		}
+		tokenNames, ok := chainNameTokenNames[strings.ToLower(token.ChainName)]
+		if !ok {
+			tokenNames = make(map[string]*TokenInfo)
+			chainNameTokenNames[strings.ToLower(token.ChainName)] = tokenNames
+		}
+		_, ok = tokenNames[strings.ToLower(token.TokenName)]
+		if !ok {
+			tokenNames[strings.ToLower(token.TokenName)] = token
+			tokenAddrs[strings.ToLower(token.TokenAddress)] = token
+			allTokens = append(allTokens, token)
+		}
+	}
+
+	mgr.mutex.Lock()
+	oldTokenAddrs := mgr.chainNameTokenAddrs
+	mgr.chainNameTokenAddrs = chainNameTokenAddrs
+	mgr.chainNameTokenNames = chainNameTokenNames
+	mgr.allTokens = allTokens
+	mgr.mutex.Unlock()
+	return oldTokenAddrs
+}
+
+func (mgr *TokenInfoManager) loadAllToken(chainManager *ChainInfoManager) {
+	now := time.Now()
 	// Query the database to select only id and name fields
-	rows, err := mgr.db.Query("SELECT token_name, chain_name, token_address, decimals, icon FROM t_token_info")
+	rows, err := mgr.scanDB().Query("SELECT " + tokenInfoColumns + " FROM t_token_info")
 
 	if err != nil || rows == nil {
 		mgr.alerter.AlertText("select t_token_info error", err)
@@ -127,37 +368,20 @@ func (mgr *TokenInfoManager) LoadAllToken(chainManager *ChainInfoManager) {
 
 	defer rows.Close()
 
-	chainNameTokenAddrs := make(map[string]map[string]*TokenInfo)
-	chainNameTokenNames := make(map[string]map[string]*TokenInfo)
 	allTokens := make([]*TokenInfo, 0)
+	mark := newMarkBuilder(mgr.incremental.newMark())
 	counter := 0
+	scanErrors := 0
 
 	// Iterate over the result set
 	for rows.Next() {
-		var token TokenInfo
-
-		if err = rows.Scan(&token.TokenName, &token.ChainName, &token.TokenAddress, &token.Decimals, &token.Icon); err != nil {
+		token, updateTs, err := scanTokenInfo(rows)
+		if err != nil {
 			mgr.alerter.AlertText("scan t_token_info row error", err)
+			scanErrors++
 		} else {
-			token.ChainName = strings.TrimSpace(token.ChainName)
-			token.TokenAddress = strings.TrimSpace(token.TokenAddress)
-			token.TokenName = strings.TrimSpace(token.TokenName)
-			token.Icon = strings.TrimSpace(token.Icon)
-
-			tokenAddrs, ok := chainNameTokenAddrs[strings.ToLower(token.ChainName)]
-			if !ok {
-				tokenAddrs = make(map[string]*TokenInfo)
-				chainNameTokenAddrs[strings.ToLower(token.ChainName)] = tokenAddrs
-			}
-			tokenAddrs[strings.ToLower(token.TokenAddress)] = &token
-
-			tokenNames, ok := chainNameTokenNames[strings.ToLower(token.ChainName)]
-			if !ok {
-				tokenNames = make(map[string]*TokenInfo)
-				chainNameTokenNames[strings.ToLower(token.ChainName)] = tokenNames
-			}
-			tokenNames[strings.ToLower(token.TokenName)] = &token
-			allTokens = append(allTokens, &token)
+			mark.apply(strconv.FormatInt(token.ID, 10), updateTs, rowVersion(token))
+			allTokens = append(allTokens, token)
 			counter++
 		}
 	}
@@ -168,6 +392,11 @@ func (mgr *TokenInfoManager) LoadAllToken(chainManager *ChainInfoManager) {
 		return
 	}
 
+	if err := mgr.snapshotState.allowReplace(len(mgr.GetAllTokens()), counter, scanErrors); err != nil {
+		mgr.alerter.AlertText("t_token_info load rejected, keep current tokens", err)
+		return
+	}
+
 	allIDs := chainManager.GetChainInfoAutoIds()
 	for _, id := range allIDs {
 		chainInfo, ok := chainManager.GetChainInfoById(id)
@@ -180,28 +409,25 @@ func (mgr *TokenInfoManager) LoadAllToken(chainManager *ChainInfoManager) {
 		token.TokenName = chainInfo.GasTokenName
 		token.Decimals = chainInfo.GasTokenDecimal
 		token.Icon = chainInfo.GasTokenIcon
+		allTokens = append(allTokens, &token)
+	}
 
-		tokenAddrs, ok := chainNameTokenAddrs[strings.ToLower(token.ChainName)]
-		if !ok {
-			tokenAddrs = make(map[string]*TokenInfo)
-			chainNameTokenAddrs[strings.ToLower(token.ChainName)] = tokenAddrs
-		}
-		tokenNames, ok := chainNameTokenNames[strings.ToLower(token.ChainName)]
-		if !ok {
-			tokenNames = make(map[string]*TokenInfo)
-			chainNameTokenNames[strings.ToLower(token.ChainName)] = tokenNames
-		}
-		_, ok = chainNameTokenNames[strings.ToLower(token.ChainName)][strings.ToLower(token.TokenName)]
-		if !ok {
-			tokenNames[strings.ToLower(token.TokenName)] = &token
-			tokenAddrs[strings.ToLower(token.TokenAddress)] = &token
-			allTokens = append(allTokens, &token)
-		}
+	oldTokenAddrs := mgr.setTokens(allTokens)
+	if err = saveSnapshot(mgr.snapshotState, mgr.GetAllTokens()); err != nil {
+		mgr.alerter.AlertText("save t_token_info snapshot error", err)
 	}
 
-	mgr.mutex.Lock()
-	mgr.chainNameTokenAddrs = chainNameTokenAddrs
-	mgr.chainNameTokenNames = chainNameTokenNames
-	mgr.allTokens = allTokens
-	mgr.mutex.Unlock()
+	mgr.incremental.mark = mark.build()
+	mgr.incremental.lastFullLoad = now
+	mgr.notify(diffChanges("t_token_info", flattenTokens(oldTokenAddrs), flattenTokens(mgr.getTokenAddrs()), sameTokenInfo))
+}
+
+func flattenTokens(chainNameTokenAddrs map[string]map[string]*TokenInfo) map[string]*TokenInfo {
+	tokens := make(map[string]*TokenInfo)
+	for chainName, tokenAddrs := range chainNameTokenAddrs {
+		for tokenAddr, token := range tokenAddrs {
+			tokens[TokenKey(chainName, tokenAddr)] = token
+		}
+	}
+	return tokens
 }
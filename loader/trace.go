@@ -0,0 +1,18 @@
+package loader
+
+import (
+	"context"
+
+	"github.com/realcaishen/utils-go/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("loader")
+
+// startReload opens the span of one full load of table. Loads report errors through
+// the alerter rather than returning them, so the span only carries timing.
+func startReload(table string) trace.Span {
+	_, span := tracer.Start(context.Background(), "loader.reload", trace.WithAttributes(attribute.String("db.sql.table", table)))
+	return span
+}
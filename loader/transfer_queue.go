@@ -0,0 +1,403 @@
+package loader
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-metrics"
+	"github.com/realcaishen/utils-go/alert"
+	"github.com/realcaishen/utils-go/dal/model"
+	"github.com/realcaishen/utils-go/telemetry"
+)
+
+type Transfer = model.TTransfer
+
+const (
+	DefaultTransferBatchSize    = 50
+	DefaultTransferConcurrency  = 8
+	DefaultTransferPollInterval = 2 * time.Second
+	DefaultTransferClaimTimeout = 5 * time.Minute
+	DefaultTransferRetryDelay   = 10 * time.Second
+	DefaultTransferMaxDelay     = 10 * time.Minute
+	// maxTransferReason is the size of t_transfer.reason
+	maxTransferReason = 255
+)
+
+// TransferInvalidError marks a transfer that must never be retried.
+type TransferInvalidError struct {
+	Reason string
+}
+
+func (e *TransferInvalidError) Error() string {
+	return "invalid transfer: " + e.Reason
+}
+
+// InvalidTransfer is returned by a TransferHandler to reject a transfer for good.
+func InvalidTransfer(reason string) error {
+	return &TransferInvalidError{Reason: reason}
+}
+
+// TransferHandler processes one claimed transfer. Returning nil marks it processed and
+// stores transfer.Hash; an InvalidTransfer error marks it invalid with the reason; any
+// other error records the error as reason and retries the transfer after a growing
+// delay. A handler may see the same transfer again if its worker dies or the claim
+// times out before the outcome is recorded, so it must be idempotent.
+type TransferHandler func(ctx context.Context, transfer *Transfer) error
+
+// TransferQueueStats describes the pending part of the queue.
+type TransferQueueStats struct {
+	Depth     int64
+	OldestAge time.Duration
+}
+
+// TransferQueue drives t_transfer as a work queue. Rows are enqueued once per
+// (source_table, source_item_id) and claimed in batches with FOR UPDATE SKIP LOCKED so
+// any number of workers can share the table. A claim only counts the attempt and pushes
+// next_retry_time out by the claim timeout before committing; handlers run outside any
+// transaction and every outcome is written on its own. While handlers run the claim is
+// renewed every third of the claim timeout, so only rows of a worker that dies mid
+// batch are picked up again once their claim times out.
+type TransferQueue struct {
+	db           *sql.DB
+	alerter      alert.Alerter
+	batchSize    int
+	concurrency  int
+	pollInterval time.Duration
+	claimTimeout time.Duration
+	retryDelay   time.Duration
+	maxDelay     time.Duration
+}
+
+func NewTransferQueue(db *sql.DB, alerter alert.Alerter) *TransferQueue {
+	return &TransferQueue{
+		db:           db,
+		alerter:      alerter,
+		batchSize:    DefaultTransferBatchSize,
+		concurrency:  DefaultTransferConcurrency,
+		pollInterval: DefaultTransferPollInterval,
+		claimTimeout: DefaultTransferClaimTimeout,
+		retryDelay:   DefaultTransferRetryDelay,
+		maxDelay:     DefaultTransferMaxDelay,
+	}
+}
+
+// SetBatchSize limits how many transfers one claim takes.
+func (q *TransferQueue) SetBatchSize(size int) {
+	q.batchSize = size
+}
+
+// SetConcurrency limits how many transfers of a batch are handled at the same time.
+func (q *TransferQueue) SetConcurrency(concurrency int) {
+	q.concurrency = concurrency
+}
+
+// SetPollInterval changes how long Run waits when the queue is drained.
+func (q *TransferQueue) SetPollInterval(interval time.Duration) {
+	q.pollInterval = interval
+}
+
+// SetClaimTimeout changes how long a claimed transfer stays hidden from other workers
+// without its claim being renewed.
+func (q *TransferQueue) SetClaimTimeout(timeout time.Duration) {
+	q.claimTimeout = timeout
+}
+
+// SetRetryDelay changes how long a failed transfer waits before its next attempt: delay
+// after the first failure, doubling with every further one up to maxDelay.
+func (q *TransferQueue) SetRetryDelay(delay time.Duration, maxDelay time.Duration) {
+	q.retryDelay = delay
+	q.maxDelay = maxDelay
+}
+
+const transferColumns = "id, chainid, from_address, to_address, value, is_processed, is_invalid, hash, source_table, source_item_id, token_address, UNIX_TIMESTAMP(insert_timestamp), attempts"
+
+func scanTransfer(rows *sql.Rows) (*Transfer, int32, error) {
+	var t Transfer
+	var insertTs int64
+	var attempts int32
+	if err := rows.Scan(&t.ID, &t.Chainid, &t.FromAddress, &t.ToAddress, &t.Value, &t.IsProcessed, &t.IsInvalid, &t.Hash,
+		&t.SourceTable, &t.SourceItemID, &t.TokenAddress, &insertTs, &attempts); err != nil {
+		return nil, 0, err
+	}
+	t.InsertTimestamp = time.Unix(insertTs, 0)
+	return &t, attempts, nil
+}
+
+// Enqueue adds a transfer unless one already exists for its source row, and returns the
+// id of the queued row and whether it was created by this call. Pass a *sql.Tx as db to
+// enqueue atomically with the source row.
+func (q *TransferQueue) Enqueue(ctx context.Context, db DBTX, t *Transfer) (int64, bool, error) {
+	t.FromAddress = strings.TrimSpace(t.FromAddress)
+	t.ToAddress = strings.TrimSpace(t.ToAddress)
+	t.TokenAddress = strings.TrimSpace(t.TokenAddress)
+	t.SourceTable = strings.TrimSpace(t.SourceTable)
+
+	// id = LAST_INSERT_ID(id) turns a duplicate into a no-op that still reports its id
+	result, err := db.ExecContext(ctx, "INSERT INTO t_transfer (chainid, from_address, to_address, value, token_address, source_table, source_item_id) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)",
+		t.Chainid, t.FromAddress, t.ToAddress, t.Value, t.TokenAddress, t.SourceTable, t.SourceItemID)
+	if err != nil {
+		return 0, false, repoError(err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, false, err
+	}
+	t.ID = id
+	return id, affected == 1, nil
+}
+
+type transferResult struct {
+	transfer *Transfer
+	err      error
+}
+
+// ProcessBatch claims up to the batch size of due transfers, runs handler on them with
+// bounded concurrency and records every outcome. It returns how many transfers were
+// claimed.
+func (q *TransferQueue) ProcessBatch(ctx context.Context, handler TransferHandler) (int, error) {
+	transfers, attempts, err := q.claim(ctx)
+	if err != nil {
+		q.alerter.AlertText("claim t_transfer batch error", err)
+		return 0, err
+	}
+	if len(transfers) == 0 {
+		return 0, nil
+	}
+
+	stop := q.heartbeat(ctx, attempts)
+	results := q.handle(ctx, transfers, handler)
+	stop()
+	for _, r := range results {
+		if err = q.finish(ctx, r, attempts[r.transfer.ID]); err != nil {
+			// the claim times out and the transfer is handled again
+			q.alerter.AlertText(fmt.Sprintf("finish t_transfer %v error", r.transfer.ID), err)
+		}
+	}
+	return len(transfers), nil
+}
+
+// claim takes due transfers, oldest retry first, and marks them in a short transaction:
+// attempts goes up by one and next_retry_time moves to the end of the claim, hiding them
+// from other workers. It returns the transfers and their attempts by id.
+func (q *TransferQueue) claim(ctx context.Context) ([]*Transfer, map[int64]int32, error) {
+	now := time.Now()
+	transfers := make([]*Transfer, 0, q.batchSize)
+	attempts := make(map[int64]int32, q.batchSize)
+	err := WithTx(ctx, q.db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, "SELECT "+transferColumns+" FROM t_transfer WHERE is_processed = 0 AND is_invalid = 0 AND next_retry_time <= ? "+
+			"ORDER BY next_retry_time, id LIMIT ? FOR UPDATE SKIP LOCKED", now.Unix(), q.batchSize)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			t, n, err := scanTransfer(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			transfers = append(transfers, t)
+			attempts[t.ID] = n + 1
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		if len(transfers) == 0 {
+			return nil
+		}
+
+		args := make([]interface{}, 0, len(transfers)+1)
+		args = append(args, now.Add(q.claimTimeout).Unix())
+		for _, t := range transfers {
+			args = append(args, t.ID)
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE t_transfer SET attempts = attempts + 1, next_retry_time = ? WHERE id IN (?"+strings.Repeat(", ?", len(transfers)-1)+")", args...)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return transfers, attempts, nil
+}
+
+// heartbeat renews the claim of the transfers of a batch every third of the claim
+// timeout until the returned stop is called, so a slow batch is not claimed again by
+// another worker. Stop waits for a renewal in flight.
+func (q *TransferQueue) heartbeat(ctx context.Context, attempts map[int64]int32) (stop func()) {
+	interval := q.claimTimeout / 3
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := q.renew(ctx, attempts); err != nil {
+					logger.CtxWarnf(ctx, "renew t_transfer claim error: %v", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// renew pushes next_retry_time of the claimed transfers out by the claim timeout again,
+// skipping rows that were claimed by another worker in the meantime.
+func (q *TransferQueue) renew(ctx context.Context, attempts map[int64]int32) error {
+	if len(attempts) == 0 {
+		return nil
+	}
+	args := make([]interface{}, 0, 2*len(attempts)+1)
+	args = append(args, time.Now().Add(q.claimTimeout).Unix())
+	for id, n := range attempts {
+		args = append(args, id, n)
+	}
+	_, err := q.db.ExecContext(ctx, "UPDATE t_transfer SET next_retry_time = ? WHERE is_processed = 0 AND is_invalid = 0 AND ((id = ? AND attempts = ?)"+
+		strings.Repeat(" OR (id = ? AND attempts = ?)", len(attempts)-1)+")", args...)
+	return err
+}
+
+func (q *TransferQueue) handle(ctx context.Context, transfers []*Transfer, handler TransferHandler) []transferResult {
+	concurrency := q.concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	results := make([]transferResult, len(transfers))
+	sem := make(chan struct{}, concurrency)
+	wg := &sync.WaitGroup{}
+	for i, t := range transfers {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, t *Transfer) {
+			defer func() {
+				if r := recover(); r != nil {
+					results[i] = transferResult{transfer: t, err: errors.New("handler panic")}
+					logger.CtxErrorf(ctx, "transfer %v handler panic: %v", t.ID, r)
+				}
+				<-sem
+				wg.Done()
+			}()
+			start := time.Now()
+			err := handler(ctx, t)
+			telemetry.MeasureSince(start, "transfer_queue", "handle")
+			results[i] = transferResult{transfer: t, err: err}
+		}(i, t)
+	}
+	wg.Wait()
+	return results
+}
+
+func truncateReason(reason string) string {
+	if runes := []rune(reason); len(runes) > maxTransferReason {
+		return string(runes[:maxTransferReason])
+	}
+	return reason
+}
+
+// retryAfter is the delay before the next attempt of a transfer that failed attempts
+// times.
+func (q *TransferQueue) retryAfter(attempts int32) time.Duration {
+	delay := q.retryDelay
+	for i := int32(1); i < attempts && delay < q.maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, q.maxDelay)
+}
+
+// finish records the outcome of a transfer claimed at attempts. A processed outcome is
+// always recorded unless the transfer is already processed, since its hash is the only
+// trace of a payout. An invalid or failed outcome that finds the transfer claimed again,
+// because this batch outlived its claim, changes nothing.
+func (q *TransferQueue) finish(ctx context.Context, r transferResult, attempts int32) error {
+	var invalid *TransferInvalidError
+	var result sql.Result
+	var err error
+	var outcome string
+	switch {
+	case r.err == nil:
+		outcome = "processed"
+		result, err = q.db.ExecContext(ctx, "UPDATE t_transfer SET is_processed = 1, hash = ?, reason = '' WHERE id = ? AND is_processed = 0",
+			strings.TrimSpace(r.transfer.Hash), r.transfer.ID)
+	case errors.As(r.err, &invalid):
+		outcome = "invalid"
+		result, err = q.db.ExecContext(ctx, "UPDATE t_transfer SET is_invalid = 1, reason = ? WHERE id = ? AND is_processed = 0 AND attempts = ?",
+			truncateReason(invalid.Reason), r.transfer.ID, attempts)
+	default:
+		outcome = "failed"
+		delay := q.retryAfter(attempts)
+		logger.CtxErrorf(ctx, "transfer %v failed at attempt %v, retry in %v: %v", r.transfer.ID, attempts, delay, r.err)
+		result, err = q.db.ExecContext(ctx, "UPDATE t_transfer SET reason = ?, next_retry_time = ? WHERE id = ? AND is_processed = 0 AND attempts = ?",
+			truncateReason(r.err.Error()), time.Now().Add(delay).Unix(), r.transfer.ID, attempts)
+	}
+	if err != nil {
+		return err
+	}
+	telemetry.IncrCounterWithLabels([]string{"transfer_queue", "finished"}, 1, []metrics.Label{telemetry.NewLabel("outcome", outcome)})
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		logger.CtxWarnf(ctx, "transfer %v was claimed again or processed before its %v outcome was recorded", r.transfer.ID, outcome)
+	}
+	return nil
+}
+
+// Stats returns the number of pending transfers and the age of the oldest one, measured
+// by the database clock.
+func (q *TransferQueue) Stats(ctx context.Context) (*TransferQueueStats, error) {
+	var depth, ageSeconds int64
+	err := q.db.QueryRowContext(ctx, "SELECT COUNT(*), IFNULL(UNIX_TIMESTAMP() - UNIX_TIMESTAMP(MIN(insert_timestamp)), 0) "+
+		"FROM t_transfer WHERE is_processed = 0 AND is_invalid = 0").Scan(&depth, &ageSeconds)
+	if err != nil {
+		return nil, err
+	}
+	return &TransferQueueStats{Depth: depth, OldestAge: time.Duration(ageSeconds) * time.Second}, nil
+}
+
+// ReportMetrics publishes Stats as the transfer_queue depth and oldest_age_seconds gauges.
+func (q *TransferQueue) ReportMetrics(ctx context.Context) {
+	stats, err := q.Stats(ctx)
+	if err != nil {
+		logger.CtxErrorf(ctx, "get t_transfer queue stats error: %v", err)
+		return
+	}
+	telemetry.SetGauge(float32(stats.Depth), "transfer_queue", "depth")
+	telemetry.SetGauge(float32(stats.OldestAge.Seconds()), "transfer_queue", "oldest_age_seconds")
+}
+
+// Run processes batches until ctx is done, waiting for the poll interval whenever the
+// queue is drained, and reports metrics at most once per poll interval.
+func (q *TransferQueue) Run(ctx context.Context, handler TransferHandler) {
+	var lastReport time.Time
+	for {
+		if time.Since(lastReport) >= q.pollInterval {
+			q.ReportMetrics(ctx)
+			lastReport = time.Now()
+		}
+		claimed, err := q.ProcessBatch(ctx, handler)
+		if err == nil && claimed >= q.batchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(q.pollInterval):
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package loader
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferQueueHandle(t *testing.T) {
+	q := NewTransferQueue(nil, nil)
+	q.SetConcurrency(2)
+
+	transfers := make([]*Transfer, 0)
+	for i := int64(1); i <= 6; i++ {
+		transfers = append(transfers, &Transfer{ID: i})
+	}
+
+	var running, maxRunning atomic.Int32
+	results := q.handle(context.Background(), transfers, func(ctx context.Context, transfer *Transfer) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		switch transfer.ID {
+		case 2:
+			return InvalidTransfer("bad recipient")
+		case 3:
+			return errors.New("rpc timeout")
+		case 4:
+			panic("boom")
+		}
+		transfer.Hash = "0xabc"
+		return nil
+	})
+
+	assert.LessOrEqual(t, maxRunning.Load(), int32(2))
+	if assert.Len(t, results, 6) {
+		for i, r := range results {
+			assert.Equal(t, int64(i+1), r.transfer.ID)
+		}
+		assert.NoError(t, results[0].err)
+		assert.Equal(t, "0xabc", results[0].transfer.Hash)
+
+		var invalid *TransferInvalidError
+		assert.ErrorAs(t, results[1].err, &invalid)
+		assert.Equal(t, "bad recipient", invalid.Reason)
+		assert.False(t, errors.As(results[2].err, &invalid))
+		assert.Error(t, results[3].err)
+	}
+}
+
+func TestTruncateReason(t *testing.T) {
+	assert.Equal(t, "short", truncateReason("short"))
+	long := strings.Repeat("é", maxTransferReason+10)
+	assert.Equal(t, maxTransferReason, len([]rune(truncateReason(long))))
+}
+
+func TestTransferRetryAfter(t *testing.T) {
+	q := NewTransferQueue(nil, nil)
+	q.SetRetryDelay(10*time.Second, time.Minute)
+	assert.Equal(t, 10*time.Second, q.retryAfter(1))
+	assert.Equal(t, 20*time.Second, q.retryAfter(2))
+	assert.Equal(t, 40*time.Second, q.retryAfter(3))
+	assert.Equal(t, time.Minute, q.retryAfter(4))
+	assert.Equal(t, time.Minute, q.retryAfter(1000))
+}
+
+func TestTransferQueueFinish(t *testing.T) {
+	db, fake := newFakeDB()
+	q := NewTransferQueue(db, nil)
+	ctx := context.Background()
+
+	// the processed outcome wins even when the transfer was claimed again meanwhile
+	err := q.finish(ctx, transferResult{transfer: &Transfer{ID: 7, Hash: " 0xabc "}}, 1)
+	assert.NoError(t, err)
+	statements, args := fake.matching("is_processed = 1")
+	if assert.Len(t, statements, 1) {
+		assert.Contains(t, statements[0], "WHERE id = ? AND is_processed = 0")
+		assert.NotContains(t, statements[0], "attempts")
+		assert.Equal(t, []driver.Value{"0xabc", int64(7)}, args[0])
+	}
+
+	err = q.finish(ctx, transferResult{transfer: &Transfer{ID: 8}, err: InvalidTransfer("bad")}, 2)
+	assert.NoError(t, err)
+	statements, args = fake.matching("is_invalid = 1")
+	if assert.Len(t, statements, 1) {
+		assert.Contains(t, statements[0], "is_processed = 0 AND attempts = ?")
+		assert.Equal(t, []driver.Value{"bad", int64(8), int64(2)}, args[0])
+	}
+
+	err = q.finish(ctx, transferResult{transfer: &Transfer{ID: 9}, err: errors.New("rpc timeout")}, 3)
+	assert.NoError(t, err)
+	statements, _ = fake.matching("next_retry_time = ? WHERE id = ?")
+	if assert.Len(t, statements, 1) {
+		assert.Contains(t, statements[0], "is_processed = 0 AND attempts = ?")
+	}
+}
+
+func TestTransferQueueHeartbeat(t *testing.T) {
+	db, fake := newFakeDB()
+	q := NewTransferQueue(db, nil)
+	q.SetClaimTimeout(30 * time.Millisecond)
+
+	stop := q.heartbeat(context.Background(), map[int64]int32{7: 2})
+	time.Sleep(50 * time.Millisecond)
+	stop()
+	statements, args := fake.matching("SET next_retry_time = ?")
+	if assert.NotEmpty(t, statements) {
+		assert.Contains(t, statements[0], "(id = ? AND attempts = ?)")
+		assert.Equal(t, []driver.Value{int64(7), int64(2)}, args[0][1:])
+	}
+
+	// no renewal after stop, so the outcome of the batch is not overwritten
+	count := len(statements)
+	time.Sleep(30 * time.Millisecond)
+	statements, _ = fake.matching("SET next_retry_time = ?")
+	assert.Len(t, statements, count)
+}
@@ -20,6 +20,8 @@ type UpdatePriceManager struct {
 	db      *sql.DB
 	alerter alert.Alerter
 	mutex   *sync.RWMutex
+
+	*readRouter
 }
 
 func NewUpdatePriceManager(db *sql.DB, alerter alert.Alerter) *UpdatePriceManager {
@@ -29,6 +31,8 @@ func NewUpdatePriceManager(db *sql.DB, alerter alert.Alerter) *UpdatePriceManage
 		db:      db,
 		alerter: alerter,
 		mutex:   &sync.RWMutex{},
+
+		readRouter: newReadRouter(db),
 	}
 }
 
@@ -40,9 +44,13 @@ func (mgr *UpdatePriceManager) GetUpdatePrice(tokenName string) (*UpdatePrice, b
 	return info, ok
 }
 
+const updatePriceColumns = "token, price, update_timestamp"
+
 func (mgr *UpdatePriceManager) LoadAllPrice() {
+	span := startReload("t_update_price")
+	defer span.End()
 	// Query the database to select only id and name fields
-	rows, err := mgr.db.Query("SELECT token, price, update_timestamp FROM t_update_price")
+	rows, err := mgr.scanDB().Query("SELECT " + updatePriceColumns + " FROM t_update_price")
 	if err != nil || rows == nil {
 		mgr.alerter.AlertText("select t_update error", err)
 		return
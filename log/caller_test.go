@@ -0,0 +1,43 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/realcaishen/utils-go/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func line() string {
+	_, _, line, _ := runtime.Caller(1)
+	return fmt.Sprintf("caller_test.go:%d", line)
+}
+
+// The caller is resolved by package rather than stack depth, so every entry point
+// reports this file, however many wrappers it goes through.
+func TestCaller(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := log.New(log.Config{Output: log.OutputStdout, Stdout: &buf})
+	require.NoError(t, err)
+	prev := log.Default()
+	log.SetDefault(l)
+	defer log.SetDefault(prev)
+	ctx := context.Background()
+
+	calls := []func() string{
+		func() string { log.Info("a"); return line() },
+		func() string { log.CtxWarnf(ctx, "b"); return line() },
+		func() string { log.CtxInfow(ctx, "c", "k", 1); return line() },
+		func() string { l.Errorf("d"); return line() },
+		func() string { log.With("k", 2).Info("e"); return line() },
+	}
+	for _, call := range calls {
+		buf.Reset()
+		want := call()
+		assert.Contains(t, buf.String(), " "+want+" ")
+	}
+}
@@ -0,0 +1,227 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/realcaishen/utils-go/redact"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Output selects where a Logger writes.
+type Output string
+
+const (
+	OutputStdout Output = "stdout"
+	OutputFile   Output = "file"
+	OutputBoth   Output = "both"
+)
+
+// Format selects how entries are rendered.
+type Format string
+
+const (
+	// FormatText is a human readable line followed by key=value fields.
+	FormatText Format = "text"
+	// FormatJSON is one object per line for log pipelines.
+	FormatJSON Format = "json"
+)
+
+// Config describes a Logger. Zero fields take the values of DefaultConfig, except the
+// booleans and TimeZone, where empty means the local zone.
+type Config struct {
+	// Level is a logrus level name such as "debug" or "info".
+	Level  string `mapstructure:"level"`
+	Output Output `mapstructure:"output"`
+	Format Format `mapstructure:"format"`
+	// Colors enables ANSI level colors on stdout; files are never colored.
+	Colors bool `mapstructure:"colors"`
+	// TimeZone is an IANA name such as "Asia/Shanghai".
+	TimeZone string `mapstructure:"time_zone"`
+	// DisableRedaction turns off masking of private keys, tokens and URL credentials,
+	// see redact.Redactor.
+	DisableRedaction bool `mapstructure:"disable_redaction"`
+	// MaskAddresses also shortens EVM addresses in every line.
+	MaskAddresses bool `mapstructure:"mask_addresses"`
+	// MaskHexKeys also masks bare 64 digit hex values, at the cost of Bitcoin and Tron
+	// txids.
+	MaskHexKeys bool `mapstructure:"mask_hex_keys"`
+
+	// Dir defaults to ~/logs/<executable name>.
+	Dir        string `mapstructure:"dir"`
+	FileName   string `mapstructure:"file_name"`
+	MaxSizeMB  int    `mapstructure:"max_size_mb"`
+	MaxBackups int    `mapstructure:"max_backups"`
+	MaxAgeDays int    `mapstructure:"max_age_days"`
+	Compress   bool   `mapstructure:"compress"`
+
+	// Stdout replaces os.Stdout, mostly for tests.
+	Stdout io.Writer `mapstructure:"-"`
+}
+
+// DefaultConfig is what the package level functions use until SetDefault is called:
+// colored stdout plus a rotated ~/logs/<exe>/app.log, in Beijing time.
+func DefaultConfig() Config {
+	return Config{
+		Level:      "info",
+		Output:     OutputBoth,
+		Format:     FormatText,
+		Colors:     true,
+		TimeZone:   "Asia/Shanghai",
+		FileName:   "app.log",
+		MaxSizeMB:  500,
+		MaxBackups: 5,
+		MaxAgeDays: 28,
+	}
+}
+
+func (c Config) withDefaults() Config {
+	def := DefaultConfig()
+	if c.Level == "" {
+		c.Level = def.Level
+	}
+	if c.Output == "" {
+		c.Output = def.Output
+	}
+	if c.Format == "" {
+		c.Format = def.Format
+	}
+	if c.FileName == "" {
+		c.FileName = def.FileName
+	}
+	if c.MaxSizeMB <= 0 {
+		c.MaxSizeMB = def.MaxSizeMB
+	}
+	if c.MaxBackups <= 0 {
+		c.MaxBackups = def.MaxBackups
+	}
+	if c.MaxAgeDays <= 0 {
+		c.MaxAgeDays = def.MaxAgeDays
+	}
+	if c.Stdout == nil {
+		c.Stdout = os.Stdout
+	}
+	return c
+}
+
+// New builds a Logger from cfg. It creates the log directory when the output includes
+// a file and fails instead of panicking when that is impossible.
+func New(cfg Config) (*Logger, error) {
+	cfg = cfg.withDefaults()
+	level, err := logrus.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	var redactor *redact.Redactor
+	if !cfg.DisableRedaction {
+		redactor = redact.New(redact.Options{MaskAddresses: cfg.MaskAddresses, MaskHexKeys: cfg.MaskHexKeys})
+	}
+	formatter, err := newFormatter(cfg.Format, false, redactor)
+	if err != nil {
+		return nil, err
+	}
+	stdoutFormatter, _ := newFormatter(cfg.Format, cfg.Colors, redactor)
+
+	l := logrus.New()
+	l.SetLevel(level)
+	l.AddHook(&callerHook{})
+	if cfg.TimeZone != "" {
+		loc, err := time.LoadLocation(cfg.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+		l.AddHook(&locationHook{location: loc})
+	}
+
+	var file *lumberjack.Logger
+	if cfg.Output == OutputFile || cfg.Output == OutputBoth {
+		file, err = openFile(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch cfg.Output {
+	case OutputStdout:
+		l.SetOutput(cfg.Stdout)
+		l.SetFormatter(stdoutFormatter)
+	case OutputFile:
+		l.SetOutput(file)
+		l.SetFormatter(formatter)
+	case OutputBoth:
+		l.SetOutput(cfg.Stdout)
+		l.SetFormatter(stdoutFormatter)
+		l.AddHook(&Hook{Writer: file, Formatter: formatter, LogLevels: logrus.AllLevels})
+	default:
+		return nil, fmt.Errorf("unknown log output %q", cfg.Output)
+	}
+
+	logger := &Logger{logger: l}
+	if file != nil {
+		logger.closer = file
+	}
+	return logger, nil
+}
+
+func openFile(cfg Config) (*lumberjack.Logger, error) {
+	dir := cfg.Dir
+	if dir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("get user home directory: %w", err)
+		}
+		dir = filepath.Join(homeDir, "logs", getProjectName())
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("create log directory: %w", err)
+	}
+	return &lumberjack.Logger{
+		Filename:   filepath.Join(dir, cfg.FileName),
+		MaxSize:    cfg.MaxSizeMB,
+		MaxBackups: cfg.MaxBackups,
+		MaxAge:     cfg.MaxAgeDays,
+		Compress:   cfg.Compress,
+	}, nil
+}
+
+func newFormatter(format Format, colors bool, redactor *redact.Redactor) (logrus.Formatter, error) {
+	switch format {
+	case FormatText:
+		return &CustomFormatter{EnableColors: colors, Redactor: redactor}, nil
+	case FormatJSON:
+		return &JSONFormatter{Redactor: redactor}, nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+func getProjectName() string {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "app"
+	}
+	projectName := filepath.Base(exePath)
+	if runtime.GOOS == "windows" {
+		projectName = strings.TrimSuffix(projectName, ".exe")
+	}
+	return projectName
+}
+
+// locationHook moves entry times into a zone loaded once, unlike TimezoneHook which
+// looks the zone up on every entry.
+type locationHook struct {
+	location *time.Location
+}
+
+func (hook *locationHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (hook *locationHook) Fire(entry *logrus.Entry) error {
+	entry.Time = entry.Time.In(hook.location)
+	return nil
+}
@@ -0,0 +1,126 @@
+package log
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Field names the log pipeline indexes. Use them with the *w helpers so the same data
+// always lands under the same key.
+const (
+	FieldChain   = "chain"
+	FieldTxHash  = "tx_hash"
+	FieldOrderID = "order_id"
+	FieldError   = "error"
+)
+
+// Fields turns alternating keys and values into logrus fields. A key that is not a
+// string is formatted with fmt.Sprint, and a trailing key without a value gets
+// "!MISSING" so the mistake shows up in the output.
+func Fields(keysAndValues ...interface{}) logrus.Fields {
+	fields := make(logrus.Fields, len(keysAndValues)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		if i+1 < len(keysAndValues) {
+			fields[key] = keysAndValues[i+1]
+		} else {
+			fields[key] = "!MISSING"
+		}
+	}
+	return fields
+}
+
+// With returns an entry carrying the given key value pairs.
+func (l *Logger) With(keysAndValues ...interface{}) *logrus.Entry {
+	return l.entry().WithFields(Fields(keysAndValues...))
+}
+
+// CtxWith returns an entry carrying the logId of ctx and the given key value pairs.
+func (l *Logger) CtxWith(ctx context.Context, keysAndValues ...interface{}) *logrus.Entry {
+	return l.CtxWithFields(ctx).WithFields(Fields(keysAndValues...))
+}
+
+func (l *Logger) Infow(msg string, keysAndValues ...interface{}) {
+	l.With(keysAndValues...).Info(msg)
+}
+
+func (l *Logger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.With(keysAndValues...).Error(msg)
+}
+
+func (l *Logger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.With(keysAndValues...).Debug(msg)
+}
+
+func (l *Logger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.With(keysAndValues...).Warn(msg)
+}
+
+func (l *Logger) CtxInfow(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.CtxWith(ctx, keysAndValues...).Info(msg)
+}
+
+func (l *Logger) CtxErrorw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.CtxWith(ctx, keysAndValues...).Error(msg)
+}
+
+func (l *Logger) CtxDebugw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.CtxWith(ctx, keysAndValues...).Debug(msg)
+}
+
+func (l *Logger) CtxWarnw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.CtxWith(ctx, keysAndValues...).Warn(msg)
+}
+
+func With(keysAndValues ...interface{}) *logrus.Entry {
+	return Default().With(keysAndValues...)
+}
+
+func CtxWith(ctx context.Context, keysAndValues ...interface{}) *logrus.Entry {
+	return Default().CtxWith(ctx, keysAndValues...)
+}
+
+// Infow logs msg with key value fields, e.g. log.Infow("sent", log.FieldChain, name, log.FieldTxHash, hash)
+func Infow(msg string, keysAndValues ...interface{}) {
+	Default().Infow(msg, keysAndValues...)
+}
+
+// Errorw logs an error message with key value fields
+func Errorw(msg string, keysAndValues ...interface{}) {
+	Default().Errorw(msg, keysAndValues...)
+}
+
+// Debugw logs a debug message with key value fields
+func Debugw(msg string, keysAndValues ...interface{}) {
+	Default().Debugw(msg, keysAndValues...)
+}
+
+// Warnw logs a warning message with key value fields
+func Warnw(msg string, keysAndValues ...interface{}) {
+	Default().Warnw(msg, keysAndValues...)
+}
+
+// CtxInfow logs an info message with context and key value fields
+func CtxInfow(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	Default().CtxInfow(ctx, msg, keysAndValues...)
+}
+
+// CtxErrorw logs an error message with context and key value fields
+func CtxErrorw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	Default().CtxErrorw(ctx, msg, keysAndValues...)
+}
+
+// CtxDebugw logs a debug message with context and key value fields
+func CtxDebugw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	Default().CtxDebugw(ctx, msg, keysAndValues...)
+}
+
+// CtxWarnw logs a warning message with context and key value fields
+func CtxWarnw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	Default().CtxWarnw(ctx, msg, keysAndValues...)
+}
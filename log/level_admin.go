@@ -0,0 +1,87 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ConfigSource reads a raw config value. apollosdk.ApolloSDK and kvstore.Store both
+// satisfy it.
+type ConfigSource interface {
+	GetString(namespace, key string) (string, error)
+}
+
+// WatchLevels applies the level spec stored under namespace/key, see ParseLevels, and
+// checks it again every interval until ctx is done. Unchanged values are not applied
+// again and invalid ones are logged and ignored, keeping the last good levels.
+func WatchLevels(ctx context.Context, source ConfigSource, namespace string, key string, interval time.Duration) {
+	last := ""
+	for {
+		spec, err := source.GetString(namespace, key)
+		switch {
+		case err != nil:
+			Errorf("read log levels %v/%v error: %v", namespace, key, err)
+		case spec != last:
+			if err = SetLevels(spec); err != nil {
+				Errorf("apply log levels %v/%v error: %v", namespace, key, err)
+				break
+			}
+			last = spec
+			Infof("log levels set to %v", Levels())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// LevelHandler shows and changes log levels at runtime:
+//
+//	GET                                   levels of the default logger and every module
+//	POST ?level=<level>                   set the default level
+//	POST ?module=<name>&level=<level>     set a module level, or level=default to follow the default again
+//
+// Changes last until the next WatchLevels update or restart. Mount it on an internal port only.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(serveLevels)
+}
+
+func serveLevels(w http.ResponseWriter, r *http.Request) {
+	writeJSON := func(status int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost, http.MethodPut:
+		name := r.URL.Query().Get("module")
+		value := r.URL.Query().Get("level")
+		if name != "" && value == "default" {
+			Module(name).ResetLevel()
+			break
+		}
+		level, err := logrus.ParseLevel(value)
+		if err != nil {
+			writeJSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if name == "" {
+			Default().SetLevel(level)
+		} else {
+			Module(name).SetLevel(level)
+		}
+	default:
+		writeJSON(http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(http.StatusOK, Levels())
+}
@@ -0,0 +1,285 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Logger is a configured logrus logger with the logId aware helpers of this package.
+// Module loggers have a module set and write through the default logger.
+type Logger struct {
+	logger *logrus.Logger
+	closer io.Closer
+	module *module
+	// limits holds the DebugfEvery state per key
+	limits sync.Map
+}
+
+var (
+	defaultMutex  sync.Mutex
+	defaultLogger atomic.Pointer[Logger]
+)
+
+// Default returns the logger behind the package level functions. Unless SetDefault was
+// called first, it is built from DefaultConfig on first use; if the log directory
+// cannot be created it logs to stdout only rather than failing.
+func Default() *Logger {
+	if l := defaultLogger.Load(); l != nil {
+		return l
+	}
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	if l := defaultLogger.Load(); l != nil {
+		return l
+	}
+	l, err := New(DefaultConfig())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "log: %v, logging to stdout only\n", err)
+		cfg := DefaultConfig()
+		cfg.Output = OutputStdout
+		l, _ = New(cfg)
+	}
+	defaultLogger.Store(l)
+	return l
+}
+
+// SetDefault replaces the logger behind the package level functions. The previous one
+// is not closed.
+func SetDefault(l *Logger) {
+	defaultLogger.Store(l)
+}
+
+// Logrus exposes the underlying logger for hooks and integrations.
+func (l *Logger) Logrus() *logrus.Logger {
+	if l.module != nil {
+		return l.module.logger()
+	}
+	return l.logger
+}
+
+func (l *Logger) entry() *logrus.Entry {
+	if l.module != nil {
+		return l.module.logger().WithField("module", l.module.name)
+	}
+	return logrus.NewEntry(l.logger)
+}
+
+// SetLevel changes the level of this logger only; a module stops following the
+// default level until ResetLevel.
+func (l *Logger) SetLevel(level logrus.Level) {
+	if l.module != nil {
+		l.module.setLevel(level)
+		return
+	}
+	l.logger.SetLevel(level)
+}
+
+func (l *Logger) GetLevel() logrus.Level {
+	return l.Logrus().GetLevel()
+}
+
+// Close releases the log file, if any.
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// CtxWithFields returns an entry carrying the logId of ctx. When ctx holds a span, its
+// trace id is the logId and the trace and span ids are added as fields, so log lines
+// and traces can be joined.
+func (l *Logger) CtxWithFields(ctx context.Context) *logrus.Entry {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return l.entry().WithFields(logrus.Fields{
+			"logId":    sc.TraceID().String(),
+			"trace_id": sc.TraceID().String(),
+			"span_id":  sc.SpanID().String(),
+		})
+	}
+	requestId := ctx.Value("logId")
+	if requestId == nil {
+		requestId = "unknown"
+	}
+	return l.entry().WithField("logId", requestId)
+}
+
+func (l *Logger) Info(args ...interface{}) {
+	l.entry().Info(args...)
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.entry().Infof(format, args...)
+}
+
+func (l *Logger) Error(args ...interface{}) {
+	l.entry().Error(args...)
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.entry().Errorf(format, args...)
+}
+
+func (l *Logger) Debug(args ...interface{}) {
+	l.entry().Debug(args...)
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.entry().Debugf(format, args...)
+}
+
+func (l *Logger) Warn(args ...interface{}) {
+	l.entry().Warn(args...)
+}
+
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.entry().Warnf(format, args...)
+}
+
+func (l *Logger) Fatal(args ...interface{}) {
+	l.entry().Fatal(args...)
+}
+
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.entry().Fatalf(format, args...)
+}
+
+func (l *Logger) Panic(args ...interface{}) {
+	l.entry().Panic(args...)
+}
+
+func (l *Logger) Panicf(format string, args ...interface{}) {
+	l.entry().Panicf(format, args...)
+}
+
+func (l *Logger) CtxInfo(ctx context.Context, args ...interface{}) {
+	l.CtxWithFields(ctx).Info(args...)
+}
+
+func (l *Logger) CtxInfof(ctx context.Context, format string, args ...interface{}) {
+	l.CtxWithFields(ctx).Infof(format, args...)
+}
+
+func (l *Logger) CtxError(ctx context.Context, args ...interface{}) {
+	l.CtxWithFields(ctx).Error(args...)
+}
+
+func (l *Logger) CtxErrorf(ctx context.Context, format string, args ...interface{}) {
+	l.CtxWithFields(ctx).Errorf(format, args...)
+}
+
+func (l *Logger) CtxDebug(ctx context.Context, args ...interface{}) {
+	l.CtxWithFields(ctx).Debug(args...)
+}
+
+func (l *Logger) CtxDebugf(ctx context.Context, format string, args ...interface{}) {
+	l.CtxWithFields(ctx).Debugf(format, args...)
+}
+
+func (l *Logger) CtxWarn(ctx context.Context, args ...interface{}) {
+	l.CtxWithFields(ctx).Warn(args...)
+}
+
+func (l *Logger) CtxWarnf(ctx context.Context, format string, args ...interface{}) {
+	l.CtxWithFields(ctx).Warnf(format, args...)
+}
+
+func CtxWithFields(ctx context.Context) *logrus.Entry {
+	return Default().CtxWithFields(ctx)
+}
+
+// Wrappers for logrus functions
+func Info(args ...interface{}) {
+	Default().Info(args...)
+}
+
+func Infof(format string, args ...interface{}) {
+	Default().Infof(format, args...)
+}
+
+func Error(args ...interface{}) {
+	Default().Error(args...)
+}
+
+func Errorf(format string, args ...interface{}) {
+	Default().Errorf(format, args...)
+}
+
+func Debug(args ...interface{}) {
+	Default().Debug(args...)
+}
+
+func Debugf(format string, args ...interface{}) {
+	Default().Debugf(format, args...)
+}
+
+func Warn(args ...interface{}) {
+	Default().Warn(args...)
+}
+
+func Warnf(format string, args ...interface{}) {
+	Default().Warnf(format, args...)
+}
+
+func Fatal(args ...interface{}) {
+	Default().Fatal(args...)
+}
+
+func Fatalf(format string, args ...interface{}) {
+	Default().Fatalf(format, args...)
+}
+
+func Panic(args ...interface{}) {
+	Default().Panic(args...)
+}
+
+func Panicf(format string, args ...interface{}) {
+	Default().Panicf(format, args...)
+}
+
+// CtxInfo logs an info message with context fields
+func CtxInfo(ctx context.Context, args ...interface{}) {
+	Default().CtxInfo(ctx, args...)
+}
+
+// CtxInfof logs a formatted info message with context fields
+func CtxInfof(ctx context.Context, format string, args ...interface{}) {
+	Default().CtxInfof(ctx, format, args...)
+}
+
+// CtxError logs an error message with context fields
+func CtxError(ctx context.Context, args ...interface{}) {
+	Default().CtxError(ctx, args...)
+}
+
+// CtxErrorf logs a formatted error message with context fields
+func CtxErrorf(ctx context.Context, format string, args ...interface{}) {
+	Default().CtxErrorf(ctx, format, args...)
+}
+
+// CtxDebug logs a debug message with context fields
+func CtxDebug(ctx context.Context, args ...interface{}) {
+	Default().CtxDebug(ctx, args...)
+}
+
+// CtxDebugf logs a formatted debug message with context fields
+func CtxDebugf(ctx context.Context, format string, args ...interface{}) {
+	Default().CtxDebugf(ctx, format, args...)
+}
+
+// CtxWarn logs a warning message with context fields
+func CtxWarn(ctx context.Context, args ...interface{}) {
+	Default().CtxWarn(ctx, args...)
+}
+
+// CtxWarnf logs a formatted warning message with context fields
+func CtxWarnf(ctx context.Context, format string, args ...interface{}) {
+	Default().CtxWarnf(ctx, format, args...)
+}
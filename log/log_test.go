@@ -0,0 +1,135 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewStdout(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{Output: OutputStdout, Level: "warn", TimeZone: "UTC", Stdout: &buf})
+	require.NoError(t, err)
+
+	l.Info("dropped")
+	l.CtxWarnf(context.WithValue(context.Background(), "logId", "req-1"), "kept %v", 1)
+	out := buf.String()
+	assert.NotContains(t, out, "dropped")
+	assert.Contains(t, out, "req-1 [WARN] kept 1")
+	assert.NotContains(t, out, "\033[")
+	assert.NoError(t, l.Close())
+}
+
+func TestNewFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested")
+	var buf bytes.Buffer
+	l, err := New(Config{Output: OutputFile, Dir: dir, FileName: "test.log", Stdout: &buf})
+	require.NoError(t, err)
+	l.Infof("to file %v", 2)
+	require.NoError(t, l.Close())
+
+	data, err := os.ReadFile(filepath.Join(dir, "test.log"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(data), "[INFO] to file 2\n"))
+	assert.Zero(t, buf.Len())
+}
+
+func TestNewInvalid(t *testing.T) {
+	_, err := New(Config{Output: OutputStdout, Level: "loud"})
+	assert.Error(t, err)
+	_, err = New(Config{Output: OutputStdout, TimeZone: "Mars/Olympus"})
+	assert.Error(t, err)
+	_, err = New(Config{Output: "syslog"})
+	assert.Error(t, err)
+	_, err = New(Config{Output: OutputStdout, Format: "xml"})
+	assert.Error(t, err)
+}
+
+func TestSetDefault(t *testing.T) {
+	prev := defaultLogger.Load()
+	defer defaultLogger.Store(prev)
+
+	var buf bytes.Buffer
+	l, err := New(Config{Output: OutputStdout, Stdout: &buf})
+	require.NoError(t, err)
+	SetDefault(l)
+	Warnf("via default %v", 3)
+	assert.Contains(t, buf.String(), "[WARN] via default 3")
+}
+
+func TestTextFields(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{Output: OutputStdout, Stdout: &buf})
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), "logId", "req-2")
+	l.CtxInfow(ctx, "sent", FieldTxHash, "0xabc", FieldChain, "eth", "note", "two words", FieldError, errors.New("nonce too low"))
+	assert.Contains(t, buf.String(), `req-2 [INFO] sent chain=eth error="nonce too low" note="two words" tx_hash=0xabc`+"\n")
+}
+
+func TestJSONFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{Output: OutputStdout, Format: FormatJSON, Stdout: &buf})
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), "logId", "req-3")
+	l.CtxErrorw(ctx, "refund failed", FieldOrderID, 42, FieldError, errors.New("timeout"), "msg", "clash")
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, "refund failed", got["msg"])
+	assert.Equal(t, "error", got["level"])
+	assert.Equal(t, "req-3", got["logId"])
+	assert.Equal(t, float64(42), got["order_id"])
+	assert.Equal(t, "timeout", got["error"])
+	assert.Equal(t, "clash", got["fields.msg"])
+	assert.Contains(t, got, "caller")
+}
+
+func TestFields(t *testing.T) {
+	assert.Equal(t, map[string]interface{}{"a": 1, "2": "b", "c": "!MISSING"}, map[string]interface{}(Fields("a", 1, 2, "b", "c")))
+}
+
+func TestRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{Output: OutputStdout, Format: FormatJSON, MaskAddresses: true, Stdout: &buf})
+	require.NoError(t, err)
+	l.Errorw("call https://rpc.example.com/?apikey=abc failed", "to", "0x52908400098527886E0F7030069857D2E4169EE7", FieldError, errors.New("auth Bearer tok3n"))
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, "call https://rpc.example.com/?apikey=**** failed", got["msg"])
+	assert.Equal(t, "0x5290****9EE7", got["to"])
+	assert.Equal(t, "auth Bearer ****", got["error"])
+
+	buf.Reset()
+	l, err = New(Config{Output: OutputStdout, DisableRedaction: true, Stdout: &buf})
+	require.NoError(t, err)
+	l.Info("Bearer tok3n")
+	assert.Contains(t, buf.String(), "Bearer tok3n")
+}
+
+func TestCtxWithTrace(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Config{Output: OutputStdout, Format: FormatJSON, Stdout: &buf})
+	require.NoError(t, err)
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1, 2, 3},
+		SpanID:  trace.SpanID{4, 5, 6},
+	})
+	ctx := trace.ContextWithSpanContext(context.WithValue(context.Background(), "logId", "req-4"), sc)
+	l.CtxInfo(ctx, "traced")
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, sc.TraceID().String(), got["logId"])
+	assert.Equal(t, sc.TraceID().String(), got["trace_id"])
+	assert.Equal(t, sc.SpanID().String(), got["span_id"])
+}
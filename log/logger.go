@@ -1,80 +1,21 @@
 package log
 
 import (
-	"context"
+	"encoding/json"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/realcaishen/utils-go/redact"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-var log *logrus.Logger
-
-func init() {
-	log = logrus.New()
-
-	// Get project name
-	projectName := getProjectName()
-
-	// Get user's home directory
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		panic("Failed to get user home directory: " + err.Error())
-	}
-
-	// Create project log directory
-	logDir := filepath.Join(homeDir, "logs", projectName)
-	if err = os.MkdirAll(logDir, os.ModePerm); err != nil {
-		panic("Failed to create log directory: " + err.Error())
-	}
-
-	logFilePath := filepath.Join(logDir, "app.log")
-
-	// Configure Lumberjack
-	lumberjackLogger := &lumberjack.Logger{
-		Filename:   logFilePath,
-		MaxSize:    500, // Max size in MB
-		MaxBackups: 5,
-		MaxAge:     28, // Max age in days
-	}
-
-	// Use custom formatter
-	customFormatter := &CustomFormatter{EnableColors: true}
-
-	// Set log output to console with colors
-	log.SetOutput(os.Stdout)
-	log.SetFormatter(customFormatter)
-	log.SetLevel(logrus.InfoLevel)
-
-	// Set timezone to Beijing time
-	log.AddHook(&TimezoneHook{Location: "Asia/Shanghai"})
-
-	// Add file output hook with custom formatter
-	fileFormatter := &CustomFormatter{EnableColors: false}
-	log.AddHook(&Hook{Writer: lumberjackLogger, Formatter: fileFormatter, LogLevels: logrus.AllLevels})
-}
-
-func getProjectName() string {
-	// Get executable path
-	exePath, err := os.Executable()
-	if err != nil {
-		panic("Failed to get executable path: " + err.Error())
-	}
-
-	// Extract project name from executable path
-	projectName := filepath.Base(exePath)
-	if runtime.GOOS == "windows" {
-		projectName = strings.TrimSuffix(projectName, ".exe")
-	}
-	return projectName
-}
-
 type Hook struct {
 	Writer    io.Writer
 	Formatter logrus.Formatter
@@ -111,21 +52,21 @@ func (hook *TimezoneHook) Fire(entry *logrus.Entry) error {
 	return nil
 }
 
+// CustomFormatter renders one text line: time, caller, logId, level and message, then
+// the remaining fields as sorted key=value pairs. With a Redactor the finished line
+// has its secrets masked.
 type CustomFormatter struct {
 	EnableColors bool
+	Redactor     *redact.Redactor
 }
 
 func (f *CustomFormatter) Format(entry *logrus.Entry) ([]byte, error) {
-	// Get caller file and line number
-	file, line := findCaller()
+	file, line := entryCaller(entry)
 	file = filepath.Base(file)
 
 	// Format timestamp, filename, line number, and log level
 	timestamp := entry.Time.Format("2006-01-02 15:04:05.000000")
-	level := strings.ToUpper(entry.Level.String())
-	if level == "WARNING" {
-		level = "WARN"
-	}
+	level := levelName(entry.Level)
 
 	logId := entry.Data["logId"]
 	logIdStr := ""
@@ -133,14 +74,103 @@ func (f *CustomFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 		logIdStr = fmt.Sprintf("%v ", logId)
 	}
 
+	var b strings.Builder
 	if f.EnableColors {
 		levelColor := getColorByLevel(entry.Level)
-		msg := fmt.Sprintf("%s %s:%d %s[%s%s%s] %s\n", timestamp, file, line, logIdStr, levelColor, level, "\033[0m", entry.Message)
-		return []byte(msg), nil
+		fmt.Fprintf(&b, "%s %s:%d %s[%s%s%s] %s", timestamp, file, line, logIdStr, levelColor, level, "\033[0m", entry.Message)
+	} else {
+		fmt.Fprintf(&b, "%s %s:%d %s[%s] %s", timestamp, file, line, logIdStr, level, entry.Message)
+	}
+	for _, key := range sortedKeys(entry.Data) {
+		if key == "logId" {
+			continue
+		}
+		b.WriteByte(' ')
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(textValue(entry.Data[key]))
+	}
+	b.WriteByte('\n')
+	if f.Redactor != nil {
+		return []byte(f.Redactor.String(b.String())), nil
+	}
+	return []byte(b.String()), nil
+}
+
+// JSONFormatter renders one JSON object per line with time, level, caller, logId and
+// msg, plus every field at the top level so the log pipeline can index them. Fields
+// named like one of those keys are prefixed with "fields.". With a Redactor the encoded
+// line has its secrets masked.
+type JSONFormatter struct {
+	Redactor *redact.Redactor
+}
+
+func (f *JSONFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	file, line := entryCaller(entry)
+	data := make(map[string]interface{}, len(entry.Data)+5)
+	for key, value := range entry.Data {
+		if key == "logId" {
+			continue
+		}
+		if _, reserved := jsonReserved[key]; reserved {
+			key = "fields." + key
+		}
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		data[key] = value
+	}
+	data["time"] = entry.Time.Format(time.RFC3339Nano)
+	data["level"] = strings.ToLower(levelName(entry.Level))
+	data["caller"] = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	data["msg"] = entry.Message
+	if logId := entry.Data["logId"]; logId != nil && logId != "unknown" {
+		data["logId"] = logId
 	}
 
-	msg := fmt.Sprintf("%s %s:%d %s[%s] %s\n", timestamp, file, line, logIdStr, level, entry.Message)
-	return []byte(msg), nil
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("marshal log entry: %w", err)
+	}
+	if f.Redactor != nil {
+		encoded = []byte(f.Redactor.String(string(encoded)))
+	}
+	return append(encoded, '\n'), nil
+}
+
+var jsonReserved = map[string]struct{}{"time": {}, "level": {}, "caller": {}, "msg": {}}
+
+func levelName(level logrus.Level) string {
+	name := strings.ToUpper(level.String())
+	if name == "WARNING" {
+		return "WARN"
+	}
+	return name
+}
+
+func sortedKeys(data logrus.Fields) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func textValue(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
 }
 
 func getColorByLevel(level logrus.Level) string {
@@ -160,112 +190,53 @@ func getColorByLevel(level logrus.Level) string {
 	}
 }
 
+// logPackage is the import path of this package, whose frames are never the caller.
+var logPackage = funcPackage(runtime.FuncForPC(reflect.ValueOf(funcPackage).Pointer()).Name())
+
+// funcPackage extracts the import path from a qualified function name such as
+// "github.com/a/b.(*T).M".
+func funcPackage(funcName string) string {
+	lastSlash := strings.LastIndex(funcName, "/")
+	if dot := strings.Index(funcName[lastSlash+1:], "."); dot >= 0 {
+		return funcName[:lastSlash+1+dot]
+	}
+	return funcName
+}
+
+// findCaller walks up the stack to the first frame outside this package and logrus.
 func findCaller() (string, int) {
-	for skip := 7; ; skip++ {
-		pc, file, line, ok := runtime.Caller(skip)
-		if !ok {
-			return "unknown", 0
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		pkg := funcPackage(frame.Function)
+		if pkg != logPackage && pkg != "github.com/sirupsen/logrus" {
+			return frame.File, frame.Line
 		}
-		funcName := runtime.FuncForPC(pc).Name()
-		if !strings.Contains(funcName, "log.") && !strings.Contains(funcName, "logrus") {
-			return file, line
+		if !more {
+			return "unknown", 0
 		}
 	}
 }
 
-func CtxWithFields(ctx context.Context) *logrus.Entry {
-	requestId := ctx.Value("logId")
-	if requestId == nil {
-		requestId = "unknown"
+func entryCaller(entry *logrus.Entry) (string, int) {
+	if entry.Caller != nil {
+		return entry.Caller.File, entry.Caller.Line
 	}
-	return log.WithField("logId", requestId)
-}
-
-// Wrappers for logrus functions
-func Info(args ...interface{}) {
-	log.Info(args...)
-}
-
-func Infof(format string, args ...interface{}) {
-	log.Infof(format, args...)
-}
-
-func Error(args ...interface{}) {
-	log.Error(args...)
-}
-
-func Errorf(format string, args ...interface{}) {
-	log.Errorf(format, args...)
-}
-
-func Debug(args ...interface{}) {
-	log.Debug(args...)
-}
-
-func Debugf(format string, args ...interface{}) {
-	log.Debugf(format, args...)
+	return findCaller()
 }
 
-func Warn(args ...interface{}) {
-	log.Warn(args...)
-}
-
-func Warnf(format string, args ...interface{}) {
-	log.Warnf(format, args...)
-}
+// callerHook resolves the caller once per entry, before the formatters of the output
+// and of the file hook both need it.
+type callerHook struct{}
 
-func Fatal(args ...interface{}) {
-	log.Fatal(args...)
-}
-
-func Fatalf(format string, args ...interface{}) {
-	log.Fatalf(format, args...)
-}
-
-func Panic(args ...interface{}) {
-	log.Panic(args...)
-}
-
-func Panicf(format string, args ...interface{}) {
-	log.Panicf(format, args...)
-}
-
-// CtxInfo logs an info message with context fields
-func CtxInfo(ctx context.Context, args ...interface{}) {
-	CtxWithFields(ctx).Info(args...)
-}
-
-// CtxInfof logs a formatted info message with context fields
-func CtxInfof(ctx context.Context, format string, args ...interface{}) {
-	CtxWithFields(ctx).Infof(format, args...)
-}
-
-// CtxError logs an error message with context fields
-func CtxError(ctx context.Context, args ...interface{}) {
-	CtxWithFields(ctx).Error(args...)
-}
-
-// CtxErrorf logs a formatted error message with context fields
-func CtxErrorf(ctx context.Context, format string, args ...interface{}) {
-	CtxWithFields(ctx).Errorf(format, args...)
-}
-
-// CtxDebug logs a debug message with context fields
-func CtxDebug(ctx context.Context, args ...interface{}) {
-	CtxWithFields(ctx).Debug(args...)
-}
-
-// CtxDebugf logs a formatted debug message with context fields
-func CtxDebugf(ctx context.Context, format string, args ...interface{}) {
-	CtxWithFields(ctx).Debugf(format, args...)
-}
-
-// CtxWarn logs a warning message with context fields
-func CtxWarn(ctx context.Context, args ...interface{}) {
-	CtxWithFields(ctx).Warn(args...)
+func (hook *callerHook) Levels() []logrus.Level {
+	return logrus.AllLevels
 }
 
-// CtxWarnf logs a formatted warning message with context fields
-func CtxWarnf(ctx context.Context, format string, args ...interface{}) {
-	CtxWithFields(ctx).Warnf(format, args...)
+func (hook *callerHook) Fire(entry *logrus.Entry) error {
+	file, line := findCaller()
+	entry.Caller = &runtime.Frame{File: file, Line: line}
+	return nil
 }
@@ -0,0 +1,161 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// inheritLevel marks a module that follows the level of the default logger.
+const inheritLevel = -1
+
+// module is the state behind a Module logger: a copy of the default logger's outputs
+// with its own level, rebuilt whenever SetDefault swaps the default.
+type module struct {
+	name  string
+	level atomic.Int64
+	clone atomic.Pointer[moduleClone]
+}
+
+type moduleClone struct {
+	base   *logrus.Logger
+	logger *logrus.Logger
+}
+
+var (
+	modulesMutex sync.Mutex
+	modules      = make(map[string]*Logger)
+)
+
+// Module returns the logger of a named part of the program such as "rpc" or "loader".
+// It writes where the default logger writes, tags entries with module=<name>, and has
+// the default level until SetLevel or SetLevels gives it its own.
+func Module(name string) *Logger {
+	name = strings.ToLower(strings.TrimSpace(name))
+	modulesMutex.Lock()
+	defer modulesMutex.Unlock()
+	if l, ok := modules[name]; ok {
+		return l
+	}
+	m := &module{name: name}
+	m.level.Store(inheritLevel)
+	l := &Logger{module: m}
+	modules[name] = l
+	return l
+}
+
+func (m *module) logger() *logrus.Logger {
+	root := Default().logger
+	c := m.clone.Load()
+	if c == nil || c.base != root {
+		c = &moduleClone{
+			base: root,
+			logger: &logrus.Logger{
+				Out:       root.Out,
+				Hooks:     root.Hooks,
+				Formatter: root.Formatter,
+				Level:     root.GetLevel(),
+				ExitFunc:  root.ExitFunc,
+			},
+		}
+		m.clone.Store(c)
+	}
+	level := root.GetLevel()
+	if explicit := m.level.Load(); explicit != inheritLevel {
+		level = logrus.Level(explicit)
+	}
+	if c.logger.GetLevel() != level {
+		c.logger.SetLevel(level)
+	}
+	return c.logger
+}
+
+func (m *module) setLevel(level logrus.Level) {
+	m.level.Store(int64(level))
+}
+
+// ResetLevel makes a module follow the default level again. It does nothing for other
+// loggers.
+func (l *Logger) ResetLevel() {
+	if l.module != nil {
+		l.module.level.Store(inheritLevel)
+	}
+}
+
+// Levels reports the level of the default logger under "default" and of every module.
+func Levels() map[string]string {
+	levels := map[string]string{"default": Default().GetLevel().String()}
+	modulesMutex.Lock()
+	defer modulesMutex.Unlock()
+	for name, l := range modules {
+		levels[name] = l.GetLevel().String()
+	}
+	return levels
+}
+
+// ParseLevels reads a level spec, either a JSON object such as
+// {"default": "info", "rpc": "debug"} or a list such as "info,rpc=debug,loader=warn"
+// where an entry without a module is the default level.
+func ParseLevels(spec string) (map[string]logrus.Level, error) {
+	spec = strings.TrimSpace(spec)
+	raw := make(map[string]string)
+	if strings.HasPrefix(spec, "{") {
+		if err := json.Unmarshal([]byte(spec), &raw); err != nil {
+			return nil, fmt.Errorf("parse log levels: %w", err)
+		}
+	} else if spec != "" {
+		for _, item := range strings.Split(spec, ",") {
+			name, level, found := strings.Cut(strings.TrimSpace(item), "=")
+			if !found {
+				name, level = "default", name
+			}
+			raw[name] = level
+		}
+	}
+
+	levels := make(map[string]logrus.Level, len(raw))
+	for name, value := range raw {
+		level, err := logrus.ParseLevel(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("parse log level of %v: %w", name, err)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			name = "default"
+		}
+		levels[name] = level
+	}
+	return levels, nil
+}
+
+// SetLevels applies a spec in the ParseLevels format. Modules left out of the spec go
+// back to the default level, so removing a line from the config undoes it. A spec that
+// fails to parse changes nothing.
+func SetLevels(spec string) error {
+	levels, err := ParseLevels(spec)
+	if err != nil {
+		return err
+	}
+	if level, ok := levels["default"]; ok {
+		Default().SetLevel(level)
+		delete(levels, "default")
+	}
+	for name := range levels {
+		Module(name)
+	}
+
+	modulesMutex.Lock()
+	defer modulesMutex.Unlock()
+	for name, l := range modules {
+		if level, ok := levels[name]; ok {
+			l.SetLevel(level)
+		} else {
+			l.ResetLevel()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useBuffer makes a stdout logger writing to a buffer the default for one test.
+func useBuffer(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	l, err := New(Config{Output: OutputStdout, Stdout: &buf})
+	require.NoError(t, err)
+	prev := defaultLogger.Load()
+	SetDefault(l)
+	t.Cleanup(func() {
+		defaultLogger.Store(prev)
+		SetLevels("")
+	})
+	return &buf
+}
+
+func TestParseLevels(t *testing.T) {
+	levels, err := ParseLevels("warn, rpc=debug,Loader=error")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]logrus.Level{"default": logrus.WarnLevel, "rpc": logrus.DebugLevel, "loader": logrus.ErrorLevel}, levels)
+
+	levels, err = ParseLevels(`{"default": "info", "asynccache": "trace"}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]logrus.Level{"default": logrus.InfoLevel, "asynccache": logrus.TraceLevel}, levels)
+
+	levels, err = ParseLevels("")
+	require.NoError(t, err)
+	assert.Empty(t, levels)
+
+	_, err = ParseLevels("rpc=chatty")
+	assert.Error(t, err)
+}
+
+func TestModuleLevels(t *testing.T) {
+	buf := useBuffer(t)
+	rpc := Module("rpc")
+	assert.Same(t, rpc, Module(" RPC "))
+
+	rpc.Debug("hidden")
+	require.NoError(t, SetLevels("rpc=debug"))
+	rpc.Debug("shown")
+	Debug("default still info")
+	out := buf.String()
+	assert.NotContains(t, out, "hidden")
+	assert.Contains(t, out, "[DEBUG] shown module=rpc")
+	assert.NotContains(t, out, "default still info")
+
+	// dropping the module from the spec makes it follow the default again
+	require.NoError(t, SetLevels("error"))
+	assert.Equal(t, logrus.ErrorLevel, rpc.GetLevel())
+	assert.Equal(t, "error", Levels()["rpc"])
+
+	// modules follow a new default logger
+	next := useBuffer(t)
+	rpc.Error("after swap")
+	assert.Contains(t, next.String(), "after swap module=rpc")
+}
+
+func TestLevelHandler(t *testing.T) {
+	useBuffer(t)
+	handler := LevelHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/?module=loader&level=debug", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"loader":"debug"`)
+	assert.Equal(t, logrus.DebugLevel, Module("loader").GetLevel())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/?module=loader&level=default", nil))
+	assert.Equal(t, logrus.InfoLevel, Module("loader").GetLevel())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/?level=loud", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+type fakeSource struct {
+	mutex sync.Mutex
+	value string
+}
+
+func (s *fakeSource) GetString(namespace, key string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.value, nil
+}
+
+func (s *fakeSource) set(value string) {
+	s.mutex.Lock()
+	s.value = value
+	s.mutex.Unlock()
+}
+
+func TestWatchLevels(t *testing.T) {
+	useBuffer(t)
+	source := &fakeSource{value: "asynccache=warn"}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go WatchLevels(ctx, source, "base_config", "log_levels", 5*time.Millisecond)
+
+	assert.Eventually(t, func() bool { return Module("asynccache").GetLevel() == logrus.WarnLevel }, time.Second, time.Millisecond)
+	source.set("asynccache=nonsense")
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, logrus.WarnLevel, Module("asynccache").GetLevel())
+	source.set("")
+	assert.Eventually(t, func() bool { return Module("asynccache").GetLevel() == logrus.InfoLevel }, time.Second, time.Millisecond)
+}
+
+func TestDebugfEvery(t *testing.T) {
+	buf := useBuffer(t)
+	l := Default()
+	l.DebugfEvery("eth", time.Hour, "balance %v", 0)
+	assert.Zero(t, buf.Len())
+
+	l.SetLevel(logrus.DebugLevel)
+	for i := 1; i <= 3; i++ {
+		l.DebugfEvery("eth", 30*time.Millisecond, "balance %v", i)
+	}
+	time.Sleep(40 * time.Millisecond)
+	l.DebugfEvery("eth", 30*time.Millisecond, "balance %v", 4)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "balance 1")
+	assert.Contains(t, lines[1], "balance 4 suppressed=2")
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// limit tracks one DebugfEvery key.
+type limit struct {
+	mutex   sync.Mutex
+	last    time.Time
+	dropped int
+}
+
+// allow reports whether an entry for key may be written now, and how many were
+// dropped since the last one.
+func (l *Logger) allow(key string, interval time.Duration) (bool, int) {
+	value, _ := l.limits.LoadOrStore(key, &limit{})
+	lim := value.(*limit)
+	lim.mutex.Lock()
+	defer lim.mutex.Unlock()
+	now := time.Now()
+	if now.Sub(lim.last) < interval {
+		lim.dropped++
+		return false, 0
+	}
+	dropped := lim.dropped
+	lim.last, lim.dropped = now, 0
+	return true, dropped
+}
+
+func (l *Logger) logEvery(entry *logrus.Entry, key string, interval time.Duration, format string, args []interface{}) {
+	if !entry.Logger.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+	ok, dropped := l.allow(key, interval)
+	if !ok {
+		return
+	}
+	if dropped > 0 {
+		entry = entry.WithField("suppressed", dropped)
+	}
+	entry.Debugf(format, args...)
+}
+
+// DebugfEvery is Debugf for hot paths such as balance polling: it writes at most one
+// entry per interval for key and adds the number of entries it skipped in between as
+// "suppressed". Nothing is formatted while debug is off. Keys should come from a small
+// set, like a chain name, since each one is remembered.
+func (l *Logger) DebugfEvery(key string, interval time.Duration, format string, args ...interface{}) {
+	l.logEvery(l.entry(), key, interval, format, args)
+}
+
+// CtxDebugfEvery is DebugfEvery with context fields.
+func (l *Logger) CtxDebugfEvery(ctx context.Context, key string, interval time.Duration, format string, args ...interface{}) {
+	l.logEvery(l.CtxWithFields(ctx), key, interval, format, args)
+}
+
+// DebugfEvery logs a formatted debug message at most once per interval for key
+func DebugfEvery(key string, interval time.Duration, format string, args ...interface{}) {
+	Default().DebugfEvery(key, interval, format, args...)
+}
+
+// CtxDebugfEvery logs a formatted debug message with context fields at most once per interval for key
+func CtxDebugfEvery(ctx context.Context, key string, interval time.Duration, format string, args ...interface{}) {
+	Default().CtxDebugfEvery(ctx, key, interval, format, args...)
+}
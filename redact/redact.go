@@ -0,0 +1,214 @@
+package redact
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces a secret in redacted text.
+const Mask = "****"
+
+// Options tune a Redactor. Secrets are always masked; the options only add to that.
+type Options struct {
+	// MaskAddresses shortens EVM addresses the way util.MaskEVMAddress does.
+	MaskAddresses bool
+	// MaskHexKeys masks every bare 64 digit hex value. Off by default since Bitcoin and
+	// Tron txids and block hashes have the same shape.
+	MaskHexKeys bool
+}
+
+// Redactor masks secrets in free text such as log lines, error strings and alerts:
+//   - values of keys named like private_key, secret, password, mnemonic or bearer
+//   - "Bearer <token>" credentials
+//   - URL user info, secret query parameters and API keys in URL paths
+//   - Solana keypairs in base58 or base64, recognised by their public half
+//   - 64 digit hex values right after a word like "key" or "private key"; bare ones
+//     only with Options.MaskHexKeys, since txids and block hashes look the same
+type Redactor struct {
+	opt Options
+}
+
+func New(opt Options) *Redactor {
+	return &Redactor{opt: opt}
+}
+
+var std = New(Options{})
+
+// String redacts s with the default options.
+func String(s string) string {
+	return std.String(s)
+}
+
+var (
+	urlPattern       = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>\\]+`)
+	namedPattern     = regexp.MustCompile(`(?i)((?:private[_-]?key|privkey|secret|password|passwd|mnemonic|seed[_-]?phrase|bearer|api[_-]?key|access[_-]?token)\\?"?\s*[:=]\s*\\?"?)([^\s"\\,;}&\])]+)`)
+	bearerPattern    = regexp.MustCompile(`(?i)(\bbearer\s+)([A-Za-z0-9._~+/=-]+)`)
+	base58Pattern    = regexp.MustCompile(`\b[1-9A-HJ-NP-Za-km-z]{86,88}\b`)
+	base64Pattern    = regexp.MustCompile(`[A-Za-z0-9+/]{86}==`)
+	hexKeyPattern    = regexp.MustCompile(`\b[0-9a-fA-F]{64}\b`)
+	namedHexPattern  = regexp.MustCompile(`(?i)(\b(?:(?:private|priv|secret|signing|wallet)[ _-]?)?key\s*[:=]?\s*)(?:0x)?[0-9a-fA-F]{64}\b`)
+	addressPattern   = regexp.MustCompile(`\b0x[0-9a-fA-F]{40}\b`)
+	secretQueryNames = map[string]struct{}{
+		"key": {}, "apikey": {}, "api_key": {}, "api-key": {}, "token": {}, "access_token": {},
+		"secret": {}, "password": {}, "auth": {}, "sig": {}, "signature": {},
+	}
+)
+
+// String returns s with every secret it recognises masked.
+func (r *Redactor) String(s string) string {
+	s = urlPattern.ReplaceAllStringFunc(s, redactURL)
+	s = namedPattern.ReplaceAllString(s, "${1}"+Mask)
+	s = bearerPattern.ReplaceAllString(s, "${1}"+Mask)
+	s = base58Pattern.ReplaceAllStringFunc(s, func(m string) string {
+		if isKeypair(decodeBase58(m)) {
+			return Mask
+		}
+		return m
+	})
+	s = base64Pattern.ReplaceAllStringFunc(s, func(m string) string {
+		if b, err := base64.StdEncoding.DecodeString(m); err == nil && isKeypair(b) {
+			return Mask
+		}
+		return m
+	})
+	s = namedHexPattern.ReplaceAllString(s, "${1}"+Mask)
+	if r.opt.MaskHexKeys {
+		s = hexKeyPattern.ReplaceAllString(s, Mask)
+	}
+	if r.opt.MaskAddresses {
+		s = addressPattern.ReplaceAllStringFunc(s, MaskAddress)
+	}
+	return s
+}
+
+// MaskAddress keeps the first 6 and last 4 characters of an address.
+func MaskAddress(address string) string {
+	if len(address) < 12 {
+		return address
+	}
+	return address[:6] + Mask + address[len(address)-4:]
+}
+
+// explorerSegments precede ids in block explorer paths, as in /tx/<hash>; what follows
+// them is public.
+var explorerSegments = map[string]struct{}{
+	"tx": {}, "txs": {}, "transaction": {}, "transactions": {}, "address": {}, "account": {},
+	"block": {}, "blocks": {}, "token": {}, "hash": {},
+}
+
+// redactURL drops user info, masks secret query values and path segments that look
+// like API keys, as in https://eth-mainnet.g.alchemy.com/v2/<key>.
+func redactURL(u string) string {
+	scheme, rest, _ := strings.Cut(u, "://")
+	end := strings.IndexAny(rest, "/?#")
+	if end < 0 {
+		end = len(rest)
+	}
+	if at := strings.LastIndex(rest[:end], "@"); at >= 0 {
+		rest = Mask + rest[at:]
+		end = end - at + len(Mask)
+	}
+	host, tail := rest[:end], rest[end:]
+
+	path, query, hasQuery := strings.Cut(tail, "?")
+	path = Path(path)
+	if !hasQuery {
+		return scheme + "://" + host + path
+	}
+
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		name, _, found := strings.Cut(param, "=")
+		if _, secret := secretQueryNames[strings.ToLower(name)]; found && secret {
+			params[i] = name + "=" + Mask
+		}
+	}
+	return scheme + "://" + host + path + "?" + strings.Join(params, "&")
+}
+
+// Path masks the segments of a URL path that look like API keys, keeping ids that
+// follow explorer segments such as /tx/.
+func Path(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if i > 0 {
+			if _, ok := explorerSegments[strings.ToLower(segments[i-1])]; ok {
+				continue
+			}
+		}
+		if looksLikeAPIKey(segment) {
+			segments[i] = Mask
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// looksLikeAPIKey matches long opaque tokens mixing letters and digits. Hex with 0x is
+// an address or hash and stays, and so does base58 that decodes to a 32 byte address
+// or a 64 byte signature.
+func looksLikeAPIKey(segment string) bool {
+	if len(segment) < 20 || strings.HasPrefix(segment, "0x") {
+		return false
+	}
+	if b := decodeBase58(segment); (len(b) == 32 || len(b) == 64) && !isKeypair(b) {
+		return false
+	}
+	letters, digits := false, false
+	for _, c := range segment {
+		switch {
+		case c >= '0' && c <= '9':
+			digits = true
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+			letters = true
+		case c == '-' || c == '_':
+		default:
+			return false
+		}
+	}
+	return letters && digits
+}
+
+// isKeypair reports whether b is a 64 byte ed25519 private key, a seed followed by its
+// own public key. Signatures have the same length but not this shape.
+func isKeypair(b []byte) bool {
+	if len(b) != ed25519.PrivateKeySize {
+		return false
+	}
+	key := ed25519.NewKeyFromSeed(b[:ed25519.SeedSize])
+	return bytes.Equal(key.Public().(ed25519.PublicKey), b[ed25519.SeedSize:])
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// decodeBase58 decodes bitcoin alphabet base58, returning nil on invalid input.
+func decodeBase58(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, c := range []byte(s) {
+		carry := strings.IndexByte(base58Alphabet, c)
+		if carry < 0 {
+			return nil
+		}
+		for i := range out {
+			carry += int(out[i]) * 58
+			out[i] = byte(carry)
+			carry >>= 8
+		}
+		for carry > 0 {
+			out = append(out, byte(carry))
+			carry >>= 8
+		}
+	}
+	for _, c := range []byte(s) {
+		if c != '1' {
+			break
+		}
+		out = append(out, 0)
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
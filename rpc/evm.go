@@ -16,7 +16,6 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/realcaishen/utils-go/abi/erc20"
 	"github.com/realcaishen/utils-go/loader"
-	"github.com/realcaishen/utils-go/log"
 	"github.com/realcaishen/utils-go/owlconsts"
 	"github.com/realcaishen/utils-go/pointer"
 	"github.com/realcaishen/utils-go/util"
@@ -265,7 +264,7 @@ func (w *EvmRpc) IsTxSuccess(ctx context.Context, hash string) (bool, int64, err
 func (w *EvmRpc) GetLatestBlockNumber(ctx context.Context) (int64, error) {
 	blockNumber, err := w.GetClient().BlockNumber(ctx)
 	if err != nil {
-		log.Errorf("%v get latest block number error %v", w.chainInfo.Name, err)
+		logger.Errorf("%v get latest block number error %v", w.chainInfo.Name, err)
 		return 0, err
 	}
 	return int64(blockNumber), nil
@@ -0,0 +1,207 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/hashicorp/go-metrics"
+	"github.com/realcaishen/utils-go/loader"
+	"github.com/realcaishen/utils-go/telemetry"
+)
+
+// Error types ClassifyError reports, used as the error_type label of rpc errors.
+const (
+	ErrorTypeTimeout     = "timeout"
+	ErrorTypeCanceled    = "canceled"
+	ErrorTypeRateLimited = "rate_limited"
+	ErrorTypeConnection  = "connection"
+	ErrorTypeNotFound    = "not_found"
+	// ErrorTypeNode is an error answered by the node itself, such as a JSON-RPC error.
+	ErrorTypeNode  = "node"
+	ErrorTypeOther = "other"
+)
+
+// ClassifyError maps a backend error to one of the ErrorType constants. Backends wrap
+// errors inconsistently, so after the typed checks it falls back to the message.
+func ClassifyError(err error) string {
+	var netErr net.Error
+	var httpErr gethrpc.HTTPError
+	var nodeErr gethrpc.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorTypeTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorTypeCanceled
+	case errors.As(err, &httpErr) && httpErr.StatusCode == 429:
+		return ErrorTypeRateLimited
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTypeTimeout
+	case errors.As(err, &netErr):
+		return ErrorTypeConnection
+	case errors.As(err, &nodeErr):
+		return ErrorTypeNode
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "timeout"), strings.Contains(msg, "deadline exceeded"):
+		return ErrorTypeTimeout
+	case strings.Contains(msg, "429"), strings.Contains(msg, "rate limit"), strings.Contains(msg, "too many requests"):
+		return ErrorTypeRateLimited
+	case strings.Contains(msg, "connection refused"), strings.Contains(msg, "connection reset"),
+		strings.Contains(msg, "no such host"), strings.Contains(msg, "eof"):
+		return ErrorTypeConnection
+	case strings.Contains(msg, "not found"):
+		return ErrorTypeNotFound
+	}
+	return ErrorTypeOther
+}
+
+// meteredRpc records, per chain, backend and method, the latency of every call as
+// rpc.latency, failures by ClassifyError as rpc.errors and calls in progress as the
+// rpc.in_flight gauge.
+type meteredRpc struct {
+	Rpc
+	chain   string
+	backend string
+}
+
+func withMetrics(rpc Rpc, chainInfo *loader.ChainInfo) Rpc {
+	return &meteredRpc{
+		Rpc:     rpc,
+		chain:   chainInfo.Name,
+		backend: backendName(chainInfo.Backend),
+	}
+}
+
+type inFlightKey struct {
+	chain   string
+	backend string
+	method  string
+}
+
+// inFlight counts the calls in progress per chain, backend and method over every
+// wrapper: a chain reloaded with a new client gets a new wrapper while calls through
+// the old one may still run, and both must add up to the same gauge.
+var inFlight sync.Map
+
+func inFlightCounter(key inFlightKey) *atomic.Int64 {
+	if counter, ok := inFlight.Load(key); ok {
+		return counter.(*atomic.Int64)
+	}
+	counter, _ := inFlight.LoadOrStore(key, &atomic.Int64{})
+	return counter.(*atomic.Int64)
+}
+
+func backendName(backend loader.Backend) string {
+	switch backend {
+	case loader.EthereumBackend:
+		return "evm"
+	case loader.StarknetBackend:
+		return "starknet"
+	case loader.SolanaBackend:
+		return "solana"
+	case loader.BitcoinBackend:
+		return "bitcoin"
+	case loader.ZksliteBackend:
+		return "zkslite"
+	case loader.TonBackend:
+		return "ton"
+	case loader.CosmosBackend:
+		return "cosmos"
+	case loader.NetworkTypeBfc:
+		return "bfc"
+	case loader.SuiBackend:
+		return "sui"
+	case loader.FuelBackend:
+		return "fuel"
+	}
+	return strconv.Itoa(int(backend))
+}
+
+func (r *meteredRpc) Unwrap() Rpc {
+	return r.Rpc
+}
+
+// labels builds a fresh slice on every call since the telemetry wrappers append to it.
+func (r *meteredRpc) labels(method string, extra ...metrics.Label) []metrics.Label {
+	labels := make([]metrics.Label, 0, 3+len(extra))
+	labels = append(labels,
+		telemetry.NewLabel("chain", r.chain),
+		telemetry.NewLabel("backend", r.backend),
+		telemetry.NewLabel("method", method))
+	return append(labels, extra...)
+}
+
+// begin counts a call of method as in flight; the returned func records its outcome.
+func (r *meteredRpc) begin(method string) func(err error) {
+	start := time.Now()
+	inFlight := inFlightCounter(inFlightKey{chain: r.chain, backend: r.backend, method: method})
+	telemetry.SetGaugeWithLabels([]string{"rpc", "in_flight"}, float32(inFlight.Add(1)), r.labels(method))
+	return func(err error) {
+		telemetry.SetGaugeWithLabels([]string{"rpc", "in_flight"}, float32(inFlight.Add(-1)), r.labels(method))
+		telemetry.MeasureSinceWithLabels([]string{"rpc", "latency"}, start, r.labels(method))
+		if err != nil {
+			telemetry.IncrCounterWithLabels([]string{"rpc", "errors"}, 1, r.labels(method, telemetry.NewLabel("error_type", ClassifyError(err))))
+		}
+	}
+}
+
+func (r *meteredRpc) GetLatestBlockNumber(ctx context.Context) (int64, error) {
+	done := r.begin("GetLatestBlockNumber")
+	number, err := r.Rpc.GetLatestBlockNumber(ctx)
+	done(err)
+	return number, err
+}
+
+func (r *meteredRpc) IsTxSuccess(ctx context.Context, hash string) (bool, int64, error) {
+	done := r.begin("IsTxSuccess")
+	success, number, err := r.Rpc.IsTxSuccess(ctx, hash)
+	done(err)
+	return success, number, err
+}
+
+func (r *meteredRpc) GetAllowance(ctx context.Context, ownerAddr string, tokenAddr string, spenderAddr string) (*big.Int, error) {
+	done := r.begin("GetAllowance")
+	allowance, err := r.Rpc.GetAllowance(ctx, ownerAddr, tokenAddr, spenderAddr)
+	done(err)
+	return allowance, err
+}
+
+func (r *meteredRpc) GetBalance(ctx context.Context, ownerAddr string, tokenAddr string) (*big.Int, error) {
+	done := r.begin("GetBalance")
+	balance, err := r.Rpc.GetBalance(ctx, ownerAddr, tokenAddr)
+	done(err)
+	return balance, err
+}
+
+func (r *meteredRpc) GetBalanceAtBlockNumber(ctx context.Context, ownerAddr string, tokenAddr string, blockNumber int64) (*big.Int, error) {
+	done := r.begin("GetBalanceAtBlockNumber")
+	balance, err := r.Rpc.GetBalanceAtBlockNumber(ctx, ownerAddr, tokenAddr, blockNumber)
+	done(err)
+	return balance, err
+}
+
+func (r *meteredRpc) GetTokenInfo(ctx context.Context, tokenAddr string) (*loader.TokenInfo, error) {
+	done := r.begin("GetTokenInfo")
+	token, err := r.Rpc.GetTokenInfo(ctx, tokenAddr)
+	done(err)
+	return token, err
+}
+
+// GetTxReceipt keeps ReceiptRpc visible through the wrapper; backends without it fall
+// back to IsTxSuccess as in the package level GetTxReceipt.
+func (r *meteredRpc) GetTxReceipt(ctx context.Context, hash string) (*TxReceipt, error) {
+	done := r.begin("GetTxReceipt")
+	receipt, err := GetTxReceipt(ctx, r.Rpc, hash)
+	done(err)
+	return receipt, err
+}
@@ -0,0 +1,95 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/hashicorp/go-metrics"
+	"github.com/realcaishen/utils-go/loader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{context.DeadlineExceeded, ErrorTypeTimeout},
+		{fmt.Errorf("get balance: %w", context.Canceled), ErrorTypeCanceled},
+		{gethrpc.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}, ErrorTypeRateLimited},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, ErrorTypeConnection},
+		{errors.New(`Post "https://rpc": read tcp: i/o timeout`), ErrorTypeTimeout},
+		{errors.New("server returned 429: rate limit exceeded"), ErrorTypeRateLimited},
+		{errors.New("not found"), ErrorTypeNotFound},
+		{errors.New("execution reverted"), ErrorTypeOther},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, ClassifyError(c.err), c.err.Error())
+	}
+}
+
+func TestMeteredRpc(t *testing.T) {
+	sink := metrics.NewInmemSink(time.Minute, time.Minute)
+	cfg := metrics.DefaultConfig("")
+	cfg.EnableHostname = false
+	cfg.EnableRuntimeMetrics = false
+	cfg.EnableServiceLabel = false
+	_, err := metrics.NewGlobal(cfg, sink)
+	require.NoError(t, err)
+	defer metrics.NewGlobal(cfg, &metrics.BlackholeSink{})
+
+	inner := &fakeRpc{}
+	r := withMetrics(inner, &loader.ChainInfo{Name: "Ethereum", Backend: loader.EthereumBackend})
+	assert.Same(t, inner, Unwrap(r))
+	_, err = r.GetBalance(context.Background(), "0xowner", "0xtoken")
+	require.NoError(t, err)
+	inner.err = context.DeadlineExceeded
+	_, err = r.GetBalance(context.Background(), "0xowner", "0xtoken")
+	assert.Error(t, err)
+
+	receiptRpc := withMetrics(&fakeReceiptRpc{}, &loader.ChainInfo{Name: "Ethereum", Backend: loader.EthereumBackend})
+	receipt, err := GetTxReceipt(context.Background(), receiptRpc, "0xhash")
+	require.NoError(t, err)
+	assert.Equal(t, "0xrecipient", receipt.Recipient)
+
+	data := sink.Data()
+	require.NotEmpty(t, data)
+	interval := data[len(data)-1]
+	interval.RLock()
+	defer interval.RUnlock()
+	labels := ";chain=Ethereum;backend=evm;method=GetBalance"
+	if assert.Contains(t, interval.Samples, "rpc.latency"+labels) {
+		assert.Equal(t, 2, interval.Samples["rpc.latency"+labels].Count)
+	}
+	if assert.Contains(t, interval.Counters, "rpc.errors"+labels+";error_type=timeout") {
+		assert.Equal(t, 1, interval.Counters["rpc.errors"+labels+";error_type=timeout"].Count)
+	}
+	if assert.Contains(t, interval.Gauges, "rpc.in_flight"+labels) {
+		assert.Equal(t, float32(0), interval.Gauges["rpc.in_flight"+labels].Value)
+	}
+	receiptLabels := ";chain=Ethereum;backend=evm;method=GetTxReceipt"
+	if assert.Contains(t, interval.Samples, "rpc.latency"+receiptLabels) {
+		assert.Equal(t, 1, interval.Samples["rpc.latency"+receiptLabels].Count)
+	}
+}
+
+func TestInFlightSharedByWrappers(t *testing.T) {
+	chain := &loader.ChainInfo{Name: "Cosmoshub", Backend: loader.CosmosBackend}
+	old := withMetrics(&fakeRpc{}, chain).(*meteredRpc)
+	reloaded := withMetrics(&fakeRpc{}, chain).(*meteredRpc)
+	assert.Equal(t, "cosmos", old.backend)
+
+	counter := inFlightCounter(inFlightKey{chain: "Cosmoshub", backend: "cosmos", method: "GetBalance"})
+	doneOld := old.begin("GetBalance")
+	doneReloaded := reloaded.begin("GetBalance")
+	assert.Equal(t, int64(2), counter.Load())
+	doneOld(nil)
+	doneReloaded(nil)
+	assert.Equal(t, int64(0), counter.Load())
+}
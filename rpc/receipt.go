@@ -0,0 +1,174 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Transfer is one movement of funds inside a transaction.
+type Transfer struct {
+	Recipient string
+	Token     string
+	Value     *big.Int
+}
+
+// TxReceipt is the settlement view of a mined transaction. Transfers lists every
+// movement of funds the backend found, and is empty when it cannot tell. Recipient,
+// Token and Value describe the first of them, or the one picked by Match. BlockTime is
+// the unix time of the block, 0 when unknown.
+type TxReceipt struct {
+	Hash        string
+	Success     bool
+	BlockNumber int64
+	BlockTime   int64
+	GasUsed     int64
+	GasPrice    *big.Int
+	TxFee       *big.Int
+	Recipient   string
+	Token       string
+	Value       *big.Int
+	Transfers   []*Transfer
+}
+
+func (r *TxReceipt) use(t *Transfer) {
+	if t == nil {
+		r.Recipient, r.Token, r.Value = "", "", nil
+		return
+	}
+	r.Recipient, r.Token, r.Value = t.Recipient, t.Token, t.Value
+}
+
+// Match points Recipient, Token and Value at the first transfer to recipient of token,
+// compared case-insensitively, where an empty argument matches anything. It reports
+// whether one was found and clears the three fields if not.
+func (r *TxReceipt) Match(recipient string, token string) bool {
+	recipient = strings.TrimSpace(recipient)
+	token = strings.TrimSpace(token)
+	for _, t := range r.Transfers {
+		if (recipient == "" || strings.EqualFold(recipient, t.Recipient)) && (token == "" || strings.EqualFold(token, t.Token)) {
+			r.use(t)
+			return true
+		}
+	}
+	r.use(nil)
+	return false
+}
+
+// ReceiptRpc is implemented by backends that can report more than IsTxSuccess.
+type ReceiptRpc interface {
+	GetTxReceipt(ctx context.Context, hash string) (*TxReceipt, error)
+}
+
+// GetTxReceipt returns the receipt of hash, falling back to IsTxSuccess for backends
+// that do not implement ReceiptRpc.
+func GetTxReceipt(ctx context.Context, r Rpc, hash string) (*TxReceipt, error) {
+	if rr, ok := r.(ReceiptRpc); ok {
+		return rr.GetTxReceipt(ctx, hash)
+	}
+	success, height, err := r.IsTxSuccess(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return &TxReceipt{Hash: hash, Success: success, BlockNumber: height}, nil
+}
+
+var erc20TransferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+func (w *EvmRpc) GetTxReceipt(ctx context.Context, hash string) (*TxReceipt, error) {
+	txHash := common.HexToHash(hash)
+	receipt, err := w.GetClient().TransactionReceipt(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	if receipt == nil {
+		return nil, fmt.Errorf("get receipt failed")
+	}
+	tx, _, err := w.GetClient().TransactionByHash(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TxReceipt{
+		Hash:        hash,
+		Success:     receipt.Status == ethtypes.ReceiptStatusSuccessful,
+		BlockNumber: receipt.BlockNumber.Int64(),
+		GasUsed:     int64(receipt.GasUsed),
+		GasPrice:    receipt.EffectiveGasPrice,
+	}
+	if result.GasPrice == nil {
+		result.GasPrice = tx.GasPrice()
+	}
+	result.TxFee = new(big.Int).Mul(result.GasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+	header, err := w.GetClient().HeaderByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		logger.CtxWarnf(ctx, "%v get block %v of %v error: %v", w.chainInfo.Name, receipt.BlockNumber, hash, err)
+	} else {
+		result.BlockTime = int64(header.Time)
+	}
+
+	if tx.Value().Sign() > 0 && tx.To() != nil {
+		if w.isTransferContract(*tx.To()) {
+			// the contract forwards the value, so the recipient is only visible in a trace
+			transfers, err := w.internalTransfers(ctx, txHash)
+			if err != nil {
+				logger.CtxWarnf(ctx, "%v trace native transfers of %v error: %v", w.chainInfo.Name, hash, err)
+			}
+			result.Transfers = append(result.Transfers, transfers...)
+		} else {
+			result.Transfers = append(result.Transfers, &Transfer{Recipient: tx.To().Hex(), Token: common.Address{}.Hex(), Value: tx.Value()})
+		}
+	}
+	for _, l := range receipt.Logs {
+		if len(l.Topics) == 3 && l.Topics[0] == erc20TransferTopic {
+			result.Transfers = append(result.Transfers, &Transfer{
+				Recipient: common.BytesToAddress(l.Topics[2].Bytes()).Hex(),
+				Token:     l.Address.Hex(),
+				Value:     new(big.Int).SetBytes(l.Data),
+			})
+		}
+	}
+	if len(result.Transfers) > 0 {
+		result.use(result.Transfers[0])
+	}
+	return result, nil
+}
+
+func (w *EvmRpc) isTransferContract(to common.Address) bool {
+	contract := w.chainInfo.TransferContractAddress
+	return contract.Valid && common.IsHexAddress(contract.String) && common.HexToAddress(contract.String) == to
+}
+
+type callFrame struct {
+	To    common.Address `json:"to"`
+	Value *hexutil.Big   `json:"value"`
+	Calls []*callFrame   `json:"calls"`
+}
+
+// internalTransfers lists the native value moved by the calls a transaction made, found
+// with the callTracer of debug_traceTransaction.
+func (w *EvmRpc) internalTransfers(ctx context.Context, txHash common.Hash) ([]*Transfer, error) {
+	var root callFrame
+	err := w.GetClient().Client().CallContext(ctx, &root, "debug_traceTransaction", txHash, map[string]string{"tracer": "callTracer"})
+	if err != nil {
+		return nil, err
+	}
+	transfers := make([]*Transfer, 0)
+	var walk func(calls []*callFrame)
+	walk = func(calls []*callFrame) {
+		for _, call := range calls {
+			if call.Value != nil && call.Value.ToInt().Sign() > 0 {
+				transfers = append(transfers, &Transfer{Recipient: call.To.Hex(), Token: common.Address{}.Hex(), Value: call.Value.ToInt()})
+			}
+			walk(call.Calls)
+		}
+	}
+	walk(root.Calls)
+	return transfers, nil
+}
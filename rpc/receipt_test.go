@@ -0,0 +1,146 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/realcaishen/utils-go/loader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const receiptBlockTime = 1_700_000_000
+
+// newReceiptNode serves eth_getTransactionReceipt, eth_getTransactionByHash,
+// eth_getBlockByNumber and debug_traceTransaction for a single transaction sending value
+// to "to", mined at receiptBlockTime.
+func newReceiptNode(t *testing.T, to common.Address, value *big.Int, logs []*ethtypes.Log, trace interface{}) (*httptest.Server, string) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := ethtypes.LatestSignerForChainID(big.NewInt(1))
+	tx := ethtypes.MustSignNewTx(key, signer, &ethtypes.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Gas:       21000,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(2_000_000_000),
+		To:        &to,
+		Value:     value,
+	})
+	blockHash := common.HexToHash("0x01")
+	receipt := &ethtypes.Receipt{
+		Type:              ethtypes.DynamicFeeTxType,
+		Status:            ethtypes.ReceiptStatusSuccessful,
+		CumulativeGasUsed: 21000,
+		GasUsed:           21000,
+		EffectiveGasPrice: big.NewInt(1_000_000_000),
+		Logs:              logs,
+		TxHash:            tx.Hash(),
+		BlockHash:         blockHash,
+		BlockNumber:       big.NewInt(100),
+	}
+
+	txJSON, err := tx.MarshalJSON()
+	require.NoError(t, err)
+	txFields := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(txJSON, &txFields))
+	txFields["blockHash"] = blockHash
+	txFields["blockNumber"] = "0x64"
+	txFields["from"] = crypto.PubkeyToAddress(key.PublicKey)
+	header := &ethtypes.Header{Number: big.NewInt(100), Difficulty: big.NewInt(0), Time: receiptBlockTime}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Id     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		var result interface{}
+		switch req.Method {
+		case "eth_getTransactionReceipt":
+			result = receipt
+		case "eth_getTransactionByHash":
+			result = txFields
+		case "eth_getBlockByNumber":
+			result = header
+		case "debug_traceTransaction":
+			result = trace
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.Id, "result": result})
+	}))
+	return server, tx.Hash().Hex()
+}
+
+func TestGetTxReceiptThroughGetRpc(t *testing.T) {
+	recipient := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	server, hash := newReceiptNode(t, recipient, big.NewInt(5), []*ethtypes.Log{}, nil)
+	defer server.Close()
+	client, err := ethclient.Dial(server.URL)
+	require.NoError(t, err)
+
+	r, err := GetRpc(&loader.ChainInfo{Name: "Ethereum", Backend: loader.EthereumBackend, Client: client}, nil)
+	require.NoError(t, err)
+	_, ok := r.(ReceiptRpc)
+	assert.True(t, ok)
+
+	receipt, err := GetTxReceipt(context.Background(), r, hash)
+	require.NoError(t, err)
+	assert.True(t, receipt.Success)
+	assert.Equal(t, int64(100), receipt.BlockNumber)
+	assert.Equal(t, int64(receiptBlockTime), receipt.BlockTime)
+	assert.Equal(t, recipient.Hex(), receipt.Recipient)
+	assert.Equal(t, common.Address{}.Hex(), receipt.Token)
+	assert.Equal(t, int64(5), receipt.Value.Int64())
+	assert.Equal(t, int64(21000), receipt.GasUsed)
+	assert.Equal(t, big.NewInt(21000*1_000_000_000), receipt.TxFee)
+}
+
+func TestGetTxReceiptTransfers(t *testing.T) {
+	contract := common.HexToAddress("0x00000000000000000000000000000000000000c0")
+	token := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	fee := common.HexToAddress("0x00000000000000000000000000000000000000fe")
+	recipient := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	transferLog := func(to common.Address, value int64) *ethtypes.Log {
+		return &ethtypes.Log{
+			Address: token,
+			Topics:  []common.Hash{erc20TransferTopic, common.BytesToHash(contract.Bytes()), common.BytesToHash(to.Bytes())},
+			Data:    common.BigToHash(big.NewInt(value)).Bytes(),
+		}
+	}
+	trace := map[string]interface{}{
+		"to": contract,
+		"calls": []map[string]interface{}{
+			{"to": fee, "value": "0x1"},
+			{"to": recipient, "value": "0x4"},
+		},
+	}
+	server, hash := newReceiptNode(t, contract, big.NewInt(5), []*ethtypes.Log{transferLog(fee, 1), transferLog(recipient, 9)}, trace)
+	defer server.Close()
+	client, err := ethclient.Dial(server.URL)
+	require.NoError(t, err)
+
+	chain := &loader.ChainInfo{Name: "Ethereum", Backend: loader.EthereumBackend, Client: client}
+	chain.TransferContractAddress.String, chain.TransferContractAddress.Valid = contract.Hex(), true
+	receipt, err := NewEvmRpc(chain).GetTxReceipt(context.Background(), hash)
+	require.NoError(t, err)
+
+	// the native value is followed through the transfer contract instead of stopping at it
+	require.Len(t, receipt.Transfers, 4)
+	assert.Equal(t, fee.Hex(), receipt.Recipient)
+	assert.True(t, receipt.Match(recipient.Hex(), common.Address{}.Hex()))
+	assert.Equal(t, int64(4), receipt.Value.Int64())
+
+	// the erc20 transfer to the recipient, not the first Transfer log
+	assert.True(t, receipt.Match(recipient.Hex(), token.Hex()))
+	assert.Equal(t, int64(9), receipt.Value.Int64())
+	assert.False(t, receipt.Match(contract.Hex(), ""))
+	assert.Nil(t, receipt.Value)
+}
@@ -7,8 +7,11 @@ import (
 
 	"github.com/realcaishen/utils-go/apollosdk"
 	"github.com/realcaishen/utils-go/loader"
+	"github.com/realcaishen/utils-go/log"
 )
 
+var logger = log.Module("rpc")
+
 type Rpc interface {
 	Client() interface{}
 	Backend() int32
@@ -22,7 +25,17 @@ type Rpc interface {
 	GetChecksumAddress(addr string) string
 }
 
+// GetRpc returns the client of chainInfo's backend, with a span and latency, error and
+// in-flight metrics per call. Use Unwrap to get at the backend's concrete type.
 func GetRpc(chainInfo *loader.ChainInfo, apolloSDK *apollosdk.ApolloSDK) (Rpc, error) {
+	rpc, err := newRpc(chainInfo, apolloSDK)
+	if err != nil {
+		return nil, err
+	}
+	return withTracing(withMetrics(rpc, chainInfo), chainInfo), nil
+}
+
+func newRpc(chainInfo *loader.ChainInfo, apolloSDK *apollosdk.ApolloSDK) (Rpc, error) {
 	if chainInfo.Backend == 1 {
 		return NewEvmRpc(chainInfo), nil
 	} else if chainInfo.Backend == 2 {
@@ -15,7 +15,6 @@ import (
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/near/borsh-go"
 	"github.com/realcaishen/utils-go/loader"
-	"github.com/realcaishen/utils-go/log"
 	sol "github.com/realcaishen/utils-go/txn/solana"
 	"github.com/realcaishen/utils-go/util"
 	"github.com/shopspring/decimal"
@@ -311,7 +310,7 @@ func (w *SolanaRpc) GetLatestBlockNumber(ctx context.Context) (int64, error) {
 	)
 
 	if err != nil {
-		log.Errorf("%v get latest block number error %v", w.chainInfo.Name, err)
+		logger.Errorf("%v get latest block number error %v", w.chainInfo.Name, err)
 		return 0, err
 	}
 	return int64(blockNumber), nil
@@ -11,7 +11,6 @@ import (
 	"github.com/NethermindEth/starknet.go/utils"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/realcaishen/utils-go/loader"
-	"github.com/realcaishen/utils-go/log"
 	"github.com/realcaishen/utils-go/util"
 )
 
@@ -114,7 +113,7 @@ func (w *StarknetRpc) IsTxSuccess(ctx context.Context, hash string) (bool, int64
 func (w *StarknetRpc) GetLatestBlockNumber(ctx context.Context) (int64, error) {
 	blockNumber, err := w.GetClient().BlockNumber(ctx)
 	if err != nil {
-		log.Errorf("%v get latest block number error %v", w.chainInfo.Name, err)
+		logger.Errorf("%v get latest block number error %v", w.chainInfo.Name, err)
 		return 0, err
 	}
 	return int64(blockNumber), nil
@@ -0,0 +1,107 @@
+package rpc
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/realcaishen/utils-go/loader"
+	"github.com/realcaishen/utils-go/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("rpc")
+
+// wrapper is implemented by the decorators GetRpc puts around a backend.
+type wrapper interface {
+	Unwrap() Rpc
+}
+
+// Unwrap strips the decorators added by GetRpc, e.g. before asserting *EvmRpc.
+func Unwrap(rpc Rpc) Rpc {
+	for {
+		w, ok := rpc.(wrapper)
+		if !ok {
+			return rpc
+		}
+		rpc = w.Unwrap()
+	}
+}
+
+// tracedRpc starts a client span around every call that takes a context.
+type tracedRpc struct {
+	Rpc
+	attrs []attribute.KeyValue
+}
+
+func withTracing(rpc Rpc, chainInfo *loader.ChainInfo) Rpc {
+	return &tracedRpc{
+		Rpc: rpc,
+		attrs: []attribute.KeyValue{
+			attribute.String("chain.name", chainInfo.Name),
+			attribute.Int("chain.backend", int(chainInfo.Backend)),
+		},
+	}
+}
+
+func (r *tracedRpc) Unwrap() Rpc {
+	return r.Rpc
+}
+
+func (r *tracedRpc) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "rpc."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(r.attrs...),
+		trace.WithAttributes(attrs...))
+}
+
+func (r *tracedRpc) GetLatestBlockNumber(ctx context.Context) (int64, error) {
+	ctx, span := r.start(ctx, "GetLatestBlockNumber")
+	number, err := r.Rpc.GetLatestBlockNumber(ctx)
+	tracing.End(span, err)
+	return number, err
+}
+
+func (r *tracedRpc) IsTxSuccess(ctx context.Context, hash string) (bool, int64, error) {
+	ctx, span := r.start(ctx, "IsTxSuccess", attribute.String("tx.hash", hash))
+	success, number, err := r.Rpc.IsTxSuccess(ctx, hash)
+	tracing.End(span, err)
+	return success, number, err
+}
+
+func (r *tracedRpc) GetAllowance(ctx context.Context, ownerAddr string, tokenAddr string, spenderAddr string) (*big.Int, error) {
+	ctx, span := r.start(ctx, "GetAllowance", attribute.String("token.address", tokenAddr))
+	allowance, err := r.Rpc.GetAllowance(ctx, ownerAddr, tokenAddr, spenderAddr)
+	tracing.End(span, err)
+	return allowance, err
+}
+
+func (r *tracedRpc) GetBalance(ctx context.Context, ownerAddr string, tokenAddr string) (*big.Int, error) {
+	ctx, span := r.start(ctx, "GetBalance", attribute.String("token.address", tokenAddr))
+	balance, err := r.Rpc.GetBalance(ctx, ownerAddr, tokenAddr)
+	tracing.End(span, err)
+	return balance, err
+}
+
+func (r *tracedRpc) GetBalanceAtBlockNumber(ctx context.Context, ownerAddr string, tokenAddr string, blockNumber int64) (*big.Int, error) {
+	ctx, span := r.start(ctx, "GetBalanceAtBlockNumber", attribute.String("token.address", tokenAddr), attribute.Int64("block.number", blockNumber))
+	balance, err := r.Rpc.GetBalanceAtBlockNumber(ctx, ownerAddr, tokenAddr, blockNumber)
+	tracing.End(span, err)
+	return balance, err
+}
+
+func (r *tracedRpc) GetTokenInfo(ctx context.Context, tokenAddr string) (*loader.TokenInfo, error) {
+	ctx, span := r.start(ctx, "GetTokenInfo", attribute.String("token.address", tokenAddr))
+	token, err := r.Rpc.GetTokenInfo(ctx, tokenAddr)
+	tracing.End(span, err)
+	return token, err
+}
+
+// GetTxReceipt keeps ReceiptRpc visible through the wrapper; backends without it fall
+// back to IsTxSuccess as in the package level GetTxReceipt.
+func (r *tracedRpc) GetTxReceipt(ctx context.Context, hash string) (*TxReceipt, error) {
+	ctx, span := r.start(ctx, "GetTxReceipt", attribute.String("tx.hash", hash))
+	receipt, err := GetTxReceipt(ctx, r.Rpc, hash)
+	tracing.End(span, err)
+	return receipt, err
+}
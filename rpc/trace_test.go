@@ -0,0 +1,80 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/realcaishen/utils-go/loader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeRpc fails every call with err.
+type fakeRpc struct {
+	err error
+}
+
+func (f *fakeRpc) Client() interface{} { return nil }
+func (f *fakeRpc) Backend() int32      { return 1 }
+func (f *fakeRpc) GetLatestBlockNumber(ctx context.Context) (int64, error) {
+	return 100, f.err
+}
+func (f *fakeRpc) IsTxSuccess(ctx context.Context, hash string) (bool, int64, error) {
+	return true, 100, f.err
+}
+func (f *fakeRpc) GetAllowance(ctx context.Context, ownerAddr string, tokenAddr string, spenderAddr string) (*big.Int, error) {
+	return big.NewInt(1), f.err
+}
+func (f *fakeRpc) GetBalance(ctx context.Context, ownerAddr string, tokenAddr string) (*big.Int, error) {
+	return big.NewInt(2), f.err
+}
+func (f *fakeRpc) GetBalanceAtBlockNumber(ctx context.Context, ownerAddr string, tokenAddr string, blockNumber int64) (*big.Int, error) {
+	return big.NewInt(3), f.err
+}
+func (f *fakeRpc) GetTokenInfo(ctx context.Context, tokenAddr string) (*loader.TokenInfo, error) {
+	return &loader.TokenInfo{TokenAddress: tokenAddr}, f.err
+}
+func (f *fakeRpc) IsAddressValid(addr string) bool       { return true }
+func (f *fakeRpc) GetChecksumAddress(addr string) string { return addr }
+
+// fakeReceiptRpc is a backend that implements ReceiptRpc.
+type fakeReceiptRpc struct {
+	fakeRpc
+}
+
+func (f *fakeReceiptRpc) GetTxReceipt(ctx context.Context, hash string) (*TxReceipt, error) {
+	return &TxReceipt{Hash: hash, Success: true, Recipient: "0xrecipient", Value: big.NewInt(5)}, f.err
+}
+
+func TestTracedRpc(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(prev)
+
+	inner := &fakeRpc{}
+	r := withTracing(inner, &loader.ChainInfo{Name: "Ethereum", Backend: 1})
+	assert.Same(t, inner, Unwrap(r))
+	assert.Same(t, inner, Unwrap(inner))
+
+	balance, err := r.GetBalance(context.Background(), "0xowner", "0xtoken")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), balance.Int64())
+	inner.err = errors.New("node down")
+	_, err = r.GetLatestBlockNumber(context.Background())
+	assert.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "rpc.GetBalance", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("chain.name", "Ethereum"))
+	assert.Contains(t, spans[0].Attributes(), attribute.String("token.address", "0xtoken"))
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
@@ -0,0 +1,111 @@
+package settlement
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+)
+
+// fakeDB is a database/sql connector that records every statement and answers queries
+// with canned rows, so the SQL paths can be tested without MySQL.
+type fakeDB struct {
+	mutex sync.Mutex
+	// log holds BEGIN, COMMIT, ROLLBACK and every statement, in order
+	log  []string
+	args [][]driver.Value
+	// rows answers the first query that contains the key
+	rows map[string][][]driver.Value
+	// affected is the rows affected of every exec
+	affected int64
+}
+
+func newFakeDB() (*sql.DB, *fakeDB) {
+	fake := &fakeDB{rows: make(map[string][][]driver.Value), affected: 1}
+	return sql.OpenDB(fake), fake
+}
+
+func (f *fakeDB) record(entry string, args []driver.Value) {
+	f.mutex.Lock()
+	f.log = append(f.log, entry)
+	f.args = append(f.args, args)
+	f.mutex.Unlock()
+}
+
+func (f *fakeDB) statements() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.log...)
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.record("BEGIN", nil)
+	return c, nil
+}
+func (c *fakeConn) Commit() error {
+	c.db.record("COMMIT", nil)
+	return nil
+}
+func (c *fakeConn) Rollback() error {
+	c.db.record("ROLLBACK", nil)
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.record(s.query, args)
+	return driver.RowsAffected(s.db.affected), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.record(s.query, args)
+	s.db.mutex.Lock()
+	defer s.db.mutex.Unlock()
+	for key, rows := range s.db.rows {
+		if strings.Contains(s.query, key) {
+			return &fakeRows{rows: rows}, nil
+		}
+	}
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
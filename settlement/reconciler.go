@@ -0,0 +1,353 @@
+package settlement
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/realcaishen/utils-go/alert"
+	"github.com/realcaishen/utils-go/apollosdk"
+	"github.com/realcaishen/utils-go/loader"
+	"github.com/realcaishen/utils-go/log"
+	"github.com/realcaishen/utils-go/rpc"
+)
+
+const (
+	DefaultBatchSize = 100
+	operator         = "settlement-reconciler"
+)
+
+// Expectation is what the destination transfer of an order should look like. Empty
+// fields are not checked.
+type Expectation struct {
+	Recipient string
+	Token     string
+	Amount    string
+}
+
+// Reconciler verifies settlements on chain: processed t_src_transaction rows whose
+// dst_tx_hash was never verified, and confirmed t_dst_transaction_gen rows whose
+// confirmed_success is still NULL.
+type Reconciler struct {
+	db            *sql.DB
+	alerter       alert.Alerter
+	chainManager  *loader.ChainInfoManager
+	srcTxManager  *loader.SrcTxManager
+	apolloSDK     *apollosdk.ApolloSDK
+	batchSize     int
+	confirmations int64
+	mutex         *sync.Mutex
+}
+
+func NewReconciler(db *sql.DB, alerter alert.Alerter, chainManager *loader.ChainInfoManager, apolloSDK *apollosdk.ApolloSDK) *Reconciler {
+	return &Reconciler{
+		db:           db,
+		alerter:      alerter,
+		chainManager: chainManager,
+		srcTxManager: loader.NewSrcTxManager(db, alerter),
+		apolloSDK:    apolloSDK,
+		batchSize:    DefaultBatchSize,
+		mutex:        &sync.Mutex{},
+	}
+}
+
+// SetBatchSize limits how many rows one pass looks at per chain.
+func (r *Reconciler) SetBatchSize(size int) {
+	r.batchSize = size
+}
+
+// SetConfirmations makes the reconciler wait until a transaction is buried under
+// confirmations blocks before writing anything back.
+func (r *Reconciler) SetConfirmations(confirmations int64) {
+	r.confirmations = confirmations
+}
+
+// Reconcile runs one pass over every enabled chain. It is meant to be driven by
+// task.PeriodicTask; concurrent calls are serialized.
+func (r *Reconciler) Reconcile(ctx context.Context) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, chain := range r.chainManager.GetAllChains() {
+		if chain.Disabled != 0 {
+			continue
+		}
+		chainId := chain.GetInt32ChainId()
+		if chainId == 0 {
+			continue
+		}
+		if _, err := r.ReconcileSrc(ctx, chainId); err != nil {
+			log.CtxErrorf(ctx, "reconcile src transactions of %v error: %v", chain.Name, err)
+		}
+	}
+	if _, err := r.ReconcileGen(ctx); err != nil {
+		log.CtxErrorf(ctx, "reconcile dst transaction gens error: %v", err)
+	}
+}
+
+type pendingSrc struct {
+	id        int64
+	dstTxHash string
+	expect    Expectation
+}
+
+// unverifiedSrcQuery selects processed orders of a dst chain that were never verified.
+// The expected recipient is the one the dst transfer was built for, else the order's
+// target address, else its sender; never the receiver, which is the maker's deposit
+// address.
+const unverifiedSrcQuery = `SELECT s.id, s.dst_tx_hash, COALESCE(NULLIF(d.transfer_recipient, ''), NULLIF(s.target_address, ''), s.sender, ''), IFNULL(d.transfer_token, ''), IFNULL(d.transfer_amount, '')
+	FROM t_src_transaction s LEFT JOIN t_dst_transaction d ON d.src_action = 'transfer' AND d.src_id = s.id AND d.confirmed_gen IS NOT NULL
+	WHERE s.is_invalid = 0 AND IFNULL(s.is_verified, 0) = 0 AND IFNULL(s.is_manual, 0) = 0 AND s.dst_chainid = ? AND s.is_processed = 1 AND s.dst_tx_hash IS NOT NULL
+	ORDER BY s.id LIMIT ?`
+
+// ReconcileSrc verifies processed orders settled on dstChainId and returns how many
+// were moved to verified.
+func (r *Reconciler) ReconcileSrc(ctx context.Context, dstChainId int32) (int, error) {
+	chain, ok := r.chainManager.GetChainInfoByInt32ChainId(dstChainId)
+	if !ok {
+		return 0, fmt.Errorf("unknown dst chain %v", dstChainId)
+	}
+	chainRpc, err := rpc.GetRpc(chain, r.apolloSDK)
+	if err != nil {
+		return 0, err
+	}
+
+	pendings, err := r.selectPendingSrc(ctx, dstChainId)
+	if err != nil {
+		return 0, err
+	}
+
+	latest, err := r.latestHeight(ctx, chainRpc)
+	if err != nil {
+		return 0, err
+	}
+
+	verified := 0
+	for _, p := range pendings {
+		if p.dstTxHash == "" {
+			continue
+		}
+		receipt, err := rpc.GetTxReceipt(ctx, chainRpc, p.dstTxHash)
+		if err != nil {
+			// not mined yet or node hiccup, try again next pass
+			log.CtxInfof(ctx, "get receipt of %v %v error: %v", chain.Name, p.dstTxHash, err)
+			continue
+		}
+		if !r.isConfirmed(receipt, latest) {
+			continue
+		}
+
+		ok, err := r.settleSrc(ctx, chain.Name, p, receipt)
+		if err != nil {
+			return verified, err
+		}
+		if ok {
+			verified++
+		}
+	}
+	return verified, nil
+}
+
+func (r *Reconciler) selectPendingSrc(ctx context.Context, dstChainId int32) ([]*pendingSrc, error) {
+	rows, err := r.db.QueryContext(ctx, unverifiedSrcQuery, dstChainId, r.batchSize)
+	if err != nil {
+		r.alerter.AlertText("select unverified t_src_transaction error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	pendings := make([]*pendingSrc, 0)
+	for rows.Next() {
+		var p pendingSrc
+		if err = rows.Scan(&p.id, &p.dstTxHash, &p.expect.Recipient, &p.expect.Token, &p.expect.Amount); err != nil {
+			return nil, err
+		}
+		p.dstTxHash = strings.TrimSpace(p.dstTxHash)
+		pendings = append(pendings, &p)
+	}
+	return pendings, rows.Err()
+}
+
+// settleSrc checks a confirmed receipt against the order and moves it to verified, or
+// to manual on a mismatch. The settlement columns and the state change commit together.
+// It reports whether the order was verified; an order another worker moved first is
+// skipped without error.
+func (r *Reconciler) settleSrc(ctx context.Context, chainName string, p *pendingSrc, receipt *rpc.TxReceipt) (bool, error) {
+	problems := Check(p.expect, receipt)
+	if len(problems) > 0 {
+		reason := strings.Join(problems, "; ")
+		r.alerter.AlertText(fmt.Sprintf("settlement mismatch src_id %v %v %v: %v", p.id, chainName, p.dstTxHash, reason), nil)
+		err := r.srcTxManager.Transition(&loader.OrderTransition{
+			SrcId: p.id, From: loader.OrderProcessed, To: loader.OrderManual, Operator: operator, Reason: reason,
+		})
+		if err != nil && !errors.Is(err, loader.ErrTransitionConflict) {
+			return false, err
+		}
+		return false, nil
+	}
+
+	verified := &loader.OrderTransition{
+		SrcId: p.id, From: loader.OrderProcessed, To: loader.OrderVerified, Operator: operator, Reason: "dst tx confirmed",
+		DstTimestamp: receipt.BlockTime,
+	}
+	err := loader.WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		if receipt.Value != nil {
+			_, err := tx.ExecContext(ctx, "UPDATE t_src_transaction SET dst_value = ?, dst_gas_used = ?, dst_gas_price = ? WHERE id = ? AND IFNULL(is_verified, 0) = 0",
+				receipt.Value.String(), fmt.Sprint(receipt.GasUsed), bigString(receipt.GasPrice), p.id)
+			if err != nil {
+				return err
+			}
+		}
+		return r.srcTxManager.TransitionTx(ctx, tx, verified)
+	})
+	if errors.Is(err, loader.ErrTransitionConflict) {
+		return false, nil
+	}
+	if err != nil {
+		r.alerter.AlertText("verify t_src_transaction settlement error", err)
+		return false, err
+	}
+	return true, nil
+}
+
+type pendingGen struct {
+	id          int64
+	hash        string
+	chainInfoId int64
+}
+
+// ReconcileGen fills the confirmed_* columns of confirmed dst transaction gens whose
+// outcome was never recorded and returns how many rows were updated.
+func (r *Reconciler) ReconcileGen(ctx context.Context) (int, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT g.id, g.hash, a.chain_id FROM t_dst_transaction_gen g
+		JOIN t_dst_transaction t ON t.confirmed_gen = g.id JOIN t_account a ON a.id = t.sender
+		WHERE g.confirmed_success IS NULL AND g.placeholder = 0 ORDER BY g.id LIMIT ?`, r.batchSize)
+	if err != nil {
+		r.alerter.AlertText("select unconfirmed t_dst_transaction_gen error", err)
+		return 0, err
+	}
+	pendings := make([]*pendingGen, 0)
+	for rows.Next() {
+		var p pendingGen
+		if err = rows.Scan(&p.id, &p.hash, &p.chainInfoId); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		p.hash = strings.TrimSpace(p.hash)
+		pendings = append(pendings, &p)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	rpcs := make(map[int64]rpc.Rpc)
+	latests := make(map[int64]int64)
+	updated := 0
+	for _, p := range pendings {
+		chainRpc, ok := rpcs[p.chainInfoId]
+		if !ok {
+			chain, ok := r.chainManager.GetChainInfoById(p.chainInfoId)
+			if !ok {
+				continue
+			}
+			if chainRpc, err = rpc.GetRpc(chain, r.apolloSDK); err != nil {
+				continue
+			}
+			if latests[p.chainInfoId], err = r.latestHeight(ctx, chainRpc); err != nil {
+				continue
+			}
+			rpcs[p.chainInfoId] = chainRpc
+		}
+
+		receipt, err := rpc.GetTxReceipt(ctx, chainRpc, p.hash)
+		if err != nil {
+			log.CtxInfof(ctx, "get receipt of gen %v %v error: %v", p.id, p.hash, err)
+			continue
+		}
+		if !r.isConfirmed(receipt, latests[p.chainInfoId]) {
+			continue
+		}
+		if !receipt.Success {
+			r.alerter.AlertText(fmt.Sprintf("dst tx gen %v %v failed on chain", p.id, p.hash), nil)
+		}
+
+		_, err = r.db.ExecContext(ctx, `UPDATE t_dst_transaction_gen SET confirmed_height = ?, confirmed_gas_used = ?, confirmed_gas_price = ?, confirmed_tx_fee = ?, confirmed_success = ?
+			WHERE id = ? AND confirmed_success IS NULL`,
+			receipt.BlockNumber, nullInt64(receipt.GasUsed), nullBigString(receipt.GasPrice), nullBigString(receipt.TxFee), receipt.Success, p.id)
+		if err != nil {
+			r.alerter.AlertText("update t_dst_transaction_gen confirmation error", err)
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+func (r *Reconciler) latestHeight(ctx context.Context, chainRpc rpc.Rpc) (int64, error) {
+	if r.confirmations <= 0 {
+		return 0, nil
+	}
+	return chainRpc.GetLatestBlockNumber(ctx)
+}
+
+func (r *Reconciler) isConfirmed(receipt *rpc.TxReceipt, latest int64) bool {
+	return r.confirmations <= 0 || latest-receipt.BlockNumber >= r.confirmations
+}
+
+// Check compares a receipt with what the order expected and describes every mismatch.
+// The receipt is matched on the transfer to the expected recipient of the expected
+// token, and an order that expects anything but whose receipt shows no transfer at all
+// is a mismatch too. Addresses are compared case-insensitively and amounts as integers.
+func Check(expect Expectation, receipt *rpc.TxReceipt) []string {
+	problems := make([]string, 0)
+	if !receipt.Success {
+		return append(problems, "dst tx failed")
+	}
+	if expect == (Expectation{}) {
+		return problems
+	}
+	if len(receipt.Transfers) == 0 {
+		return append(problems, "no transfer found in dst tx")
+	}
+	if !receipt.Match(expect.Recipient, expect.Token) {
+		receipt.Match("", "")
+		return append(problems, fmt.Sprintf("no transfer of token %v to %v, first transfer is %v of %v to %v",
+			orAny(expect.Token), orAny(expect.Recipient), receipt.Value, receipt.Token, receipt.Recipient))
+	}
+	if expect.Amount != "" {
+		amount, ok := new(big.Int).SetString(strings.TrimSpace(expect.Amount), 10)
+		if !ok || amount.Cmp(receipt.Value) != 0 {
+			problems = append(problems, fmt.Sprintf("amount %v, expected %v", receipt.Value, expect.Amount))
+		}
+	}
+	return problems
+}
+
+func orAny(s string) string {
+	if s == "" {
+		return "any"
+	}
+	return s
+}
+
+func bigString(v *big.Int) string {
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
+
+func nullBigString(v *big.Int) sql.NullString {
+	if v == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: v.String(), Valid: true}
+}
+
+func nullInt64(v int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: v, Valid: v != 0}
+}
@@ -0,0 +1,115 @@
+package settlement
+
+import (
+	"context"
+	"database/sql/driver"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/realcaishen/utils-go/alert"
+	"github.com/realcaishen/utils-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	expect := Expectation{
+		Recipient: "0xAbC0000000000000000000000000000000000001",
+		Token:     "0x00000000000000000000000000000000000000cc",
+		Amount:    "1000",
+	}
+	fee := &rpc.Transfer{Recipient: "0xfee0000000000000000000000000000000000001", Token: "0x00000000000000000000000000000000000000cc", Value: big.NewInt(5)}
+	payout := &rpc.Transfer{Recipient: "0xabc0000000000000000000000000000000000001", Token: "0x00000000000000000000000000000000000000CC", Value: big.NewInt(1000)}
+
+	// the transfer to the expected recipient is checked, not the first one
+	receipt := &rpc.TxReceipt{Success: true, Transfers: []*rpc.Transfer{fee, payout}}
+	assert.Empty(t, Check(expect, receipt))
+	assert.Equal(t, big.NewInt(1000), receipt.Value)
+
+	payout.Value = big.NewInt(999)
+	assert.Len(t, Check(expect, receipt), 1)
+	assert.Len(t, Check(expect, &rpc.TxReceipt{Success: true, Transfers: []*rpc.Transfer{fee}}), 1)
+
+	// receipts without transfer details cannot verify an order that expects one
+	assert.Equal(t, []string{"no transfer found in dst tx"}, Check(expect, &rpc.TxReceipt{Success: true}))
+	assert.Empty(t, Check(Expectation{}, &rpc.TxReceipt{Success: true}))
+	assert.Equal(t, []string{"dst tx failed"}, Check(expect, &rpc.TxReceipt{Success: false}))
+}
+
+func TestSelectPendingSrc(t *testing.T) {
+	db, fake := newFakeDB()
+	defer db.Close()
+	fake.rows["FROM t_src_transaction s"] = [][]driver.Value{
+		{int64(7), "0xdst ", "0xtarget", "", "1000"},
+	}
+	r := NewReconciler(db, alert.NewCommonAlerter(0, 0), nil, nil)
+
+	pendings, err := r.selectPendingSrc(context.Background(), 42161)
+	require.NoError(t, err)
+	require.Len(t, pendings, 1)
+	assert.Equal(t, &pendingSrc{id: 7, dstTxHash: "0xdst", expect: Expectation{Recipient: "0xtarget", Amount: "1000"}}, pendings[0])
+
+	query := fake.statements()[0]
+	assert.Contains(t, query, "COALESCE(NULLIF(d.transfer_recipient, ''), NULLIF(s.target_address, ''), s.sender, '')")
+	assert.NotContains(t, query, "s.receiver")
+	assert.Equal(t, []driver.Value{int64(42161), int64(DefaultBatchSize)}, fake.args[0])
+}
+
+func TestSettleSrc(t *testing.T) {
+	ctx := context.Background()
+	p := &pendingSrc{id: 7, dstTxHash: "0xdst", expect: Expectation{Recipient: "0xtarget", Amount: "1000"}}
+	receipt := &rpc.TxReceipt{Success: true, GasUsed: 21000, GasPrice: big.NewInt(1),
+		Transfers: []*rpc.Transfer{{Recipient: "0xtarget", Value: big.NewInt(1000)}}}
+
+	verbs := func(fake *fakeDB) []string {
+		verbs := make([]string, 0)
+		for _, statement := range fake.statements() {
+			fields := strings.Fields(statement)
+			verbs = append(verbs, strings.Join(fields[:min(2, len(fields))], " "))
+		}
+		return verbs
+	}
+
+	// the settlement columns and the verified transition commit in one transaction, the
+	// order is counted as settled after that
+	db, fake := newFakeDB()
+	r := NewReconciler(db, alert.NewCommonAlerter(0, 0), nil, nil)
+	ok, err := r.settleSrc(ctx, "Arbitrum", p, receipt)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"BEGIN", "UPDATE t_src_transaction", "UPDATE t_src_transaction", "INSERT INTO", "COMMIT", "SELECT chainid,"}, verbs(fake))
+	assert.Contains(t, fake.statements()[1], "dst_value = ?")
+	assert.Contains(t, fake.statements()[2], "is_verified = 1")
+	db.Close()
+
+	// another worker moved the order first: nothing commits
+	db, fake = newFakeDB()
+	fake.affected = 0
+	r = NewReconciler(db, alert.NewCommonAlerter(0, 0), nil, nil)
+	ok, err = r.settleSrc(ctx, "Arbitrum", p, receipt)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "ROLLBACK", fake.statements()[len(fake.statements())-1])
+	db.Close()
+
+	// a mismatch parks the order for an operator without touching dst_value
+	db, fake = newFakeDB()
+	r = NewReconciler(db, alert.NewCommonAlerter(0, 0), nil, nil)
+	ok, err = r.settleSrc(ctx, "Arbitrum", p, &rpc.TxReceipt{Success: true, Transfers: []*rpc.Transfer{{Recipient: "0xmaker", Value: big.NewInt(1000)}}})
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, []string{"BEGIN", "UPDATE t_src_transaction", "INSERT INTO", "COMMIT"}, verbs(fake))
+	assert.Contains(t, fake.statements()[1], "is_manual = 1")
+	db.Close()
+
+	// so does a receipt that shows no transfer at all, e.g. from a backend without
+	// receipt details
+	db, fake = newFakeDB()
+	r = NewReconciler(db, alert.NewCommonAlerter(0, 0), nil, nil)
+	ok, err = r.settleSrc(ctx, "Arbitrum", p, &rpc.TxReceipt{Success: true})
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Contains(t, fake.statements()[1], "is_manual = 1")
+	db.Close()
+}
@@ -55,3 +55,31 @@ func ScheduleDailyTask(ctx context.Context, fn func(), hour, minute, second int)
 		}
 	}()
 }
+
+// Leadership reports whether this process is the one that should run singleton jobs,
+// e.g. a *dlock.Elector.
+type Leadership interface {
+	// LeaderContext returns a context cancelled when leadership is lost, or nil if this
+	// process is not the leader.
+	LeaderContext() context.Context
+}
+
+// LeaderPeriodicTask is PeriodicTask for jobs that must run on one replica only: every
+// round is skipped unless this process is the leader at that moment. The job gets a
+// context that is cancelled when leadership is lost or ctx is done, and must stop its
+// work when it is.
+func LeaderPeriodicTask(ctx context.Context, leader Leadership, task func(ctx context.Context), waitSecond time.Duration) {
+	PeriodicTask(ctx, func() {
+		leaderCtx := leader.LeaderContext()
+		if leaderCtx == nil {
+			return
+		}
+		jobCtx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+		stop := context.AfterFunc(leaderCtx, func() {
+			cancel(context.Cause(leaderCtx))
+		})
+		defer stop()
+		task(jobCtx)
+	}, waitSecond)
+}
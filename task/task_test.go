@@ -0,0 +1,74 @@
+package task
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeLeader struct {
+	mutex  sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (f *fakeLeader) LeaderContext() context.Context {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.ctx
+}
+
+func (f *fakeLeader) elect() {
+	f.mutex.Lock()
+	f.ctx, f.cancel = context.WithCancel(context.Background())
+	f.mutex.Unlock()
+}
+
+func (f *fakeLeader) resign() {
+	f.mutex.Lock()
+	f.cancel()
+	f.ctx = nil
+	f.mutex.Unlock()
+}
+
+func TestLeaderPeriodicTask(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := &fakeLeader{}
+	var runs atomic.Int32
+	jobs := make(chan context.Context, 1)
+	done := make(chan struct{})
+	go func() {
+		LeaderPeriodicTask(ctx, leader, func(jobCtx context.Context) {
+			if runs.Add(1) == 1 {
+				jobs <- jobCtx
+				// a long job runs until leadership is lost
+				<-jobCtx.Done()
+			}
+		}, 5*time.Millisecond)
+		close(done)
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, int32(0), runs.Load())
+
+	leader.elect()
+	var jobCtx context.Context
+	select {
+	case jobCtx = <-jobs:
+	case <-time.After(time.Second):
+		t.Fatal("job did not run as leader")
+	}
+	assert.NoError(t, jobCtx.Err())
+
+	leader.resign()
+	assert.Eventually(t, func() bool { return jobCtx.Err() != nil }, time.Second, 5*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, int32(1), runs.Load())
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,161 @@
+package telemetry
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-metrics"
+)
+
+// Label names of the bridge metrics.
+const (
+	LabelSrcChain = "src_chain"
+	LabelDstChain = "dst_chain"
+	LabelChain    = "chain"
+	LabelToken    = "token"
+	LabelMaker    = "maker"
+	LabelAppId    = "appid"
+)
+
+// Bridge KPIs. The loader emits the order metrics and collected fees from SrcTxManager,
+// the scanner lag and staleness from ProcessedBlockManager and the maker balances from
+// MakerBalanceReporter.
+var (
+	MetricOrdersReceived = Definition{
+		Kind:   KindCounter,
+		Name:   []string{"bridge", "orders", "received"},
+		Help:   "Source transactions recorded, per route.",
+		Labels: []string{LabelSrcChain, LabelDstChain, LabelToken},
+	}
+	MetricOrdersSettled = Definition{
+		Kind:   KindCounter,
+		Name:   []string{"bridge", "orders", "settled"},
+		Help:   "Orders whose destination transfer was verified, per route.",
+		Labels: []string{LabelSrcChain, LabelDstChain, LabelToken},
+	}
+	MetricOrdersFailed = Definition{
+		Kind:   KindCounter,
+		Name:   []string{"bridge", "orders", "failed"},
+		Help:   "Orders marked invalid, per route.",
+		Labels: []string{LabelSrcChain, LabelDstChain, LabelToken},
+	}
+	MetricSettlementLatency = Definition{
+		Kind:   KindSummary,
+		Name:   []string{"bridge", "settlement", "latency"},
+		Help:   "Milliseconds from the source transaction to the block of the verified destination transfer.",
+		Labels: []string{LabelSrcChain, LabelDstChain},
+	}
+	MetricFeesCollected = Definition{
+		Kind:   KindCounter,
+		Name:   []string{"bridge", "fees", "collected"},
+		Help:   "Fees collected in token units, per token.",
+		Labels: []string{LabelChain, LabelToken},
+	}
+	MetricMakerBalance = Definition{
+		Kind:   KindGauge,
+		Name:   []string{"bridge", "maker", "balance"},
+		Help:   "Last seen maker balance in token units.",
+		Labels: []string{LabelChain, LabelMaker, LabelToken},
+	}
+	MetricScannerLag = Definition{
+		Kind:   KindGauge,
+		Name:   []string{"bridge", "scanner", "lag"},
+		Help:   "Blocks between the chain head and the last block an event scanner processed.",
+		Labels: []string{LabelChain, LabelAppId},
+	}
+	MetricScannerStaleness = Definition{
+		Kind:   KindGauge,
+		Name:   []string{"bridge", "scanner", "staleness"},
+		Help:   "Seconds since an event scanner last recorded progress.",
+		Labels: []string{LabelChain, LabelAppId},
+	}
+
+	BridgeMetrics = []Definition{
+		MetricOrdersReceived,
+		MetricOrdersSettled,
+		MetricOrdersFailed,
+		MetricSettlementLatency,
+		MetricFeesCollected,
+		MetricMakerBalance,
+		MetricScannerLag,
+		MetricScannerStaleness,
+	}
+)
+
+func init() {
+	MustRegister(BridgeMetrics...)
+}
+
+// Route identifies a bridge direction. Chains are the chain ids used in
+// t_src_transaction, Token the source token name.
+type Route struct {
+	SrcChain string
+	DstChain string
+	Token    string
+}
+
+// labels builds a fresh slice on every call since the wrappers append to it.
+func (r Route) labels() []metrics.Label {
+	return []metrics.Label{
+		NewLabel(LabelSrcChain, r.SrcChain),
+		NewLabel(LabelDstChain, r.DstChain),
+		NewLabel(LabelToken, r.Token),
+	}
+}
+
+// OrderReceived counts a new source transaction on route.
+func OrderReceived(route Route) {
+	IncrCounterWithLabels(MetricOrdersReceived.Name, 1, route.labels())
+}
+
+// OrderSettled counts a verified order and records how long it took since the source
+// transaction; a non-positive latency is not recorded.
+func OrderSettled(route Route, latency time.Duration) {
+	IncrCounterWithLabels(MetricOrdersSettled.Name, 1, route.labels())
+	if latency > 0 {
+		AddSampleWithLabels(MetricSettlementLatency.Name, float32(latency.Milliseconds()), []metrics.Label{
+			NewLabel(LabelSrcChain, route.SrcChain),
+			NewLabel(LabelDstChain, route.DstChain),
+		})
+	}
+}
+
+// OrderFailed counts an order that was rejected for good.
+func OrderFailed(route Route) {
+	IncrCounterWithLabels(MetricOrdersFailed.Name, 1, route.labels())
+}
+
+// FeeCollected adds amount, in token units, to the fees of token on chain.
+func FeeCollected(chain string, token string, amount float64) {
+	IncrCounterWithLabels(MetricFeesCollected.Name, float32(amount), []metrics.Label{
+		NewLabel(LabelChain, chain),
+		NewLabel(LabelToken, token),
+	})
+}
+
+// MakerBalance records the balance, in token units, of a maker address.
+func MakerBalance(chain string, maker string, token string, balance float64) {
+	SetGaugeWithLabels(MetricMakerBalance.Name, float32(balance), []metrics.Label{
+		NewLabel(LabelChain, chain),
+		NewLabel(LabelMaker, maker),
+		NewLabel(LabelToken, token),
+	})
+}
+
+// ScannerLag records how many blocks the scanner appid is behind on chain.
+func ScannerLag(chain string, appid int32, blocks int64) {
+	SetGaugeWithLabels(MetricScannerLag.Name, float32(blocks), scannerLabels(chain, appid))
+}
+
+// ScannerStaleness records how long ago the scanner appid last recorded progress on
+// chain. A stalled scanner keeps reporting its last lag, this keeps growing.
+func ScannerStaleness(chain string, appid int32, age time.Duration) {
+	SetGaugeWithLabels(MetricScannerStaleness.Name, float32(age.Seconds()), scannerLabels(chain, appid))
+}
+
+func scannerLabels(chain string, appid int32) []metrics.Label {
+	return []metrics.Label{
+		NewLabel(LabelChain, chain),
+		NewLabel(LabelAppId, strconv.FormatInt(int64(appid), 10)),
+	}
+}
@@ -0,0 +1,103 @@
+package telemetry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-metrics"
+	metricsprom "github.com/hashicorp/go-metrics/prometheus"
+)
+
+// Kind is the type of a catalogued metric.
+type Kind string
+
+const (
+	KindCounter Kind = "counter"
+	KindGauge   Kind = "gauge"
+	KindSummary Kind = "summary"
+)
+
+// Definition documents a metric: its key, what it measures and the labels it carries.
+type Definition struct {
+	Kind   Kind
+	Name   []string
+	Help   string
+	Labels []string
+}
+
+// Key is the dotted metric key, e.g. "bridge.orders.received".
+func (d Definition) Key() string {
+	return strings.Join(d.Name, ".")
+}
+
+var (
+	catalogueMutex sync.RWMutex
+	catalogue      = map[string]Definition{}
+)
+
+// Register adds metrics to the catalogue. Register before New so the Prometheus sink
+// publishes their help texts. Registering the same key twice is an error unless the
+// definitions are identical.
+func Register(defs ...Definition) error {
+	catalogueMutex.Lock()
+	defer catalogueMutex.Unlock()
+	for _, def := range defs {
+		if len(def.Name) == 0 {
+			return fmt.Errorf("metric definition without name")
+		}
+		if existing, ok := catalogue[def.Key()]; ok && !sameDefinition(existing, def) {
+			return fmt.Errorf("metric %s already registered as %s", def.Key(), existing.Kind)
+		}
+	}
+	for _, def := range defs {
+		catalogue[def.Key()] = def
+	}
+	return nil
+}
+
+// MustRegister is Register for package initialization.
+func MustRegister(defs ...Definition) {
+	if err := Register(defs...); err != nil {
+		panic(err)
+	}
+}
+
+// Definitions returns the catalogue sorted by key.
+func Definitions() []Definition {
+	catalogueMutex.RLock()
+	defs := make([]Definition, 0, len(catalogue))
+	for _, def := range catalogue {
+		defs = append(defs, def)
+	}
+	catalogueMutex.RUnlock()
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Key() < defs[j].Key() })
+	return defs
+}
+
+func sameDefinition(a, b Definition) bool {
+	return a.Kind == b.Kind && a.Help == b.Help && a.Key() == b.Key() && strings.Join(a.Labels, ",") == strings.Join(b.Labels, ",")
+}
+
+// prometheusDefinitions turns the catalogue into sink definitions, prefixing the names
+// the way go-metrics prefixes the emitted keys under conf.
+func prometheusDefinitions(conf *metrics.Config, opts *metricsprom.PrometheusOpts) {
+	for _, def := range Definitions() {
+		name := def.Name
+		if def.Kind == KindGauge && conf.HostName != "" && conf.EnableHostname && !conf.EnableHostnameLabel {
+			name = append([]string{conf.HostName}, name...)
+		}
+		if conf.ServiceName != "" && !conf.EnableServiceLabel {
+			name = append([]string{conf.ServiceName}, name...)
+		}
+		switch def.Kind {
+		case KindCounter:
+			opts.CounterDefinitions = append(opts.CounterDefinitions, metricsprom.CounterDefinition{Name: name, Help: def.Help})
+		case KindGauge:
+			opts.GaugeDefinitions = append(opts.GaugeDefinitions, metricsprom.GaugeDefinition{Name: name, Help: def.Help})
+		case KindSummary:
+			opts.SummaryDefinitions = append(opts.SummaryDefinitions, metricsprom.SummaryDefinition{Name: name, Help: def.Help})
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package telemetry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-metrics"
+	metricsprom "github.com/hashicorp/go-metrics/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	assert.NoError(t, Register(MetricOrdersReceived))
+	assert.Error(t, Register(Definition{Kind: KindGauge, Name: MetricOrdersReceived.Name}))
+	assert.Error(t, Register(Definition{Kind: KindGauge}))
+
+	defs := Definitions()
+	keys := make([]string, 0, len(defs))
+	for _, def := range defs {
+		keys = append(keys, def.Key())
+	}
+	assert.IsIncreasing(t, keys)
+	assert.Contains(t, keys, "bridge.scanner.lag")
+}
+
+func TestPrometheusDefinitions(t *testing.T) {
+	conf := metrics.DefaultConfig("svc")
+	conf.HostName = "host"
+	conf.EnableHostname = true
+
+	opts := metricsprom.PrometheusOpts{}
+	prometheusDefinitions(conf, &opts)
+	assert.Contains(t, opts.CounterDefinitions, metricsprom.CounterDefinition{Name: []string{"svc", "bridge", "orders", "received"}, Help: MetricOrdersReceived.Help})
+	assert.Contains(t, opts.GaugeDefinitions, metricsprom.GaugeDefinition{Name: []string{"svc", "host", "bridge", "scanner", "lag"}, Help: MetricScannerLag.Help})
+	assert.Contains(t, opts.SummaryDefinitions, metricsprom.SummaryDefinition{Name: []string{"svc", "bridge", "settlement", "latency"}, Help: MetricSettlementLatency.Help})
+}
+
+func TestBridgeMetrics(t *testing.T) {
+	sink := metrics.NewInmemSink(time.Minute, time.Minute)
+	cfg := metrics.DefaultConfig("")
+	cfg.EnableHostname = false
+	cfg.EnableRuntimeMetrics = false
+	_, err := metrics.NewGlobal(cfg, sink)
+	require.NoError(t, err)
+	defer metrics.NewGlobal(cfg, &metrics.BlackholeSink{})
+
+	route := Route{SrcChain: "1", DstChain: "42161", Token: "USDC"}
+	OrderReceived(route)
+	OrderReceived(route)
+	OrderSettled(route, 90*time.Second)
+	OrderFailed(route)
+	FeeCollected("42161", "USDC", 1.5)
+	MakerBalance("42161", "0xmaker", "USDC", 1000)
+	ScannerLag("1", 3, 12)
+	ScannerStaleness("1", 3, 90*time.Second)
+
+	data := sink.Data()
+	require.NotEmpty(t, data)
+	interval := data[len(data)-1]
+	interval.RLock()
+	defer interval.RUnlock()
+
+	assert.Equal(t, 2, interval.Counters["bridge.orders.received;src_chain=1;dst_chain=42161;token=USDC"].Count)
+	assert.Equal(t, 1, interval.Counters["bridge.orders.settled;src_chain=1;dst_chain=42161;token=USDC"].Count)
+	assert.Equal(t, 1, interval.Counters["bridge.orders.failed;src_chain=1;dst_chain=42161;token=USDC"].Count)
+	assert.Equal(t, float64(90000), interval.Samples["bridge.settlement.latency;src_chain=1;dst_chain=42161"].Max)
+	assert.Equal(t, 1.5, interval.Counters["bridge.fees.collected;chain=42161;token=USDC"].Sum)
+	assert.Equal(t, float32(1000), interval.Gauges["bridge.maker.balance;chain=42161;maker=0xmaker;token=USDC"].Value)
+	assert.Equal(t, float32(12), interval.Gauges["bridge.scanner.lag;chain=1;appid=3"].Value)
+	assert.Equal(t, float32(90), interval.Gauges["bridge.scanner.staleness;chain=1;appid=3"].Value)
+}
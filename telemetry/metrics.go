@@ -142,6 +142,7 @@ func New(cfg Config) (_ *Metrics, rerr error) {
 		prometheusOpts := metricsprom.PrometheusOpts{
 			Expiration: time.Duration(cfg.PrometheusRetentionTime) * time.Second,
 		}
+		prometheusDefinitions(metricsConf, &prometheusOpts)
 
 		promSink, err := metricsprom.NewPrometheusSinkFrom(prometheusOpts)
 		if err != nil {
@@ -0,0 +1,120 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName prefixes the tracer names of this module's packages.
+const InstrumentationName = "github.com/realcaishen/utils-go"
+
+// Exporter selects where spans go.
+type Exporter string
+
+const (
+	// ExporterNone keeps propagation working but records nothing.
+	ExporterNone Exporter = "none"
+	// ExporterStdout prints spans as JSON, for local runs and tests.
+	ExporterStdout Exporter = "stdout"
+	// ExporterOTLP sends spans to an OTLP/HTTP collector.
+	ExporterOTLP Exporter = "otlp"
+)
+
+type Config struct {
+	ServiceName string   `mapstructure:"service_name"`
+	Exporter    Exporter `mapstructure:"exporter"`
+	// Endpoint is the collector's host:port; empty uses OTEL_EXPORTER_OTLP_ENDPOINT or
+	// localhost:4318.
+	Endpoint string `mapstructure:"endpoint"`
+	Insecure bool   `mapstructure:"insecure"`
+	// SampleRatio is the share of new traces recorded, 1 when not positive. Spans
+	// always follow the decision of an incoming parent.
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+
+	// Stdout replaces os.Stdout for ExporterStdout.
+	Stdout io.Writer `mapstructure:"-"`
+}
+
+// Setup installs the global tracer provider and the W3C trace context propagator.
+// Call the returned shutdown before exit to flush pending spans. Without Setup the
+// spans of this module are no-ops.
+func Setup(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		out := cfg.Stdout
+		if out == nil {
+			out = os.Stdout
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(out))
+	case ExporterOTLP:
+		opts := make([]otlptracehttp.Option, 0)
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of a package of this module, e.g. Tracer("rpc").
+func Tracer(pkg string) trace.Tracer {
+	return otel.Tracer(InstrumentationName + "/" + pkg)
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// IDs returns the trace and span id of the span in ctx, or empty strings if there is none.
+func IDs(ctx context.Context) (traceID string, spanID string) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return "", ""
+	}
+	return sc.TraceID().String(), sc.SpanID().String()
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/realcaishen/utils-go/redact"
 	"github.com/shopspring/decimal"
 )
 
@@ -87,8 +88,5 @@ func IsEvmAddress(address string, chainID int32) bool {
 }
 
 func MaskEVMAddress(address string) string {
-	if len(address) < 12 {
-		return address
-	}
-	return address[:6] + "****" + address[len(address)-4:]
+	return redact.MaskAddress(address)
 }
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"math/rand"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 var loc, _ = time.LoadLocation("Asia/Shanghai")
@@ -20,7 +22,12 @@ func GenerateLogID() string {
 	return fmt.Sprintf("%s%s", timestamp, randomPart)
 }
 
+// GetLogId returns the trace id of the span in ctx if there is one, like the log
+// package does, and otherwise the id set by WithLogIDCtx.
 func GetLogId(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return sc.TraceID().String()
+	}
 	logId := ctx.Value("logId")
 	if LogId, ok := logId.(string); ok {
 		return LogId